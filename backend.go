@@ -0,0 +1,188 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Storages of tasks that can be used instead of `task` command calls.
+//
+// There are three implementations of Backend:
+//
+//   - CLIBackend calls the `task` binary, works with every taskwarrior version and runs hooks.
+//   - DataFileBackend reads and writes pending.data and completed.data files of taskwarrior 2.x.
+//   - TaskChampionBackend reads and writes taskchampion.sqlite3 replica of taskwarrior 3.
+//
+// OpenBackend selects the implementation from the content of `data.location` directory.
+
+package taskwarrior
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Backend is a storage of tasks.
+type Backend interface {
+	// Return all tasks of the database.
+	List(ctx context.Context) ([]Task, error)
+
+	// Return task with given UUID. Missing task is reported as ErrNoMatchingTasks.
+	Get(ctx context.Context, uuid string) (*Task, error)
+
+	// Store new task and return it as saved. Missing UUID, status and entry date are filled in.
+	Add(ctx context.Context, task *Task) (*Task, error)
+
+	// Replace stored task having the same UUID with given one and return it as saved.
+	Modify(ctx context.Context, task *Task) (*Task, error)
+
+	// Mark task with given UUID as deleted.
+	Delete(ctx context.Context, uuid string) error
+
+	// Synchronize the database with the sync server. Returns ErrNotSupported if the backend can't do it.
+	Sync(ctx context.Context) error
+}
+
+// Choose backend for the data location of TaskWarrior instance:
+// TaskChampionBackend if there is taskchampion.sqlite3, DataFileBackend if there is pending.data and CLIBackend
+// otherwise. If both files exist, taskchampion.sqlite3 is used with taskwarrior 3.
+//
+// TaskChampionBackend requires a SQLite driver registered as TaskChampionDriver, which the application has to import.
+// Without it the replica is used through CLIBackend.
+func OpenBackend(tw *TaskWarrior) (Backend, error) {
+	dir := PathExpandTilda(tw.Config.DataLocation)
+	_, errChampion := os.Stat(filepath.Join(dir, TaskChampionFile))
+	_, errData := os.Stat(filepath.Join(dir, PendingDataFile))
+
+	// Both storages exist after upgrade to taskwarrior 3, the installed version decides which one is used.
+	if errChampion == nil && (errData != nil || tw.Capabilities().ChampionStorage) {
+		if !slices.Contains(sql.Drivers(), TaskChampionDriver) {
+			return NewCLIBackend(tw), nil
+		}
+		return NewTaskChampionBackend(tw.Config)
+	}
+	if errData == nil {
+		return NewDataFileBackend(tw.Config), nil
+	}
+	return NewCLIBackend(tw), nil
+}
+
+// CLIBackend stores tasks with `task` command calls of TaskWarrior instance.
+type CLIBackend struct {
+	tw *TaskWarrior
+}
+
+// Create backend that uses runner, configuration and timeout of given TaskWarrior instance.
+func NewCLIBackend(tw *TaskWarrior) *CLIBackend {
+	return &CLIBackend{tw: tw}
+}
+
+// Return all tasks with `task export`.
+func (b *CLIBackend) List(ctx context.Context) ([]Task, error) {
+	return b.tw.export(ctx)
+}
+
+// Return task with given UUID with `task uuid:<uuid> export`.
+func (b *CLIBackend) Get(ctx context.Context, uuid string) (*Task, error) {
+	tasks, err := b.tw.export(ctx, "uuid:"+uuid)
+	if err != nil {
+		return nil, err
+	}
+	return findTask(tasks, uuid)
+}
+
+// Store new task with `task import`.
+func (b *CLIBackend) Add(ctx context.Context, task *Task) (*Task, error) {
+	newTask := prepareNewTask(task, time.Now())
+	if err := b.importTask(ctx, &newTask); err != nil {
+		return nil, err
+	}
+	return b.Get(ctx, newTask.Uuid)
+}
+
+// Replace stored task with `task import`.
+func (b *CLIBackend) Modify(ctx context.Context, task *Task) (*Task, error) {
+	if _, err := b.Get(ctx, task.Uuid); err != nil {
+		return nil, err
+	}
+	if err := b.importTask(ctx, task); err != nil {
+		return nil, err
+	}
+	return b.Get(ctx, task.Uuid)
+}
+
+// Mark task as deleted with `task delete`.
+func (b *CLIBackend) Delete(ctx context.Context, uuid string) error {
+	rcOpt := "rc:" + b.tw.Config.ConfigPath
+	_, err := b.tw.run(ctx, nil, rcOpt, "rc.confirmation=off", "uuid:"+uuid, "delete")
+	return err
+}
+
+// Synchronize with `task sync`.
+func (b *CLIBackend) Sync(ctx context.Context) error {
+	rcOpt := "rc:" + b.tw.Config.ConfigPath
+	_, err := b.tw.run(ctx, nil, rcOpt, "sync")
+	return err
+}
+
+func (b *CLIBackend) importTask(ctx context.Context, task *Task) error {
+	caps, err := b.tw.capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	buf, err := EncodeTask(task, caps)
+	if err != nil {
+		return err
+	}
+	rcOpt := "rc:" + b.tw.Config.ConfigPath
+	_, err = b.tw.run(ctx, buf, rcOpt, "import", "-")
+	return err
+}
+
+// Return copy of the task with given UUID from the list.
+func findTask(tasks []Task, uuid string) (*Task, error) {
+	for i := range tasks {
+		if strings.EqualFold(tasks[i].Uuid, uuid) {
+			task := tasks[i]
+			return &task, nil
+		}
+	}
+	return nil, fmt.Errorf("task %s: %w", uuid, ErrNoMatchingTasks)
+}
+
+// Return copy of the task with defaults filled in for storing it as a new one.
+func prepareNewTask(task *Task, now time.Time) Task {
+	newTask := *task
+	newTask.Id = 0
+	if newTask.Uuid == "" {
+		newTask.Uuid = NewUUID()
+	}
+	if newTask.Status == "" {
+		newTask.Status = "pending"
+	}
+	if newTask.Entry.IsZero() {
+		newTask.Entry = NewTaskTime(now)
+	}
+	newTask.Modified = NewTaskTime(now)
+	for i := range newTask.Annotations {
+		if newTask.Annotations[i].Entry.IsZero() {
+			newTask.Annotations[i].Entry = NewTaskTime(now)
+		}
+	}
+	return newTask
+}
+
+// Change task status to deleted, like `task delete` does.
+func markDeleted(task *Task, now time.Time) {
+	task.Status = "deleted"
+	if task.End.IsZero() {
+		task.End = NewTaskTime(now)
+	}
+}
+
+// Check whether tasks with given status are stored with completed ones.
+func isCompletedStatus(status string) bool {
+	return status == "completed" || status == "deleted"
+}
@@ -0,0 +1,178 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Helper that checks common behavior of all backends on a database with no tasks.
+func testBackend(t *testing.T, backend Backend) {
+	ctx := context.Background()
+
+	added, err := backend.Add(ctx, &Task{
+		Description: "Backend task",
+		Project:     "work",
+		Tags:        []string{"a", "b"},
+		Due:         MustParseTaskTime("20260210T120000Z"),
+		Annotations: []Annotation{{Entry: MustParseTaskTime("20260206T120500Z"), Description: "Note"}},
+		UDA:         map[string]interface{}{"sprint": "S1"},
+	})
+	if err != nil {
+		t.Fatalf("Add fails with following error: %v", err)
+	}
+	if added.Uuid == "" || added.Status != "pending" || added.Entry.IsZero() || added.Id != 1 {
+		t.Errorf("Defaults were not filled for added task: %+v", added)
+	}
+	if added.Due.String() != "20260210T120000Z" || len(added.Tags) != 2 || added.UDA["sprint"] != "S1" {
+		t.Errorf("Attributes of added task were lost: %+v", added)
+	}
+	if len(added.Annotations) != 1 || added.Annotations[0].Description != "Note" {
+		t.Errorf("Annotations of added task were lost: %+v", added.Annotations)
+	}
+
+	added.Project = ""
+	added.Priority = "H"
+	added.Tags = []string{"b"}
+	modified, err := backend.Modify(ctx, added)
+	if err != nil {
+		t.Fatalf("Modify fails with following error: %v", err)
+	}
+	if modified.Project != "" || modified.Priority != "H" || len(modified.Tags) != 1 {
+		t.Errorf("Task was modified incorrectly: %+v", modified)
+	}
+
+	tasks, err := backend.List(ctx)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("List returned %d tasks (%v)", len(tasks), err)
+	}
+
+	if err := backend.Delete(ctx, added.Uuid); err != nil {
+		t.Fatalf("Delete fails with following error: %v", err)
+	}
+	deleted, err := backend.Get(ctx, added.Uuid)
+	if err != nil || deleted.Status != "deleted" || deleted.End.IsZero() || deleted.Id != 0 {
+		t.Errorf("Task was not deleted: %+v (%v)", deleted, err)
+	}
+
+	_, err = backend.Get(ctx, "00000000-0000-0000-0000-00000000ffff")
+	if !errors.Is(err, ErrNoMatchingTasks) {
+		t.Errorf("Expected ErrNoMatchingTasks for missing task, got %v", err)
+	}
+	_, err = backend.Modify(ctx, &Task{Uuid: "00000000-0000-0000-0000-00000000ffff", Description: "Missing"})
+	if !errors.Is(err, ErrNoMatchingTasks) {
+		t.Errorf("Expected ErrNoMatchingTasks for missing task, got %v", err)
+	}
+}
+
+func TestCLIBackend(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+	backend := NewCLIBackend(tw)
+	testBackend(t, backend)
+
+	if err := backend.Sync(context.Background()); err != nil {
+		t.Errorf("Sync fails with following error: %v", err)
+	}
+}
+
+func TestDataFileBackend(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, PendingDataFile), nil, 0600)
+	os.WriteFile(filepath.Join(dir, CompletedDataFile), nil, 0600)
+	backend := NewDataFileBackend(&TaskRC{DataLocation: dir})
+	testBackend(t, backend)
+
+	// Deleted task is moved to completed.data
+	pending, _ := ReadDataFile(filepath.Join(dir, PendingDataFile))
+	completed, _ := ReadDataFile(filepath.Join(dir, CompletedDataFile))
+	if len(pending) != 0 || len(completed) != 1 {
+		t.Errorf("Expected 0 pending and 1 completed tasks, got %d and %d", len(pending), len(completed))
+	}
+
+	backlog, _ := os.ReadFile(filepath.Join(dir, BacklogDataFile))
+	if len(backlog) == 0 {
+		t.Error("Changes were not recorded in backlog.data")
+	}
+
+	if !errors.Is(backend.Sync(context.Background()), ErrNotSupported) {
+		t.Error("Sync should not be supported")
+	}
+}
+
+func TestOpenBackend(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+
+	// Fixture directory with data files
+	backend, err := OpenBackend(tw)
+	if _, ok := backend.(*DataFileBackend); !ok || err != nil {
+		t.Errorf("Expected DataFileBackend, got %T (%v)", backend, err)
+	}
+
+	// Empty directory
+	tw.Config.DataLocation = t.TempDir()
+	backend, err = OpenBackend(tw)
+	if _, ok := backend.(*CLIBackend); !ok || err != nil {
+		t.Errorf("Expected CLIBackend, got %T (%v)", backend, err)
+	}
+
+	// TaskChampion replica
+	createChampionReplica(t, tw.Config.DataLocation)
+	backend, err = OpenBackend(tw)
+	if _, ok := backend.(*TaskChampionBackend); !ok || err != nil {
+		t.Errorf("Expected TaskChampionBackend, got %T (%v)", backend, err)
+	}
+	backend.(*TaskChampionBackend).Close()
+
+	// Both storages after upgrade, chosen by version
+	os.WriteFile(filepath.Join(tw.Config.DataLocation, PendingDataFile), nil, 0644)
+	backend, err = OpenBackend(tw)
+	if _, ok := backend.(*DataFileBackend); !ok || err != nil {
+		t.Errorf("Expected DataFileBackend for taskwarrior 2, got %T (%v)", backend, err)
+	}
+	tw.Runner = &FakeRunner{Version: "3.1.0"}
+	backend, err = OpenBackend(tw)
+	if _, ok := backend.(*TaskChampionBackend); !ok || err != nil {
+		t.Errorf("Expected TaskChampionBackend for taskwarrior 3, got %T (%v)", backend, err)
+	}
+	backend.(*TaskChampionBackend).Close()
+
+	// SQLite driver is not imported
+	defer func(driver string) { TaskChampionDriver = driver }(TaskChampionDriver)
+	TaskChampionDriver = "unregistered"
+	backend, err = OpenBackend(tw)
+	if _, ok := backend.(*CLIBackend); !ok || err != nil {
+		t.Errorf("Expected CLIBackend without SQLite driver, got %T (%v)", backend, err)
+	}
+}
+
+func TestComposeDataLine(t *testing.T) {
+	task := &Task{
+		Description: `Quote " and [brackets] / é`,
+		Status:      "pending",
+		Uuid:        "00000000-0000-0000-0000-000000000001",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
+		Tags:        []string{"a", "b"},
+		Imask:       2,
+		UDA:         map[string]interface{}{"estimate": 2.5, "reviewed": NewTaskTime(time.Unix(1770379200, 0))},
+	}
+	line, err := ComposeDataLine(task)
+	if err != nil {
+		t.Fatalf("ComposeDataLine fails with following error: %v", err)
+	}
+	expected := `[description:"Quote \" and [brackets] / é" entry:"1770379200" estimate:"2.5" imask:"2" ` +
+		`reviewed:"1770379200" status:"pending" tags:"a,b" uuid:"00000000-0000-0000-0000-000000000001"]`
+	if line != expected {
+		t.Errorf("Incorrect line:\nexpected %s\ngot      %s", expected, line)
+	}
+
+	parsed, err := ParseDataLine(line)
+	if err != nil || parsed.Description != task.Description || parsed.Imask != 2 {
+		t.Errorf("Composed line can't be parsed back: %+v (%v)", parsed, err)
+	}
+}
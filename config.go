@@ -0,0 +1,195 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Layered configuration resolution.
+//
+// Taskwarrior builds its configuration from several sources, each one overriding the previous:
+//
+//  1. Built-in defaults.
+//  2. The rc file (and files included from it). The file is taken from `rc:<path>` argument, TASKRC environment
+//     variable, ~/.taskrc or $XDG_CONFIG_HOME/task/taskrc, whichever comes first.
+//  3. TASKDATA environment variable, which overrides data.location.
+//  4. `rc.<name>=<value>` command-line arguments.
+
+package taskwarrior
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Origin describes where configuration value came from.
+type Origin int
+
+const (
+	OriginUnset    Origin = iota // Value is not set
+	OriginDefault                // Built-in default value
+	OriginFile                   // The rc file
+	OriginInclude                // File included from the rc file
+	OriginEnv                    // Environment variable
+	OriginOverride               // `rc.<name>=<value>` override
+)
+
+func (o Origin) String() string {
+	switch o {
+	case OriginDefault:
+		return "default"
+	case OriginFile:
+		return "file"
+	case OriginInclude:
+		return "include"
+	case OriginEnv:
+		return "env"
+	case OriginOverride:
+		return "override"
+	}
+	return "unset"
+}
+
+// Built-in taskwarrior defaults for options used by this library.
+var DefaultConfig = map[string]string{
+	"data.location":                      "~/.task",
+	"confirmation":                       "yes",
+	"dateformat":                         "Y-M-D",
+	"weekstart":                          "sunday",
+	"search.case.sensitive":              "yes",
+	"regex":                              "yes",
+	"hooks":                              "yes",
+	"recurrence":                         "yes",
+	"recurrence.limit":                   "1",
+	"json.array":                         "yes",
+	"json.depends.array":                 "yes",
+	"uda.priority.type":                  "string",
+	"uda.priority.label":                 "Priority",
+	"uda.priority.values":                "H,M,L,",
+	"urgency.user.tag.next.coefficient":  "15.0",
+	"urgency.due.coefficient":            "12.0",
+	"urgency.blocking.coefficient":       "8.0",
+	"urgency.uda.priority.H.coefficient": "6.0",
+	"urgency.uda.priority.M.coefficient": "3.9",
+	"urgency.uda.priority.L.coefficient": "1.8",
+	"urgency.scheduled.coefficient":      "5.0",
+	"urgency.active.coefficient":         "4.0",
+	"urgency.age.coefficient":            "2.0",
+	"urgency.annotations.coefficient":    "1.0",
+	"urgency.tags.coefficient":           "1.0",
+	"urgency.project.coefficient":        "1.0",
+	"urgency.blocked.coefficient":        "-5.0",
+	"urgency.waiting.coefficient":        "-3.0",
+	"urgency.age.max":                    "365",
+	"urgency.inherit":                    "no",
+	"due":                                "7",
+}
+
+// ConfigSources describes inputs of configuration resolution.
+type ConfigSources struct {
+	Path      string              // Explicit rc file path, like `rc:<path>` argument
+	Overrides map[string]string   // Values of `rc.<name>=<value>` arguments
+	Getenv    func(string) string // Environment lookup, os.Getenv if nil
+}
+
+// Build configuration from defaults, rc file, environment and overrides the same way taskwarrior does.
+//
+// Explicitly given rc file must exist. When the file is found implicitly and doesn't exist, the configuration
+// consists of defaults and overrides only, like in fresh taskwarrior installation.
+func ResolveTaskRC(src ConfigSources) (*TaskRC, error) {
+	getenv := src.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	c := &TaskRC{}
+	for key, val := range DefaultConfig {
+		c.set(key, val, OriginDefault)
+	}
+
+	// Read the rc file
+	path, explicit := PathExpandTilda(src.Path), src.Path != ""
+	if !explicit {
+		path, explicit = findTaskRC(getenv)
+	}
+	c.ConfigPath = path
+	if err := c.parseFile(path, nil); err != nil {
+		if explicit || !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	if dataDir := getenv("TASKDATA"); dataDir != "" {
+		c.set("data.location", dataDir, OriginEnv)
+	}
+
+	for key, val := range src.Overrides {
+		c.set(key, val, OriginOverride)
+	}
+
+	c.mapValues()
+	return c, nil
+}
+
+// Find location of the rc file. Returns whether the location was set explicitly with TASKRC environment variable.
+func findTaskRC(getenv func(string) string) (string, bool) {
+	if path := getenv("TASKRC"); path != "" {
+		return PathExpandTilda(path), true
+	}
+	if _, err := os.Stat(TASKRC); err == nil {
+		return TASKRC, false
+	}
+
+	configHome := getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = PathExpandTilda("~/.config")
+	}
+	xdgPath := filepath.Join(configHome, "task", "taskrc")
+	if _, err := os.Stat(xdgPath); err == nil {
+		return xdgPath, false
+	}
+
+	return TASKRC, false
+}
+
+// Set configuration value with given origin.
+func (c *TaskRC) set(key, val string, origin Origin) {
+	if c.values == nil {
+		c.values = map[string]string{}
+		c.origins = map[string]Origin{}
+	}
+	c.values[key] = val
+	c.origins[key] = origin
+}
+
+// Return source of configuration value.
+func (c *TaskRC) Origin(key string) Origin {
+	return c.origins[key]
+}
+
+// Override configuration value, like `rc.<name>=<value>` argument does. TaskWarrior instances pass overrides of
+// their configuration to every `task` call.
+func (c *TaskRC) Override(key, val string) {
+	c.set(key, val, OriginOverride)
+	c.mapValues()
+}
+
+// Return `rc.<name>=<value>` arguments for all overridden values, in sorted order.
+func (c *TaskRC) OverrideArgs() []string {
+	var args []string
+	for key, origin := range c.origins {
+		if origin == OriginOverride {
+			args = append(args, fmt.Sprintf("rc.%s=%s", key, c.values[key]))
+		}
+	}
+	sort.Strings(args)
+	return args
+}
+
+// Return value of configuration option or its built-in default. Nil configuration has defaults only.
+func (c *TaskRC) valueOrDefault(key string) string {
+	if c != nil {
+		if val, ok := c.values[key]; ok {
+			return val
+		}
+	}
+	return DefaultConfig[key]
+}
@@ -0,0 +1,122 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Helper that returns environment lookup function for given variables.
+func fakeEnv(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestResolveTaskRC(t *testing.T) {
+	// Explicit path with includes, environment and overrides
+	config, err := ResolveTaskRC(ConfigSources{
+		Path:      "./fixtures/taskrc/include_1",
+		Overrides: map[string]string{"weekstart": "sunday", "confirmation": "off"},
+		Getenv:    fakeEnv(map[string]string{"TASKDATA": "/tmp/taskdata"}),
+	})
+	if err != nil {
+		t.Fatalf("ResolveTaskRC fails with following error: %v", err)
+	}
+
+	expected := []struct {
+		key    string
+		value  string
+		origin Origin
+	}{
+		{"dateformat", "Y-M-D", OriginDefault},
+		{"urgency.due.coefficient", "12.0", OriginFile},
+		{"default.project", "inbox", OriginInclude},
+		{"data.location", "/tmp/taskdata", OriginEnv},
+		{"weekstart", "sunday", OriginOverride},
+		{"confirmation", "off", OriginOverride},
+		{"not.set", "", OriginUnset},
+	}
+	for _, e := range expected {
+		if config.Get(e.key) != e.value || config.Origin(e.key) != e.origin {
+			t.Errorf("Incorrect value of '%s': expected '%s' (%s) got '%s' (%s)",
+				e.key, e.value, e.origin, config.Get(e.key), config.Origin(e.key))
+		}
+	}
+	if config.DataLocation != "/tmp/taskdata" {
+		t.Errorf("TASKDATA should override DataLocation, got '%s'", config.DataLocation)
+	}
+
+	args := config.OverrideArgs()
+	if len(args) != 2 || args[0] != "rc.confirmation=off" || args[1] != "rc.weekstart=sunday" {
+		t.Errorf("Unexpected override arguments: %v", args)
+	}
+
+	// Missing explicit path
+	_, err = ResolveTaskRC(ConfigSources{Path: "./fixtures/not_exists/33"})
+	if err == nil {
+		t.Error("ResolveTaskRC works with non-existent config")
+	}
+}
+
+func TestResolveTaskRC_Location(t *testing.T) {
+	// TASKRC environment variable
+	config, err := ResolveTaskRC(ConfigSources{
+		Getenv: fakeEnv(map[string]string{"TASKRC": "./fixtures/taskrc/simple_1"}),
+	})
+	if err != nil {
+		t.Fatalf("ResolveTaskRC fails with following error: %v", err)
+	}
+	if config.ConfigPath != "./fixtures/taskrc/simple_1" || config.Origin("data.location") != OriginFile {
+		t.Errorf("TASKRC environment variable was ignored: %s", config.ConfigPath)
+	}
+
+	_, err = ResolveTaskRC(ConfigSources{
+		Getenv: fakeEnv(map[string]string{"TASKRC": "./fixtures/not_exists/33"}),
+	})
+	if err == nil {
+		t.Error("ResolveTaskRC works with non-existent TASKRC")
+	}
+
+	// XDG location, used only when ~/.taskrc doesn't exist
+	if _, err := os.Stat(TASKRC); err == nil {
+		t.Skip("~/.taskrc exists")
+	}
+	xdg := t.TempDir()
+	os.MkdirAll(filepath.Join(xdg, "task"), 0755)
+	os.WriteFile(filepath.Join(xdg, "task", "taskrc"), []byte("weekstart=monday\n"), 0644)
+	config, err = ResolveTaskRC(ConfigSources{Getenv: fakeEnv(map[string]string{"XDG_CONFIG_HOME": xdg})})
+	if err != nil {
+		t.Fatalf("ResolveTaskRC fails with following error: %v", err)
+	}
+	if config.Get("weekstart") != "monday" {
+		t.Errorf("XDG configuration was ignored: %s", config.ConfigPath)
+	}
+
+	// Nothing found: defaults only
+	config, err = ResolveTaskRC(ConfigSources{Getenv: fakeEnv(map[string]string{"XDG_CONFIG_HOME": t.TempDir()})})
+	if err != nil {
+		t.Fatalf("ResolveTaskRC fails without configuration file: %v", err)
+	}
+	if config.ConfigPath != TASKRC || config.Get("weekstart") != "sunday" {
+		t.Errorf("Unexpected configuration without rc file: %s %s", config.ConfigPath, config.Get("weekstart"))
+	}
+}
+
+func TestTaskWarrior_Overrides(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+	tw.Config.Override("confirmation", "off")
+	if err := tw.FetchAllTasks(); err != nil {
+		t.Fatalf("FetchAllTasks fails with following error: %v", err)
+	}
+
+	calls := tw.Runner.(*FakeRunner).Calls
+	found := false
+	for _, arg := range calls[0].Args {
+		found = found || arg == "rc.confirmation=off"
+	}
+	if !found {
+		t.Errorf("Override was not passed to task command: %v", calls[0].Args)
+	}
+}
@@ -0,0 +1,608 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Reader for taskwarrior 2.x data files.
+//
+// Taskwarrior 2.x keeps tasks in pending.data and completed.data files inside `data.location` directory. Each line
+// describes a single task in FF4 format:
+//
+//	[description:"Buy milk" entry:"1517745313" status:"pending" tags:"home,shop" uuid:"..."]
+//
+// Values are JSON-encoded strings where '[', ']' and '"' may be replaced with &open;, &close; and &dquot;. Dates are
+// Unix timestamps, tags and dependencies are comma-separated lists, annotations are stored as
+// annotation_<timestamp> attributes.
+//
+// Files of older formats (FF1-FF3, used before taskwarrior 1.9.3) are detected and reported as FormatError.
+
+package taskwarrior
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of taskwarrior 2.x data files.
+const (
+	PendingDataFile   = "pending.data"
+	CompletedDataFile = "completed.data"
+)
+
+// FormatError is returned when data file line can't be parsed.
+type FormatError struct {
+	Path    string // Data file
+	Line    int    // Line number, starting from 1
+	Version string // Detected file format: "FF1", "FF2", "FF3" or "FF4"
+	Err     error
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s:%d: %s: %v", e.Path, e.Line, e.Version, e.Err)
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// Returned for lines in legacy formats that are not supported by the reader.
+var ErrLegacyFormat = errors.New("legacy data format is not supported, run taskwarrior 2.x to upgrade it")
+
+// Line prefix of legacy formats: the task UUID followed by a status character.
+var reLegacyLine = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12} [-+XRW] `)
+
+// Name of the file with changes not yet sent to the sync server.
+const BacklogDataFile = "backlog.data"
+
+// DataFileBackend reads and writes tasks directly in taskwarrior 2.x data files without calling the `task` binary.
+//
+// Writes don't run taskwarrior hooks and don't record undo information. Changed tasks are appended to backlog.data,
+// so they are sent to the server on the next `task sync`. Data files are locked while they are changed and rewritten
+// in place, like taskwarrior 2.x does with `locking=on`, so `task` commands running at the same time wait for the
+// change. Like with taskwarrior itself, a crash in the middle of a write may leave a file truncated.
+type DataFileBackend struct {
+	Dir    string           // Data directory containing pending.data and completed.data
+	Config *TaskRC          // Configuration used to convert UDA values, may be nil
+	Now    func() time.Time // Clock used for entry and modified timestamps, time.Now if nil
+}
+
+// Create backend for data directory of given configuration.
+func NewDataFileBackend(config *TaskRC) *DataFileBackend {
+	return &DataFileBackend{Dir: PathExpandTilda(config.DataLocation), Config: config}
+}
+
+// Return all tasks from pending.data and completed.data. Pending and waiting tasks get IDs in the order they are
+// stored, like in taskwarrior.
+func (b *DataFileBackend) List(ctx context.Context) ([]Task, error) {
+	var tasks []Task
+	for _, name := range []string{PendingDataFile, CompletedDataFile} {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fileTasks, err := ReadDataFile(filepath.Join(b.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, fileTasks...)
+	}
+
+	var id int32
+	for i := range tasks {
+		if tasks[i].Status == "pending" || tasks[i].Status == "waiting" {
+			id++
+			tasks[i].Id = id
+		}
+		if b.Config != nil {
+			b.Config.ConvertUDA(&tasks[i])
+		}
+	}
+	return tasks, nil
+}
+
+// Return task with given UUID.
+func (b *DataFileBackend) Get(ctx context.Context, uuid string) (*Task, error) {
+	tasks, err := b.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return findTask(tasks, uuid)
+}
+
+// Add new task to the data files. Missing UUID, status and entry date are filled in.
+func (b *DataFileBackend) Add(ctx context.Context, task *Task) (*Task, error) {
+	newTask := prepareNewTask(task, b.now())
+	err := b.Update(ctx, func(files *DataFiles) error {
+		if _, err := findTask(append(files.Pending, files.Completed...), newTask.Uuid); err == nil {
+			return fmt.Errorf("task %s already exists", newTask.Uuid)
+		}
+		if isCompletedStatus(newTask.Status) {
+			files.Completed = append(files.Completed, newTask)
+		} else {
+			files.Pending = append(files.Pending, newTask)
+		}
+		return files.addBacklog(&newTask)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Get(ctx, newTask.Uuid)
+}
+
+// Replace stored task having the same UUID with given one. Tasks are moved between pending.data and completed.data
+// according to their status.
+func (b *DataFileBackend) Modify(ctx context.Context, task *Task) (*Task, error) {
+	err := b.replace(ctx, task.Uuid, func(stored *Task) { *stored = *task })
+	if err != nil {
+		return nil, err
+	}
+	return b.Get(ctx, task.Uuid)
+}
+
+// Mark task as deleted, like `task delete` does.
+func (b *DataFileBackend) Delete(ctx context.Context, uuid string) error {
+	return b.replace(ctx, uuid, func(stored *Task) { markDeleted(stored, b.now()) })
+}
+
+// Change stored task with given UUID and set its modification time.
+func (b *DataFileBackend) replace(ctx context.Context, uuid string, change func(stored *Task)) error {
+	return b.Update(ctx, func(files *DataFiles) error {
+		var modified *Task
+		var pending, completed []Task
+		for _, t := range append(files.Pending, files.Completed...) {
+			if modified == nil && strings.EqualFold(t.Uuid, uuid) {
+				change(&t)
+				t.Modified = NewTaskTime(b.now())
+				modified = &t
+			}
+			if isCompletedStatus(t.Status) {
+				completed = append(completed, t)
+			} else {
+				pending = append(pending, t)
+			}
+		}
+		if modified == nil {
+			return fmt.Errorf("task %s: %w", uuid, ErrNoMatchingTasks)
+		}
+		files.Pending, files.Completed = pending, completed
+		return files.addBacklog(modified)
+	})
+}
+
+// Synchronization requires the `task` binary or a sync client.
+func (b *DataFileBackend) Sync(ctx context.Context) error {
+	return ErrNotSupported
+}
+
+// Content of taskwarrior 2.x data files changed with DataFileBackend.Update.
+type DataFiles struct {
+	Pending   []Task   // Tasks of pending.data
+	Completed []Task   // Tasks of completed.data
+	Backlog   []string // Non-empty lines of backlog.data: the sync key and JSON-encoded changed tasks
+}
+
+// Record changed task in the backlog, so it is sent to the server on the next sync.
+func (f *DataFiles) addBacklog(task *Task) error {
+	line, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	f.Backlog = append(f.Backlog, string(line))
+	return nil
+}
+
+// Run fn on the content of data files and write back the files it changes. Missing files are created. Tasks are
+// passed without any conversions and get no IDs.
+//
+// Files are locked for the whole call, like taskwarrior locks them with `locking=on`, and rewritten in place: a
+// `task` process waiting for the lock of a replaced file would write into the old one. Completed tasks are written
+// first, so a task moved from pending.data is never missing from both files.
+func (b *DataFileBackend) Update(ctx context.Context, fn func(files *DataFiles) error) error {
+	names := []string{PendingDataFile, CompletedDataFile, BacklogDataFile}
+	var unlocks []func()
+	defer func() {
+		for _, unlock := range unlocks {
+			unlock()
+		}
+	}()
+	// Files are always locked in the same order, so backends don't deadlock each other.
+	for _, name := range names {
+		path := filepath.Join(b.Dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		unlock, err := lockFile(ctx, path)
+		if err != nil {
+			return err
+		}
+		unlocks = append(unlocks, unlock)
+	}
+
+	var files DataFiles
+	var err error
+	if files.Pending, err = ReadDataFile(filepath.Join(b.Dir, PendingDataFile)); err != nil {
+		return err
+	}
+	if files.Completed, err = ReadDataFile(filepath.Join(b.Dir, CompletedDataFile)); err != nil {
+		return err
+	}
+	backlog, err := os.ReadFile(filepath.Join(b.Dir, BacklogDataFile))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(backlog), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files.Backlog = append(files.Backlog, line)
+		}
+	}
+
+	before, err := composeDataFiles(&files)
+	if err != nil {
+		return err
+	}
+	if err := fn(&files); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	after, err := composeDataFiles(&files)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{CompletedDataFile, PendingDataFile, BacklogDataFile} {
+		if bytes.Equal(before[name], after[name]) {
+			continue
+		}
+		if err := rewriteFile(filepath.Join(b.Dir, name), after[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return content of each data file by its name.
+func composeDataFiles(files *DataFiles) (map[string][]byte, error) {
+	pending, err := composeDataFile(files.Pending)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := composeDataFile(files.Completed)
+	if err != nil {
+		return nil, err
+	}
+	var backlog []byte
+	for _, line := range files.Backlog {
+		backlog = append(backlog, line+"\n"...)
+	}
+	return map[string][]byte{PendingDataFile: pending, CompletedDataFile: completed, BacklogDataFile: backlog}, nil
+}
+
+func (b *DataFileBackend) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// Truncate the file and write given data into it, keeping the file and locks taken on it.
+func rewriteFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Return content of data file with given tasks in FF4 format.
+func composeDataFile(tasks []Task) ([]byte, error) {
+	var buf strings.Builder
+	for i := range tasks {
+		line, err := ComposeDataLine(&tasks[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return []byte(buf.String()), nil
+}
+
+// Write tasks to data file in FF4 format. The file is replaced atomically, so it is never seen half-written, but a
+// running taskwarrior may keep using the replaced file: use DataFileBackend to change files of a live database.
+func WriteDataFile(path string, tasks []Task) error {
+	data, err := composeDataFile(tasks)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode())
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Compose line of data file in FF4 format. Attributes are sorted by name, like taskwarrior does.
+func ComposeDataLine(task *Task) (string, error) {
+	attrs, err := flattenTask(task)
+	if err != nil {
+		return "", err
+	}
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var line strings.Builder
+	line.WriteByte('[')
+	for i, key := range keys {
+		if i > 0 {
+			line.WriteByte(' ')
+		}
+		line.WriteString(key)
+		line.WriteString(`:"`)
+		line.WriteString(encodeFF4Value(attrs[key]))
+		line.WriteByte('"')
+	}
+	line.WriteByte(']')
+	return line.String(), nil
+}
+
+// Read all tasks from single data file.
+func ReadDataFile(path string) ([]Task, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var tasks []Task
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		task, err := ParseDataLine(line)
+		if err != nil {
+			var formatErr *FormatError
+			if errors.As(err, &formatErr) {
+				formatErr.Path, formatErr.Line = path, n
+				return nil, formatErr
+			}
+			return nil, &FormatError{Path: path, Line: n, Version: "FF4", Err: err}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// Parse single line of data file in FF4 format.
+func ParseDataLine(line string) (Task, error) {
+	var task Task
+	if version := detectLegacyFormat(line); version != "" {
+		return task, &FormatError{Version: version, Err: ErrLegacyFormat}
+	}
+
+	attrs, err := parseFF4(line)
+	if err != nil {
+		return task, err
+	}
+
+	return unflattenTask(attrs)
+}
+
+// Attributes of Task with date values.
+var taskDateKeys = map[string]bool{
+	"entry": true, "due": true, "start": true, "end": true, "until": true, "wait": true, "scheduled": true,
+	"modified": true,
+}
+
+// Convert flat string attributes used by taskwarrior storages to Task. Dates are Unix timestamps, tags and
+// dependencies are comma-separated lists and annotations are stored as annotation_<timestamp> attributes.
+func unflattenTask(attrs map[string]string) (Task, error) {
+	var task Task
+
+	// Convert attributes to taskwarrior JSON object and decode it as usual
+	obj := map[string]interface{}{}
+	var annotations []Annotation
+	for key, val := range attrs {
+		switch {
+		case key == "tags" || key == "depends":
+			if val != "" {
+				obj[key] = strings.Split(val, ",")
+			}
+		case key == "imask":
+			imask, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return task, fmt.Errorf("invalid imask '%s'", val)
+			}
+			obj[key] = int(imask)
+		case strings.HasPrefix(key, "annotation_"):
+			entry, err := ParseTaskTime(strings.TrimPrefix(key, "annotation_"))
+			if err != nil {
+				return task, err
+			}
+			annotations = append(annotations, Annotation{Entry: entry, Description: val})
+		default:
+			obj[key] = val
+		}
+	}
+	sort.Slice(annotations, func(i, j int) bool { return annotations[i].Entry.Before(annotations[j].Entry.Time) })
+	if len(annotations) > 0 {
+		obj["annotations"] = annotations
+	}
+
+	buf, err := json.Marshal(obj)
+	if err != nil {
+		return task, err
+	}
+	err = json.Unmarshal(buf, &task)
+	return task, err
+}
+
+// Convert Task to flat string attributes, reverse of unflattenTask. Working set ID and urgency are not stored.
+func flattenTask(task *Task) (map[string]string, error) {
+	buf, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(buf, &obj); err != nil {
+		return nil, err
+	}
+	delete(obj, "id")
+	delete(obj, "urgency")
+	delete(obj, "annotations")
+
+	attrs := map[string]string{}
+	for key, val := range obj {
+		switch v := val.(type) {
+		case string:
+			_, isDateUDA := task.UDA[key].(TaskTime)
+			if _, ok := task.UDA[key].(time.Time); ok {
+				isDateUDA = true
+			}
+			if taskDateKeys[key] || isDateUDA {
+				date, err := ParseTaskTime(v)
+				if err != nil {
+					return nil, err
+				}
+				v = strconv.FormatInt(date.Unix(), 10)
+			}
+			attrs[key] = v
+		case float64:
+			attrs[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			attrs[key] = strconv.FormatBool(v)
+		case []interface{}:
+			var items []string
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			attrs[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			return nil, fmt.Errorf("unsupported value of '%s': %v", key, val)
+		}
+	}
+	for _, annotation := range task.Annotations {
+		attrs["annotation_"+strconv.FormatInt(annotation.Entry.Unix(), 10)] = annotation.Description
+	}
+	return attrs, nil
+}
+
+// Split FF4 line on attributes.
+func parseFF4(line string) (map[string]string, error) {
+	if len(line) < 2 || line[0] != '[' || line[len(line)-1] != ']' {
+		return nil, errors.New("line is not enclosed in brackets")
+	}
+	body := line[1 : len(line)-1]
+
+	attrs := map[string]string{}
+	for pos := 0; pos < len(body); {
+		if body[pos] == ' ' {
+			pos++
+			continue
+		}
+
+		// Attribute name up to the colon
+		colon := strings.Index(body[pos:], ":\"")
+		if colon <= 0 {
+			return nil, fmt.Errorf("malformed attribute at offset %d", pos+1)
+		}
+		name := body[pos : pos+colon]
+		pos += colon + 2
+
+		// Quoted value up to unescaped quote
+		end := pos
+		for end < len(body) && body[end] != '"' {
+			if body[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(body) {
+			return nil, fmt.Errorf("unterminated value of '%s'", name)
+		}
+		val, err := decodeFF4Value(body[pos:end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s': %w", name, err)
+		}
+		attrs[name] = val
+		pos = end + 1
+	}
+	return attrs, nil
+}
+
+var ff4Entities = strings.NewReplacer("&open;", "[", "&close;", "]", "&dquot;", `"`)
+
+// Decode value of FF4 attribute.
+func decodeFF4Value(raw string) (string, error) {
+	val := raw
+	if strings.Contains(raw, `\`) {
+		if err := json.Unmarshal([]byte(`"`+raw+`"`), &val); err != nil {
+			return "", err
+		}
+	}
+	return ff4Entities.Replace(val), nil
+}
+
+// Encode value of FF4 attribute as JSON string body.
+func encodeFF4Value(val string) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(val)
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	return encoded[1 : len(encoded)-1]
+}
+
+// Return name of legacy format of the line or empty string for FF4.
+func detectLegacyFormat(line string) string {
+	if !reLegacyLine.MatchString(line) {
+		return ""
+	}
+	// FF1 has tags and attributes sections, FF2 and FF3 add annotations section. FF3 annotations keep their
+	// text in quotes.
+	sections := strings.Count(line, "] [") + 1
+	switch {
+	case sections < 3:
+		return "FF1"
+	case strings.Contains(line, `:"`):
+		return "FF3"
+	}
+	return "FF2"
+}
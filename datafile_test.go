@@ -0,0 +1,171 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDataLine(t *testing.T) {
+	line := `[description:"Say &dquot;hi&dquot; é" entry:"1770379200" status:"pending" tags:"a,b" ` +
+		`uuid:"00000000-0000-0000-0000-000000000001" annotation_1770379200:"Note" imask:"2" sprint:"S1"]`
+	task, err := ParseDataLine(line)
+	if err != nil {
+		t.Fatalf("ParseDataLine fails with following error: %v", err)
+	}
+	if task.Description != `Say "hi" é` {
+		t.Errorf("Incorrect description: '%s'", task.Description)
+	}
+	if task.Entry.String() != "20260206T120000Z" {
+		t.Errorf("Incorrect entry: '%s'", task.Entry)
+	}
+	if len(task.Tags) != 2 || task.Tags[1] != "b" {
+		t.Errorf("Incorrect tags: %v", task.Tags)
+	}
+	if len(task.Annotations) != 1 || task.Annotations[0].Description != "Note" ||
+		task.Annotations[0].Entry.String() != "20260206T120000Z" {
+		t.Errorf("Incorrect annotations: %v", task.Annotations)
+	}
+	if task.Imask != 2 {
+		t.Errorf("Incorrect imask: %d", task.Imask)
+	}
+	if task.UDA["sprint"] != "S1" {
+		t.Errorf("Incorrect UDA: %v", task.UDA)
+	}
+
+	// Malformed lines
+	for _, bad := range []string{`description:"No brackets"`, `[description:"Unterminated]`, `[description]`} {
+		if _, err := ParseDataLine(bad); err == nil {
+			t.Errorf("ParseDataLine accepts malformed line: %s", bad)
+		}
+	}
+
+	// Legacy formats
+	legacy := map[string]string{
+		"FF1": `00000000-0000-0000-0000-000000000001 - [tag] [project:work] Description`,
+		"FF2": `00000000-0000-0000-0000-000000000001 - [tag] [project:work] [1170379200:note] Description`,
+		"FF3": `00000000-0000-0000-0000-000000000001 - [tag] [project:"work"] [1170379200:"note"] Description`,
+	}
+	for version, line := range legacy {
+		_, err := ParseDataLine(line)
+		var formatErr *FormatError
+		if !errors.As(err, &formatErr) || formatErr.Version != version || !errors.Is(err, ErrLegacyFormat) {
+			t.Errorf("Expected %s format error, got %v", version, err)
+		}
+	}
+}
+
+func TestDataFileBackend_List(t *testing.T) {
+	config, err := ParseTaskRC("./fixtures/taskrc/simple_1")
+	if err != nil {
+		t.Fatalf("Can't parse configuration file: %v", err)
+	}
+	config.UDA = map[string]string{"estimate": "numeric"}
+
+	tasks, err := NewDataFileBackend(config).List(context.Background())
+	if err != nil {
+		t.Fatalf("List fails with following error: %v", err)
+	}
+	if len(tasks) != 6 {
+		t.Fatalf("Expected 6 tasks, got %d", len(tasks))
+	}
+
+	first := tasks[0]
+	if first.Description != "Write [draft] report" || first.Project != "work" || first.Id != 1 {
+		t.Errorf("Incorrect first task: %+v", first)
+	}
+	if len(first.Annotations) != 2 || first.Annotations[0].Description != `First "quoted" note` {
+		t.Errorf("Annotations are incorrect or unsorted: %v", first.Annotations)
+	}
+	if first.UDA["estimate"] != 3.0 {
+		t.Errorf("Numeric UDA was not converted: %v", first.UDA["estimate"])
+	}
+	if tasks[1].Id != 2 || len(tasks[1].Depends) != 1 || tasks[1].Wait.IsZero() {
+		t.Errorf("Incorrect waiting task: %+v", tasks[1])
+	}
+	if tasks[2].Id != 0 || tasks[2].Recur != "weekly" || tasks[3].Id != 3 || tasks[3].Parent != tasks[2].Uuid {
+		t.Errorf("Incorrect recurring tasks: %+v %+v", tasks[2], tasks[3])
+	}
+	if tasks[4].Status != "completed" || tasks[4].Id != 0 || tasks[4].End.IsZero() {
+		t.Errorf("Incorrect completed task: %+v", tasks[4])
+	}
+
+	// Missing data files
+	for _, config := range []string{"./fixtures/taskrc/err_paths_1", "./fixtures/taskrc/err_paths_2"} {
+		rc, _ := ParseTaskRC(config)
+		if _, err := NewDataFileBackend(rc).List(context.Background()); err == nil {
+			t.Errorf("List works with missing data files of %s", config)
+		}
+	}
+}
+
+func TestTaskWarrior_FetchAllTasksBackend(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+	tw.Backend = NewDataFileBackend(tw.Config)
+	if err := tw.FetchAllTasks(); err != nil {
+		t.Fatalf("FetchAllTasks fails with following error: %v", err)
+	}
+	if len(tw.Tasks) != 6 {
+		t.Errorf("Expected 6 tasks, got %d", len(tw.Tasks))
+	}
+	if len(tw.Runner.(*FakeRunner).Calls) != 0 {
+		t.Error("FetchAllTasks called task command with backend set")
+	}
+}
+
+func TestDataFileBackend_Update(t *testing.T) {
+	dir := t.TempDir()
+	backend := &DataFileBackend{Dir: dir}
+	ctx := context.Background()
+
+	// Missing files are created
+	err := backend.Update(ctx, func(files *DataFiles) error {
+		files.Pending = append(files.Pending, Task{Uuid: "00000000-0000-0000-0000-000000000001", Description: "Buy milk", Status: "pending"})
+		files.Backlog = append(files.Backlog, "key")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update fails with following error: %v", err)
+	}
+	if tasks, err := backend.List(ctx); err != nil || len(tasks) != 1 {
+		t.Errorf("Unexpected tasks: %+v (%v)", tasks, err)
+	}
+
+	// Files are written only when they are changed
+	completed, _ := os.Stat(filepath.Join(dir, CompletedDataFile))
+	backlog, _ := os.Stat(filepath.Join(dir, BacklogDataFile))
+	err = backend.Update(ctx, func(files *DataFiles) error {
+		if len(files.Backlog) != 1 || files.Backlog[0] != "key" {
+			t.Errorf("Unexpected backlog: %q", files.Backlog)
+		}
+		files.Backlog = nil
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(filepath.Join(dir, CompletedDataFile)); !info.ModTime().Equal(completed.ModTime()) {
+		t.Error("Unchanged completed.data was written")
+	}
+	if info, _ := os.Stat(filepath.Join(dir, BacklogDataFile)); info.Size() != 0 || !os.SameFile(info, backlog) {
+		t.Errorf("Backlog should be truncated in place, got %d bytes", info.Size())
+	}
+
+	// Failed change is not written
+	failure := errors.New("failure")
+	err = backend.Update(ctx, func(files *DataFiles) error {
+		files.Pending = nil
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("Expected error of the change, got %v", err)
+	}
+	if tasks, _ := ReadDataFile(filepath.Join(dir, PendingDataFile)); len(tasks) != 1 {
+		t.Errorf("Pending tasks were changed: %+v", tasks)
+	}
+}
@@ -0,0 +1,360 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Parsing of date expressions.
+//
+// Taskwarrior accepts dates in several forms: named dates (tomorrow, eom, monday), dates in the format of
+// `dateformat` option, ISO 8601 dates and durations relative to now, and sums of them (now+3d, eow-1wk). See
+// https://taskwarrior.org/docs/dates/ and https://taskwarrior.org/docs/named_dates/. Dates without time zone are
+// in the location of the current time.
+
+package taskwarrior
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parse date expression relative to given time. Configuration provides `dateformat` and `weekstart` options, nil
+// configuration uses built-in defaults.
+//
+// Example:
+//
+//	due, err := taskwarrior.ParseDate("eow+2d", time.Now(), tw.Config)
+func ParseDate(expr string, now time.Time, config *TaskRC) (time.Time, error) {
+	return parseDateExpr(expr, now, config, nil)
+}
+
+// Same as ParseDate, but date attributes of the task can be used in the expression, like `due+2d` or `entry`.
+func ParseDateFor(task *Task, expr string, now time.Time, config *TaskRC) (time.Time, error) {
+	return parseDateExpr(expr, now, config, task)
+}
+
+// Distant date used for `later` and `someday`.
+var laterDate = time.Date(9999, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func parseDateExpr(expr string, now time.Time, config *TaskRC, task *Task) (time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	if t, ok := parseDateTerm(expr, now, config, task); ok {
+		return t, nil
+	}
+
+	// Date followed by a duration: try every sign from the right, since ISO dates contain dashes.
+	for i := len(expr) - 1; i > 0; i-- {
+		if expr[i] != '+' && expr[i] != '-' {
+			continue
+		}
+		offset, ok := parseDateOffset(expr[i+1:])
+		if !ok {
+			continue
+		}
+		base, err := parseDateExpr(expr[:i], now, config, task)
+		if err != nil {
+			continue
+		}
+		if expr[i] == '-' {
+			offset = offset.Neg()
+		}
+		return offset.AddTo(base), nil
+	}
+
+	// Duration alone is relative to now.
+	if offset, ok := parseDateOffset(expr); ok {
+		return offset.AddTo(now), nil
+	}
+	return time.Time{}, fmt.Errorf("'%s' is not a valid date", expr)
+}
+
+// Parse single date: named date, task attribute, date in `dateformat` or ISO 8601 date.
+func parseDateTerm(s string, now time.Time, config *TaskRC, task *Task) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	if t, ok := namedDate(strings.ToLower(s), now, config); ok {
+		return t, true
+	}
+	if task != nil {
+		if t, ok := taskDate(task, strings.ToLower(s)); ok {
+			return t, true
+		}
+	}
+	loc := now.Location()
+	if layout := dateformatLayout(config.valueOrDefault("dateformat")); layout != "" {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	if t, err := time.Parse(dateLayout, s); err == nil {
+		return t, true
+	}
+	for _, layout := range isoDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	if epoch, err := strconv.ParseInt(s, 10, 64); err == nil && len(s) >= 9 {
+		return time.Unix(epoch, 0).In(loc), true
+	}
+	return time.Time{}, false
+}
+
+// ISO 8601 forms accepted besides taskwarrior one.
+var isoDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"20060102T150405",
+	"20060102",
+}
+
+// Convert taskwarrior `dateformat` to layout of the time package. Returns empty string for unsupported formats.
+func dateformatLayout(format string) string {
+	var b strings.Builder
+	for _, c := range format {
+		switch c {
+		case 'Y':
+			b.WriteString("2006")
+		case 'y':
+			b.WriteString("06")
+		case 'M':
+			b.WriteString("01")
+		case 'm':
+			b.WriteString("1")
+		case 'D':
+			b.WriteString("02")
+		case 'd':
+			b.WriteString("2")
+		case 'H', 'h':
+			b.WriteString("15")
+		case 'N':
+			b.WriteString("04")
+		case 'n':
+			b.WriteString("4")
+		case 'S':
+			b.WriteString("05")
+		case 's':
+			b.WriteString("5")
+		case 'a':
+			b.WriteString("Mon")
+		case 'A':
+			b.WriteString("Monday")
+		case 'b':
+			b.WriteString("Jan")
+		case 'B':
+			b.WriteString("January")
+		case 'j', 'J', 'v', 'V':
+			// Day of year and week number can't be parsed.
+			return ""
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Return date attribute of the task by name.
+func taskDate(task *Task, name string) (time.Time, bool) {
+	var t TaskTime
+	switch name {
+	case "entry":
+		t = task.Entry
+	case "modified":
+		t = task.Modified
+	case "start":
+		t = task.Start
+	case "end":
+		t = task.End
+	case "due":
+		t = task.Due
+	case "wait":
+		t = task.Wait
+	case "scheduled":
+		t = task.Scheduled
+	case "until":
+		t = task.Until
+	default:
+		v, ok := task.UDA[name]
+		if !ok {
+			return time.Time{}, false
+		}
+		switch v := v.(type) {
+		case TaskTime:
+			t = v
+		case time.Time:
+			t = NewTaskTime(v)
+		case string:
+			parsed, err := ParseTaskTime(v)
+			if err != nil {
+				return time.Time{}, false
+			}
+			t = parsed
+		}
+	}
+	return t.Time, !t.IsZero()
+}
+
+var ordinalDateRegexp = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)$`)
+
+// Evaluate named date relative to now.
+func namedDate(name string, now time.Time, config *TaskRC) (time.Time, bool) {
+	sod := startOfDay(now)
+	endOf := func(next time.Time) time.Time { return next.Add(-time.Second) }
+
+	// Start of current week, depending on weekstart option.
+	weekstart := time.Sunday
+	if strings.ToLower(config.valueOrDefault("weekstart")) == "monday" {
+		weekstart = time.Monday
+	}
+	socw := sod.AddDate(0, 0, -((int(now.Weekday()) - int(weekstart) + 7) % 7))
+	soww := sod.AddDate(0, 0, -((int(now.Weekday()) + 6) % 7))
+
+	y, mo, _ := now.Date()
+	socm := time.Date(y, mo, 1, 0, 0, 0, 0, now.Location())
+	socq := time.Date(y, mo-(mo-1)%3, 1, 0, 0, 0, 0, now.Location())
+	socy := time.Date(y, time.January, 1, 0, 0, 0, 0, now.Location())
+
+	switch name {
+	case "now":
+		return now, true
+	case "today", "sod":
+		return sod, true
+	case "eod":
+		return endOf(sod.AddDate(0, 0, 1)), true
+	case "tomorrow":
+		return sod.AddDate(0, 0, 1), true
+	case "yesterday":
+		return sod.AddDate(0, 0, -1), true
+	case "later", "someday":
+		return laterDate.In(now.Location()), true
+
+	case "socw":
+		return socw, true
+	case "eocw", "eow":
+		return endOf(socw.AddDate(0, 0, 7)), true
+	case "sow", "sonw":
+		return socw.AddDate(0, 0, 7), true
+	case "eonw":
+		return endOf(socw.AddDate(0, 0, 14)), true
+	case "sopw":
+		return socw.AddDate(0, 0, -7), true
+	case "eopw":
+		return endOf(socw), true
+	case "soww":
+		return soww, true
+	case "eoww":
+		return endOf(soww.AddDate(0, 0, 5)), true
+
+	case "socm":
+		return socm, true
+	case "eocm", "eom":
+		return endOf(socm.AddDate(0, 1, 0)), true
+	case "som", "sonm":
+		return socm.AddDate(0, 1, 0), true
+	case "eonm":
+		return endOf(socm.AddDate(0, 2, 0)), true
+	case "sopm":
+		return socm.AddDate(0, -1, 0), true
+	case "eopm":
+		return endOf(socm), true
+
+	case "socq":
+		return socq, true
+	case "eocq", "eoq":
+		return endOf(socq.AddDate(0, 3, 0)), true
+	case "soq", "sonq":
+		return socq.AddDate(0, 3, 0), true
+	case "eonq":
+		return endOf(socq.AddDate(0, 6, 0)), true
+	case "sopq":
+		return socq.AddDate(0, -3, 0), true
+	case "eopq":
+		return endOf(socq), true
+
+	case "socy":
+		return socy, true
+	case "eocy", "eoy":
+		return endOf(socy.AddDate(1, 0, 0)), true
+	case "soy", "sony":
+		return socy.AddDate(1, 0, 0), true
+	case "eony":
+		return endOf(socy.AddDate(2, 0, 0)), true
+	case "sopy":
+		return socy.AddDate(-1, 0, 0), true
+	case "eopy":
+		return endOf(socy), true
+
+	case "easter", "eastermonday", "goodfriday", "ascension", "pentecost":
+		offset := map[string]int{"easter": 0, "eastermonday": 1, "goodfriday": -2, "ascension": 39, "pentecost": 49}[name]
+		holiday := easter(y, now.Location()).AddDate(0, 0, offset)
+		if holiday.Before(sod) {
+			holiday = easter(y+1, now.Location()).AddDate(0, 0, offset)
+		}
+		return holiday, true
+	}
+
+	// Next day of week, strictly after today.
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			days := (int(d) - int(now.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return sod.AddDate(0, 0, days), true
+		}
+	}
+
+	// First day of next occurrence of the month.
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if name == full || name == full[:3] {
+			year := y
+			if m <= mo {
+				year++
+			}
+			return time.Date(year, m, 1, 0, 0, 0, 0, now.Location()), true
+		}
+	}
+
+	// Next occurrence of the day of month, like `15th`.
+	if match := ordinalDateRegexp.FindStringSubmatch(name); match != nil {
+		day, _ := strconv.Atoi(match[1])
+		if day < 1 || day > 31 {
+			return time.Time{}, false
+		}
+		for month := socm; ; month = month.AddDate(0, 1, 0) {
+			t := month.AddDate(0, 0, day-1)
+			if t.Month() == month.Month() && t.After(sod) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Return date of Easter Sunday in given year (anonymous Gregorian algorithm).
+func easter(year int, loc *time.Location) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := (19*a + b - b/4 - (b-(b+8)/25+1)/3 + 15) % 30
+	e := (32 + 2*(b%4) + 2*(c/4) - d - c%4) % 7
+	f := d + e - 7*((a+11*d+22*e)/451) + 114
+	return time.Date(year, time.Month(f/31), f%31+1, 0, 0, 0, 0, loc)
+}
+
+// Parse duration of date arithmetic. Numbers without unit are not accepted, so they are not confused with parts of
+// dates.
+func parseDateOffset(s string) (Duration, bool) {
+	s = strings.TrimSpace(s)
+	if _, err := strconv.Atoi(s); err == nil || strings.HasPrefix(s, "-") {
+		return Duration{}, false
+	}
+	d, ok := parseDuration(s)
+	return d, ok
+}
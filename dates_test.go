@@ -0,0 +1,133 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"testing"
+)
+
+func TestParseDate(t *testing.T) {
+	// Tuesday.
+	now := MustParseTaskTime("20260210T120000Z").Time
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"now", "20260210T120000Z"},
+		{"today", "20260210T000000Z"},
+		{"sod", "20260210T000000Z"},
+		{"eod", "20260210T235959Z"},
+		{"tomorrow", "20260211T000000Z"},
+		{"yesterday", "20260209T000000Z"},
+		{"socw", "20260208T000000Z"},
+		{"sow", "20260215T000000Z"},
+		{"eow", "20260214T235959Z"},
+		{"sopw", "20260201T000000Z"},
+		{"soww", "20260209T000000Z"},
+		{"eoww", "20260213T235959Z"},
+		{"socm", "20260201T000000Z"},
+		{"som", "20260301T000000Z"},
+		{"eom", "20260228T235959Z"},
+		{"soq", "20260401T000000Z"},
+		{"eoq", "20260331T235959Z"},
+		{"soy", "20270101T000000Z"},
+		{"eoy", "20261231T235959Z"},
+		{"monday", "20260216T000000Z"},
+		{"Tuesday", "20260217T000000Z"},
+		{"fri", "20260213T000000Z"},
+		{"january", "20270101T000000Z"},
+		{"mar", "20260301T000000Z"},
+		{"15th", "20260215T000000Z"},
+		{"10th", "20260310T000000Z"},
+		{"31st", "20260331T000000Z"},
+		{"easter", "20260405T000000Z"},
+		{"goodfriday", "20260403T000000Z"},
+		{"later", "99991230T000000Z"},
+		{"someday", "99991230T000000Z"},
+		{"now+3d", "20260213T120000Z"},
+		{"now-1wk", "20260203T120000Z"},
+		{"eom+1d", "20260301T235959Z"},
+		{"today+P1M", "20260310T000000Z"},
+		{"3d", "20260213T120000Z"},
+		{"2026-03-01", "20260301T000000Z"},
+		{"2026-03-01+2wk", "20260315T000000Z"},
+		{"2026-03-01-1d", "20260228T000000Z"},
+		{"2026-02-10T08:30:00", "20260210T083000Z"},
+		{"2026-02-10T08:30:00+02:00", "20260210T063000Z"},
+		{"20260301T100000Z", "20260301T100000Z"},
+	}
+	for _, test := range tests {
+		got, err := ParseDate(test.expr, now, nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+		} else if NewTaskTime(got).String() != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.expr, test.expected, NewTaskTime(got))
+		}
+	}
+
+	for _, expr := range []string{"", "sometime", "now+", "32nd", "2026-13-01"} {
+		if _, err := ParseDate(expr, now, nil); err == nil {
+			t.Errorf("Invalid date '%s' was accepted", expr)
+		}
+	}
+}
+
+func TestParseDate_Config(t *testing.T) {
+	now := MustParseTaskTime("20260210T120000Z").Time
+	config := &TaskRC{}
+	if err := config.MapTaskRC("dateformat=m/d/Y\nweekstart=monday\n"); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"3/15/2026":     "20260315T000000Z",
+		"3/15/2026+1d":  "20260316T000000Z",
+		"socw":          "20260209T000000Z",
+		"eocw":          "20260215T235959Z",
+		"sonw":          "20260216T000000Z",
+		"2026-03-15":    "20260315T000000Z",
+		"eopw":          "20260208T235959Z",
+		"10/1/2026-1mo": "20260901T000000Z",
+	}
+	for expr, expected := range tests {
+		got, err := ParseDate(expr, now, config)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+		} else if NewTaskTime(got).String() != expected {
+			t.Errorf("%s: expected %s, got %s", expr, expected, NewTaskTime(got))
+		}
+	}
+}
+
+func TestParseDateFor(t *testing.T) {
+	now := MustParseTaskTime("20260210T120000Z").Time
+	config := &TaskRC{UDA: map[string]string{"review": "date"}}
+	task := &Task{
+		Due: MustParseTaskTime("20260220T090000Z"),
+		UDA: map[string]interface{}{"review": "20260301T000000Z"},
+	}
+	tests := map[string]string{
+		"due":        "20260220T090000Z",
+		"due+2d":     "20260222T090000Z",
+		"due-1wk":    "20260213T090000Z",
+		"review-1d":  "20260228T000000Z",
+		"tomorrow":   "20260211T000000Z",
+		"now+1h":     "20260210T130000Z",
+		"due+P1DT2H": "20260221T110000Z",
+	}
+	for expr, expected := range tests {
+		got, err := ParseDateFor(task, expr, now, config)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+		} else if NewTaskTime(got).String() != expected {
+			t.Errorf("%s: expected %s, got %s", expr, expected, NewTaskTime(got))
+		}
+	}
+
+	if _, err := ParseDateFor(task, "wait+1d", now, config); err == nil {
+		t.Error("Unset attribute was accepted")
+	}
+	if _, err := ParseDate("due+1d", now, config); err == nil {
+		t.Error("Attribute was accepted without task")
+	}
+}
@@ -0,0 +1,270 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Duration values of taskwarrior.
+//
+// Durations are used by `recur` attribute, UDAs of duration type and date arithmetic. Taskwarrior accepts many
+// spellings: named periods (daily, weekdays, quarterly), numbers with units (3d, 2wks, 1.5h) and ISO 8601 durations
+// (P1Y2M, PT4H). Months and years have different lengths, so they are kept apart from days and exact time.
+
+package taskwarrior
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a taskwarrior duration. Years are stored as 12 months.
+type Duration struct {
+	Months   int           // Calendar months
+	Days     int           // Calendar days
+	Time     time.Duration // Exact part: hours, minutes and seconds
+	Weekdays bool          // Every day from Monday to Friday, used by recurring tasks
+}
+
+// Named durations and units of `<number><unit>` durations.
+var durationUnits = map[string]Duration{
+	"s": {Time: time.Second}, "sec": {Time: time.Second}, "secs": {Time: time.Second},
+	"second": {Time: time.Second}, "seconds": {Time: time.Second},
+
+	"min": {Time: time.Minute}, "mins": {Time: time.Minute},
+	"minute": {Time: time.Minute}, "minutes": {Time: time.Minute},
+
+	"h": {Time: time.Hour}, "hr": {Time: time.Hour}, "hrs": {Time: time.Hour},
+	"hour": {Time: time.Hour}, "hours": {Time: time.Hour},
+
+	"d": {Days: 1}, "day": {Days: 1}, "days": {Days: 1}, "daily": {Days: 1},
+	"weekdays": {Weekdays: true},
+
+	"w": {Days: 7}, "wk": {Days: 7}, "wks": {Days: 7}, "week": {Days: 7}, "weeks": {Days: 7}, "weekly": {Days: 7},
+	"sennight": {Days: 7}, "biweekly": {Days: 14}, "fortnight": {Days: 14},
+
+	"mo": {Months: 1}, "mos": {Months: 1}, "mth": {Months: 1}, "mths": {Months: 1}, "mnths": {Months: 1},
+	"month": {Months: 1}, "months": {Months: 1}, "monthly": {Months: 1}, "bimonthly": {Months: 2},
+
+	"q": {Months: 3}, "qtr": {Months: 3}, "qtrs": {Months: 3}, "qrtrs": {Months: 3},
+	"quarter": {Months: 3}, "quarters": {Months: 3}, "quarterly": {Months: 3}, "semiannual": {Months: 6},
+
+	"y": {Months: 12}, "yr": {Months: 12}, "yrs": {Months: 12},
+	"year": {Months: 12}, "years": {Months: 12}, "yearly": {Months: 12}, "annual": {Months: 12},
+	"biannual": {Months: 24}, "biyearly": {Months: 24},
+}
+
+var (
+	unitDurationRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-z]+)$`)
+	isoDurationRegexp  = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+)
+
+// Parse duration in any taskwarrior spelling: named duration (weekly, quarterly), number with unit (3d, 2 wks,
+// 1.5h), number of seconds or ISO 8601 duration (P1Y2M, P2W, PT90M). Leading minus negates the duration.
+func ParseDuration(s string) (Duration, error) {
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+
+	d, ok := parseDuration(text)
+	if !ok {
+		return Duration{}, fmt.Errorf("'%s' is not a valid duration", s)
+	}
+	if negative {
+		d = d.Neg()
+	}
+	return d, nil
+}
+
+func parseDuration(s string) (Duration, bool) {
+	lower := strings.ToLower(s)
+	if d, ok := durationUnits[lower]; ok && len(lower) > 1 {
+		return d, true
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Duration{Time: time.Duration(seconds) * time.Second}, true
+	}
+	if m := unitDurationRegexp.FindStringSubmatch(lower); m != nil {
+		unit, ok := durationUnits[m[2]]
+		if !ok || unit.Weekdays {
+			return Duration{}, false
+		}
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			return Duration{Months: unit.Months * n, Days: unit.Days * n, Time: unit.Time * time.Duration(n)}, true
+		}
+		// Fractions are exact time, so they can't be used with months.
+		f, _ := strconv.ParseFloat(m[1], 64)
+		if unit.Months != 0 {
+			return Duration{}, false
+		}
+		exact := float64(unit.Time) + float64(unit.Days)*float64(24*time.Hour)
+		return Duration{Time: time.Duration(math.Round(f*exact/float64(time.Second))) * time.Second}, true
+	}
+	upper := strings.ToUpper(s)
+	if m := isoDurationRegexp.FindStringSubmatch(upper); m != nil && upper != "P" && !strings.HasSuffix(upper, "T") {
+		n := make([]int, len(m))
+		for i := 1; i < len(m); i++ {
+			n[i], _ = strconv.Atoi(m[i])
+		}
+		return Duration{
+			Months: n[1]*12 + n[2],
+			Days:   n[3]*7 + n[4],
+			Time:   time.Duration(n[5])*time.Hour + time.Duration(n[6])*time.Minute + time.Duration(n[7])*time.Second,
+		}, true
+	}
+	return Duration{}, false
+}
+
+// Same as ParseDuration, but panics if the duration can't be parsed.
+func MustParseDuration(s string) Duration {
+	d, err := ParseDuration(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Check whether the duration is empty.
+func (d Duration) IsZero() bool {
+	return d == Duration{}
+}
+
+// Return negated duration.
+func (d Duration) Neg() Duration {
+	return Duration{Months: -d.Months, Days: -d.Days, Time: -d.Time, Weekdays: d.Weekdays}
+}
+
+// Add the duration to given time. Adding months keeps the day of month, clamped to the length of the month
+// (January 31 plus a month is February 28). Weekdays duration moves to the next day from Monday to Friday.
+func (d Duration) AddTo(t time.Time) time.Time {
+	if d.Weekdays {
+		switch t.Weekday() {
+		case time.Friday:
+			return t.AddDate(0, 0, 3)
+		case time.Saturday:
+			return t.AddDate(0, 0, 2)
+		}
+		return t.AddDate(0, 0, 1)
+	}
+	return addMonths(t, d.Months).AddDate(0, 0, d.Days).Add(d.Time)
+}
+
+func addMonths(t time.Time, months int) time.Time {
+	if months == 0 {
+		return t
+	}
+	y, m, day := t.Date()
+	first := time.Date(y, m+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// Return approximate length of the duration, counting a year as 365 days and a month as 30 days like
+// taskwarrior does.
+func (d Duration) Approximate() time.Duration {
+	if d.Weekdays {
+		return 24 * time.Hour
+	}
+	days := d.Months/12*365 + d.Months%12*30 + d.Days
+	return time.Duration(days)*24*time.Hour + d.Time
+}
+
+// Compare approximate lengths of durations. Returns -1, 0 or 1.
+func (d Duration) Compare(other Duration) int {
+	a, b := d.Approximate(), other.Approximate()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Check whether months, days and time of the duration have different signs, which ISO 8601 can't represent.
+func (d Duration) mixedSigns() bool {
+	return (d.Months > 0 || d.Days > 0 || d.Time > 0) && (d.Months < 0 || d.Days < 0 || d.Time < 0)
+}
+
+// Return the duration in ISO 8601 format, like taskwarrior stores durations: P1Y2M3DT4H5M6S. Weekdays duration is
+// returned as `weekdays`. Duration with mixed signs, like one month minus one day, is returned as its approximate
+// length in days and time.
+func (d Duration) String() string {
+	if d.Weekdays {
+		return "weekdays"
+	}
+	if d.mixedSigns() {
+		approx := d.Approximate()
+		day := 24 * time.Hour
+		d = Duration{Days: int(approx / day), Time: approx % day}
+	}
+	sign := ""
+	if d.Months <= 0 && d.Days <= 0 && d.Time <= 0 && !d.IsZero() {
+		sign = "-"
+		d = d.Neg()
+	}
+
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if d.Months/12 != 0 {
+		fmt.Fprintf(&b, "%dY", d.Months/12)
+	}
+	if d.Months%12 != 0 {
+		fmt.Fprintf(&b, "%dM", d.Months%12)
+	}
+	if d.Days != 0 {
+		fmt.Fprintf(&b, "%dD", d.Days)
+	}
+	if d.Time != 0 || d.IsZero() {
+		seconds := int64(d.Time / time.Second)
+		b.WriteString("T")
+		if h := seconds / 3600; h != 0 {
+			fmt.Fprintf(&b, "%dH", h)
+		}
+		if m := seconds % 3600 / 60; m != 0 {
+			fmt.Fprintf(&b, "%dM", m)
+		}
+		if s := seconds % 60; s != 0 || seconds == 0 {
+			fmt.Fprintf(&b, "%dS", s)
+		}
+	}
+	return b.String()
+}
+
+// MarshalJSON encodes the duration as ISO 8601 string. Duration with mixed signs is an error, since its string is
+// only approximate.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	if d.mixedSigns() {
+		return nil, fmt.Errorf("duration %+v has mixed signs", d)
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes duration in any spelling accepted by ParseDuration, or a number of seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration{Time: time.Duration(v) * time.Second}
+		return nil
+	case string:
+		parsed, err := ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	}
+	return fmt.Errorf("invalid duration: %s", data)
+}
+
+// Parse `recur` attribute of the task.
+func (t *Task) RecurDuration() (Duration, error) {
+	return ParseDuration(t.Recur)
+}
@@ -0,0 +1,169 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Duration
+		iso      string
+	}{
+		{"3d", Duration{Days: 3}, "P3D"},
+		{"2wks", Duration{Days: 14}, "P14D"},
+		{"2 weeks", Duration{Days: 14}, "P14D"},
+		{"weekly", Duration{Days: 7}, "P7D"},
+		{"fortnight", Duration{Days: 14}, "P14D"},
+		{"quarterly", Duration{Months: 3}, "P3M"},
+		{"semiannual", Duration{Months: 6}, "P6M"},
+		{"yearly", Duration{Months: 12}, "P1Y"},
+		{"18mo", Duration{Months: 18}, "P1Y6M"},
+		{"1.5h", Duration{Time: 90 * time.Minute}, "PT1H30M"},
+		{"0.5d", Duration{Time: 12 * time.Hour}, "PT12H"},
+		{"45min", Duration{Time: 45 * time.Minute}, "PT45M"},
+		{"3600", Duration{Time: time.Hour}, "PT1H"},
+		{"P1Y2M", Duration{Months: 14}, "P1Y2M"},
+		{"P2W", Duration{Days: 14}, "P14D"},
+		{"P1DT2H3M4S", Duration{Days: 1, Time: 2*time.Hour + 3*time.Minute + 4*time.Second}, "P1DT2H3M4S"},
+		{"PT0S", Duration{}, "PT0S"},
+		{"-3d", Duration{Days: -3}, "-P3D"},
+		{"weekdays", Duration{Weekdays: true}, "weekdays"},
+	}
+	for _, test := range tests {
+		d, err := ParseDuration(test.s)
+		if err != nil {
+			t.Errorf("%s: %v", test.s, err)
+			continue
+		}
+		if d != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.s, test.expected, d)
+		}
+		if d.String() != test.iso {
+			t.Errorf("%s: expected %s, got %s", test.s, test.iso, d)
+		}
+		if again := MustParseDuration(d.String()); again != d {
+			t.Errorf("%s: %s parsed as %+v", test.s, d, again)
+		}
+	}
+
+	for _, s := range []string{"", "soon", "3x", "1.5mo", "P", "PT", "P1H", "d"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Errorf("Invalid duration '%s' was accepted", s)
+		}
+	}
+}
+
+func TestDuration_AddTo(t *testing.T) {
+	tests := []struct {
+		d        string
+		from     string
+		expected string
+	}{
+		{"1mo", "20260131T090000Z", "20260228T090000Z"},
+		{"1mo", "20240131T090000Z", "20240229T090000Z"},
+		{"-1mo", "20260331T090000Z", "20260228T090000Z"},
+		{"P1Y", "20240229T090000Z", "20250228T090000Z"},
+		{"P1M2D", "20260130T090000Z", "20260302T090000Z"},
+		{"2d", "20260130T090000Z", "20260201T090000Z"},
+		{"36h", "20260130T090000Z", "20260131T210000Z"},
+		{"weekdays", "20260213T090000Z", "20260216T090000Z"},
+	}
+	for _, test := range tests {
+		got := MustParseDuration(test.d).AddTo(MustParseTaskTime(test.from).Time)
+		if NewTaskTime(got).String() != test.expected {
+			t.Errorf("%s after %s: expected %s, got %s", test.d, test.from, test.expected, NewTaskTime(got))
+		}
+	}
+}
+
+func TestDuration_Compare(t *testing.T) {
+	ordered := []string{"-1d", "PT0S", "12h", "1d", "weekly", "P29D", "monthly", "P31D", "quarterly", "P364D", "yearly"}
+	for i := range ordered {
+		for j := range ordered {
+			a, b := MustParseDuration(ordered[i]), MustParseDuration(ordered[j])
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if got := a.Compare(b); got != expected {
+				t.Errorf("Compare(%s, %s): expected %d, got %d", ordered[i], ordered[j], expected, got)
+			}
+		}
+	}
+	if MustParseDuration("P2W").Compare(MustParseDuration("14d")) != 0 {
+		t.Error("Equal durations are not equal")
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	type record struct {
+		Estimate Duration `json:"estimate"`
+	}
+	var r record
+	if err := json.Unmarshal([]byte(`{"estimate":"2wks"}`), &r); err != nil || r.Estimate != (Duration{Days: 14}) {
+		t.Errorf("Can't decode duration: %+v %v", r, err)
+	}
+	if err := json.Unmarshal([]byte(`{"estimate":5400}`), &r); err != nil || r.Estimate != (Duration{Time: 90 * time.Minute}) {
+		t.Errorf("Can't decode seconds: %+v %v", r, err)
+	}
+	if err := json.Unmarshal([]byte(`{"estimate":"soon"}`), &r); err == nil {
+		t.Error("Invalid duration was decoded")
+	}
+	buf, _ := json.Marshal(record{Duration{Months: 1, Days: 2}})
+	if string(buf) != `{"estimate":"P1M2D"}` {
+		t.Errorf("Unexpected encoding: %s", buf)
+	}
+
+	// Round trip, durations with mixed signs are rejected
+	for _, d := range []Duration{{}, {Months: 14, Days: 3, Time: 90 * time.Minute}, {Days: -1, Time: -time.Hour}, {Weekdays: true}} {
+		var decoded Duration
+		buf, err := json.Marshal(d)
+		if err == nil {
+			err = json.Unmarshal(buf, &decoded)
+		}
+		if err != nil || decoded != d {
+			t.Errorf("%+v: decoded %+v from %s (%v)", d, decoded, buf, err)
+		}
+	}
+	mixed := Duration{Months: 1, Days: -1}
+	if _, err := json.Marshal(mixed); err == nil {
+		t.Error("Duration with mixed signs was encoded")
+	}
+	if parsed, err := ParseDuration(mixed.String()); err != nil || parsed != (Duration{Days: 29}) {
+		t.Errorf("Unexpected string of mixed duration: %s %+v (%v)", mixed, parsed, err)
+	}
+
+	// Task with duration UDA
+	data := `{"uuid":"00000000-0000-0000-0000-000000000001","description":"Write report","status":"pending",` +
+		`"recur":"quarterly","estimate":"PT3H","chunk":7200}`
+	var task Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		t.Fatal(err)
+	}
+	config := &TaskRC{UDA: map[string]string{"estimate": "duration", "chunk": "duration"}}
+	config.ConvertUDA(&task)
+	if task.UDA["estimate"] != (Duration{Time: 3 * time.Hour}) || task.UDA["chunk"] != (Duration{Time: 2 * time.Hour}) {
+		t.Errorf("Duration UDA were not converted: %v", task.UDA)
+	}
+	if recur, err := task.RecurDuration(); err != nil || recur != (Duration{Months: 3}) {
+		t.Errorf("Unexpected recur duration: %+v %v", recur, err)
+	}
+
+	buf, err := json.Marshal(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj map[string]interface{}
+	json.Unmarshal(buf, &obj)
+	if obj["estimate"] != "PT3H" || obj["chunk"] != "PT2H" || obj["recur"] != "quarterly" {
+		t.Errorf("Durations were not written back: %s", buf)
+	}
+}
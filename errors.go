@@ -0,0 +1,108 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Error types returned by taskwarrior calls.
+
+package taskwarrior
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sentinel errors describing common failure reasons, use errors.Is to check for them.
+var (
+	ErrTaskBinaryNotFound   = errors.New("taskwarrior binary not found")
+	ErrNoMatchingTasks      = errors.New("no matching tasks")
+	ErrDataLocked           = errors.New("taskwarrior data is locked")
+	ErrConfirmationRequired = errors.New("taskwarrior requires confirmation")
+	ErrNotSupported         = errors.New("operation is not supported")
+	ErrDependencyCycle      = errors.New("dependency cycle")
+	ErrUnsupportedVersion   = errors.New("taskwarrior version is not supported")
+)
+
+// Output fragments that identify sentinel errors. Matched case-insensitively against stdout and stderr.
+var commandErrorPatterns = []struct {
+	err       error
+	fragments []string
+}{
+	{ErrNoMatchingTasks, []string{"no matches.", "no tasks specified."}},
+	{ErrDataLocked, []string{"database is locked", "could not acquire lock", "could not obtain lock",
+		"unable to lock", "waiting for file lock"}},
+	{ErrConfirmationRequired, []string{"(yes/no", "confirmation required"}},
+}
+
+// CommandError is returned when `task` command exits with non-zero status.
+type CommandError struct {
+	Args     []string // Arguments of failed command
+	ExitCode int      // Exit status
+	Stdout   []byte   // Captured standard output
+	Stderr   []byte   // Captured standard error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("task %s: exit status %d", strings.Join(e.Args, " "), e.ExitCode)
+	if stderr := strings.TrimSpace(string(e.Stderr)); stderr != "" {
+		msg += ": " + strings.SplitN(stderr, "\n", 2)[0]
+	}
+	return msg
+}
+
+// Is reports whether the failure matches one of the sentinel errors.
+func (e *CommandError) Is(target error) bool {
+	for _, p := range commandErrorPatterns {
+		if p.err != target {
+			continue
+		}
+		for _, out := range [][]byte{e.Stderr, e.Stdout} {
+			lower := bytes.ToLower(out)
+			for _, fragment := range p.fragments {
+				if bytes.Contains(lower, []byte(fragment)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// TimeoutError is returned when `task` command was interrupted because its context was done: either the timeout
+// expired or the caller canceled the operation.
+type TimeoutError struct {
+	Args    []string      // Arguments of interrupted command
+	Timeout time.Duration // Timeout of TaskWarrior instance, zero if it was not set
+	Err     error         // context.DeadlineExceeded or context.Canceled
+}
+
+func (e *TimeoutError) Error() string {
+	cmd := "task " + strings.Join(e.Args, " ")
+	if errors.Is(e.Err, context.Canceled) {
+		return fmt.Sprintf("%s: canceled", cmd)
+	}
+	if e.Timeout > 0 {
+		return fmt.Sprintf("%s: timed out after %s", cmd, e.Timeout)
+	}
+	return fmt.Sprintf("%s: %v", cmd, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// CycleError is returned when task dependencies form a cycle.
+type CycleError struct {
+	UUIDs []string // Tasks of the cycle, each one depends on the next, the first one is repeated at the end
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", strings.Join(e.UUIDs, " -> "))
+}
+
+// Is reports whether the target is ErrDependencyCycle.
+func (e *CycleError) Is(target error) bool {
+	return target == ErrDependencyCycle
+}
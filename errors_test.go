@@ -0,0 +1,66 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCommandError_Is(t *testing.T) {
+	cases := []struct {
+		stdout, stderr string
+		expected       error
+	}{
+		{"", "No matches.\n", ErrNoMatchingTasks},
+		{"", "Command prohibited without filter.\nNo tasks specified.\n", ErrNoMatchingTasks},
+		{"", "database is locked\n", ErrDataLocked},
+		{"Delete task 1 'Foo'? (yes/no) ", "Task not deleted.\n", ErrConfirmationRequired},
+	}
+	sentinels := []error{ErrNoMatchingTasks, ErrDataLocked, ErrConfirmationRequired, ErrTaskBinaryNotFound}
+
+	for _, c := range cases {
+		err := error(&CommandError{Args: []string{"1", "delete"}, ExitCode: 1,
+			Stdout: []byte(c.stdout), Stderr: []byte(c.stderr)})
+		for _, sentinel := range sentinels {
+			if errors.Is(err, sentinel) != (sentinel == c.expected) {
+				t.Errorf("errors.Is(%q, %v) = %v", c.stderr, sentinel, errors.Is(err, sentinel))
+			}
+		}
+	}
+
+	err := &CommandError{Args: []string{"export"}, ExitCode: 2, Stderr: []byte("Bad thing\nDetails\n")}
+	if err.Error() != "task export: exit status 2: Bad thing" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
+
+func TestTaskWarrior_CommandError(t *testing.T) {
+	tw1 := newFakeTaskWarrior(t)
+
+	// Failed command is reported with its output
+	_, err := tw1.run(context.Background(), nil, "project:none", "modify", "priority:H")
+	var cmdErr *CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("Expected CommandError, got %v", err)
+	}
+	if cmdErr.ExitCode != 1 || !strings.Contains(string(cmdErr.Stderr), "No matches") {
+		t.Errorf("Unexpected CommandError content: %+v", cmdErr)
+	}
+	if cmdErr.Args[1] != "modify" {
+		t.Errorf("Unexpected CommandError arguments: %v", cmdErr.Args)
+	}
+	if !errors.Is(err, ErrNoMatchingTasks) {
+		t.Errorf("Expected ErrNoMatchingTasks, got %v", err)
+	}
+
+	// Missing binary
+	tw1.Runner = &ExecRunner{Binary: "/does/not/exist/task"}
+	err = tw1.FetchAllTasks()
+	if !errors.Is(err, ErrTaskBinaryNotFound) {
+		t.Errorf("Expected ErrTaskBinaryNotFound, got %v", err)
+	}
+}
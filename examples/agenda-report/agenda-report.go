@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/errnoh/go-taskwarrior"
 )
@@ -35,17 +36,21 @@ func sendMail(report string) {
 	io.Copy(os.Stdout, &b2)
 }
 
-func getPendingTasks(ss []taskwarrior.Task, test func(taskwarrior.Task) bool) (ret []string) {
+func formatTasks(ss []taskwarrior.Task) (ret []string) {
 	for _, s := range ss {
-		if test(s) {
-			project := s.Project
-			if len(project) == 0 {
-				project = "<no project>"
+		project := s.Project
+		if len(project) == 0 {
+			project = "<no project>"
+		}
+		entry := fmt.Sprintf("%-12.12s :: %s", project, s.Description)
+		if !s.Due.IsZero() {
+			entry += fmt.Sprintf(" (due %s)", s.Due.Local().Format("2006-01-02 15:04"))
+			if s.IsOverdue(time.Now()) {
+				entry += " OVERDUE"
 			}
-			entry := fmt.Sprintf("%-12.12s :: %s", project, s.Description)
-			fmt.Printf("%+v\n", s)
-			ret = append(ret, entry)
 		}
+		fmt.Printf("%+v\n", s)
+		ret = append(ret, entry)
 	}
 	return
 }
@@ -53,8 +58,8 @@ func getPendingTasks(ss []taskwarrior.Task, test func(taskwarrior.Task) bool) (r
 func main() {
 	tw, _ := taskwarrior.NewTaskWarrior("~/.taskrc")
 	tw.FetchAllTasks()
-	mytest := func(s taskwarrior.Task) bool { return s.Status == "pending" }
-	pending := getPendingTasks(tw.Tasks, mytest)
+	tasks, _ := tw.FilterTasks(taskwarrior.Filter{Status: "pending"})
+	pending := formatTasks(tasks)
 
 	title := fmt.Sprintf("\nThere are %d pending taskwarrior tasks:\n\n", len(pending))
 	tasks_str := strings.Join(pending, "\n")
@@ -0,0 +1,557 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// In-memory replacement for taskwarrior binary.
+//
+// FakeRunner understands a small subset of taskwarrior command line: `export`, `import`, `add`, `modify`, `delete`,
+// `done`, `start`, `stop`, `annotate`, `denotate`, `duplicate` and `sync` with filters before the command name and
+// modifications after it. Filters are evaluated with Matcher, `rc.<name>=<value>` overrides are applied to them. It
+// keeps tasks as raw JSON objects, so it stores every attribute passed to it, including ones it knows nothing about.
+
+package taskwarrior
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version reported by FakeRunner by default. The emulated JSON format and commands are the ones of this version.
+const FakeVersion = "2.6.2"
+
+// FakeRunner is a Runner that emulates taskwarrior database in memory.
+type FakeRunner struct {
+	Calls   []Invocation     // History of all invocations
+	Now     func() time.Time // Clock used for entry and modified timestamps, time.Now if nil
+	Version string           // Reported by `task --version`, FakeVersion if empty
+
+	mu     sync.Mutex
+	tasks  []map[string]interface{}
+	config *TaskRC // Overrides of the current invocation
+}
+
+// Create new FakeRunner with given tasks in the database.
+func NewFakeRunner(tasks ...Task) *FakeRunner {
+	f := &FakeRunner{}
+	for i := range tasks {
+		f.tasks = append(f.tasks, taskToRecord(&tasks[i]))
+	}
+	return f
+}
+
+// Return a copy of all tasks currently stored in the fake database.
+func (f *FakeRunner) Tasks() []Task {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tasks := make([]Task, 0, len(f.tasks))
+	for _, rec := range f.tasks {
+		var task Task
+		buf, _ := json.Marshal(rec)
+		json.Unmarshal(buf, &task)
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// Run emulates execution of `task` command.
+func (f *FakeRunner) Run(ctx context.Context, inv Invocation) (Output, error) {
+	if err := ctx.Err(); err != nil {
+		return Output{}, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Calls = append(f.Calls, inv)
+
+	if len(inv.Args) == 1 && inv.Args[0] == "--version" {
+		version := f.Version
+		if version == "" {
+			version = FakeVersion
+		}
+		return Output{Stdout: []byte(version + "\n")}, nil
+	}
+
+	// The rc file is ignored, overrides affect filtering only.
+	var args []string
+	f.config = &TaskRC{}
+	for _, arg := range inv.Args {
+		if strings.HasPrefix(arg, "rc.") {
+			key, val, _ := strings.Cut(arg[len("rc."):], "=")
+			f.config.set(key, val, OriginOverride)
+			continue
+		}
+		if strings.HasPrefix(arg, "rc:") {
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	// Split command line on the filter, the command and the modifications.
+	cmdIdx := -1
+	for i, arg := range args {
+		if _, ok := fakeCommands[arg]; ok {
+			cmdIdx = i
+			break
+		}
+	}
+	if cmdIdx < 0 {
+		return fakeFailure("Unsupported command: %s", strings.Join(args, " ")), nil
+	}
+	filter, command, mods := args[:cmdIdx], args[cmdIdx], args[cmdIdx+1:]
+
+	return fakeCommands[command](f, filter, mods, inv.Stdin), nil
+}
+
+// Handlers of supported commands.
+var fakeCommands map[string]func(f *FakeRunner, filter, mods []string, stdin []byte) Output
+
+func init() {
+	fakeCommands = map[string]func(f *FakeRunner, filter, mods []string, stdin []byte) Output{
+		"export":    (*FakeRunner).export,
+		"import":    (*FakeRunner).importTasks,
+		"add":       (*FakeRunner).add,
+		"modify":    (*FakeRunner).modify,
+		"delete":    (*FakeRunner).delete,
+		"done":      (*FakeRunner).done,
+		"start":     (*FakeRunner).start,
+		"stop":      (*FakeRunner).stop,
+		"annotate":  (*FakeRunner).annotate,
+		"denotate":  (*FakeRunner).denotate,
+		"duplicate": (*FakeRunner).duplicate,
+		"sync":      (*FakeRunner).sync,
+	}
+}
+
+func (f *FakeRunner) export(filter, mods []string, stdin []byte) Output {
+	indexes, err := f.match(filter)
+	if err != nil {
+		return fakeFailure("%v", err)
+	}
+	matched := []map[string]interface{}{}
+	for _, i := range indexes {
+		rec := map[string]interface{}{}
+		for k, v := range f.tasks[i] {
+			rec[k] = v
+		}
+		if id := f.id(i); id > 0 {
+			rec["id"] = id
+		}
+		matched = append(matched, rec)
+	}
+	buf, err := json.Marshal(matched)
+	if err != nil {
+		return fakeFailure("%v", err)
+	}
+	return Output{Stdout: buf}
+}
+
+func (f *FakeRunner) importTasks(filter, mods []string, stdin []byte) Output {
+	var recs []map[string]interface{}
+	trimmed := bytes.TrimSpace(stdin)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &recs); err != nil {
+			return fakeFailure("Not a valid JSON value: %v", err)
+		}
+	} else {
+		for _, line := range bytes.Split(trimmed, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			var rec map[string]interface{}
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return fakeFailure("Not a valid JSON value: %v", err)
+			}
+			recs = append(recs, rec)
+		}
+	}
+
+	var stdout bytes.Buffer
+	stdout.WriteString("Importing '-'\n")
+	for _, rec := range recs {
+		// Identifiers are assigned by the database.
+		delete(rec, "id")
+		delete(rec, "urgency")
+		if s, _ := rec["uuid"].(string); s == "" {
+			rec["uuid"] = NewUUID()
+		}
+		if s, _ := rec["status"].(string); s == "" {
+			rec["status"] = "pending"
+		}
+		if s, _ := rec["entry"].(string); s == "" {
+			rec["entry"] = f.now()
+		}
+
+		action := "add "
+		if i := f.find(rec["uuid"].(string)); i >= 0 {
+			if recordsEqual(f.tasks[i], rec) {
+				action = "skip"
+			} else {
+				action = "mod "
+				f.tasks[i] = rec
+			}
+		} else {
+			f.tasks = append(f.tasks, rec)
+		}
+		fmt.Fprintf(&stdout, " %s %s %s\n", action, rec["uuid"], rec["description"])
+	}
+	stderr := fmt.Sprintf("Imported %d tasks.\n", len(recs))
+	return Output{Stdout: stdout.Bytes(), Stderr: []byte(stderr)}
+}
+
+func (f *FakeRunner) add(filter, mods []string, stdin []byte) Output {
+	now := f.now()
+	rec := map[string]interface{}{
+		"uuid":     NewUUID(),
+		"status":   "pending",
+		"entry":    now,
+		"modified": now,
+	}
+	if err := applyFakeModifications(rec, mods); err != nil {
+		return fakeFailure("%v", err)
+	}
+	if s, _ := rec["description"].(string); s == "" {
+		return fakeFailure("Additional text must be provided.")
+	}
+	f.tasks = append(f.tasks, rec)
+	return Output{Stdout: []byte(fmt.Sprintf("Created task %d.\n", f.id(len(f.tasks)-1)))}
+}
+
+func (f *FakeRunner) modify(filter, mods []string, stdin []byte) Output {
+	return f.each(filter, "Modifying", "Modified", func(rec map[string]interface{}) error {
+		return applyFakeModifications(rec, mods)
+	})
+}
+
+func (f *FakeRunner) delete(filter, mods []string, stdin []byte) Output {
+	return f.each(filter, "Deleting", "Deleted", func(rec map[string]interface{}) error {
+		rec["status"] = "deleted"
+		if _, ok := rec["end"]; !ok {
+			rec["end"] = f.now()
+		}
+		return nil
+	})
+}
+
+func (f *FakeRunner) done(filter, mods []string, stdin []byte) Output {
+	return f.each(filter, "Completing", "Completed", func(rec map[string]interface{}) error {
+		if rec["status"] != "pending" && rec["status"] != "waiting" {
+			return fmt.Errorf("Task %s '%s' is neither pending nor waiting.", rec["uuid"], rec["description"])
+		}
+		rec["status"] = "completed"
+		rec["end"] = f.now()
+		delete(rec, "start")
+		return applyFakeModifications(rec, mods)
+	})
+}
+
+func (f *FakeRunner) start(filter, mods []string, stdin []byte) Output {
+	return f.each(filter, "Starting", "Started", func(rec map[string]interface{}) error {
+		if _, ok := rec["start"]; ok {
+			return fmt.Errorf("Task %s '%s' already started.", rec["uuid"], rec["description"])
+		}
+		rec["start"] = f.now()
+		return applyFakeModifications(rec, mods)
+	})
+}
+
+func (f *FakeRunner) stop(filter, mods []string, stdin []byte) Output {
+	return f.each(filter, "Stopping", "Stopped", func(rec map[string]interface{}) error {
+		if _, ok := rec["start"]; !ok {
+			return fmt.Errorf("Task %s '%s' not started.", rec["uuid"], rec["description"])
+		}
+		delete(rec, "start")
+		return applyFakeModifications(rec, mods)
+	})
+}
+
+func (f *FakeRunner) annotate(filter, mods []string, stdin []byte) Output {
+	text := strings.Join(fakeWords(mods), " ")
+	if text == "" {
+		return fakeFailure("Additional text must be provided.")
+	}
+	return f.each(filter, "Annotating", "Annotated", func(rec map[string]interface{}) error {
+		annotations, _ := rec["annotations"].([]interface{})
+		rec["annotations"] = append(annotations, map[string]interface{}{"entry": f.now(), "description": text})
+		return nil
+	})
+}
+
+func (f *FakeRunner) denotate(filter, mods []string, stdin []byte) Output {
+	text := strings.Join(fakeWords(mods), " ")
+	return f.each(filter, "Denotating", "Denotated", func(rec map[string]interface{}) error {
+		annotations, _ := rec["annotations"].([]interface{})
+		// Exact match is preferred to partial one.
+		found := -1
+		for i, a := range annotations {
+			desc, _ := a.(map[string]interface{})["description"].(string)
+			if desc == text {
+				found = i
+				break
+			}
+			if found < 0 && strings.Contains(desc, text) {
+				found = i
+			}
+		}
+		if found < 0 {
+			return fmt.Errorf("Did not find any matching annotation to be deleted for '%s'.", text)
+		}
+		annotations = append(annotations[:found:found], annotations[found+1:]...)
+		if len(annotations) == 0 {
+			delete(rec, "annotations")
+		} else {
+			rec["annotations"] = annotations
+		}
+		return nil
+	})
+}
+
+func (f *FakeRunner) duplicate(filter, mods []string, stdin []byte) Output {
+	if len(filter) == 0 {
+		return fakeFailure("Command prohibited without filter.")
+	}
+	matched, err := f.match(filter)
+	if err != nil {
+		return fakeFailure("%v", err)
+	}
+	if len(matched) == 0 {
+		return fakeFailure("No matches.")
+	}
+
+	var stdout bytes.Buffer
+	for _, i := range matched {
+		dup := map[string]interface{}{}
+		for k, v := range f.tasks[i] {
+			dup[k] = v
+		}
+		for _, key := range []string{"start", "end", "parent", "mask", "imask"} {
+			delete(dup, key)
+		}
+		now := f.now()
+		dup["uuid"], dup["status"], dup["entry"], dup["modified"] = NewUUID(), "pending", now, now
+		if err := applyFakeModifications(dup, mods); err != nil {
+			return fakeFailure("%v", err)
+		}
+		f.tasks = append(f.tasks, dup)
+		fmt.Fprintf(&stdout, "Duplicated task %d '%s'.\n", f.id(i), f.tasks[i]["description"])
+		fmt.Fprintf(&stdout, "Created task %d.\n", f.id(len(f.tasks)-1))
+	}
+	return Output{Stdout: stdout.Bytes()}
+}
+
+// Apply change to every task matching the filter, like taskwarrior commands with filters do. Failed commands leave
+// the database intact.
+func (f *FakeRunner) each(filter []string, verb, summary string, change func(rec map[string]interface{}) error) Output {
+	if len(filter) == 0 {
+		return fakeFailure("Command prohibited without filter.")
+	}
+	matched, err := f.match(filter)
+	if err != nil {
+		return fakeFailure("%v", err)
+	}
+	if len(matched) == 0 {
+		return fakeFailure("No matches.")
+	}
+
+	changed := make([]map[string]interface{}, len(matched))
+	var stdout bytes.Buffer
+	for n, i := range matched {
+		rec := map[string]interface{}{}
+		for k, v := range f.tasks[i] {
+			rec[k] = v
+		}
+		if err := change(rec); err != nil {
+			return fakeFailure("%v", err)
+		}
+		rec["modified"] = f.now()
+		changed[n] = rec
+		fmt.Fprintf(&stdout, "%s task %s '%s'.\n", verb, rec["uuid"], rec["description"])
+	}
+	for n, i := range matched {
+		f.tasks[i] = changed[n]
+	}
+	fmt.Fprintf(&stdout, "%s %d task(s).\n", summary, len(matched))
+	return Output{Stdout: stdout.Bytes()}
+}
+
+func (f *FakeRunner) sync(filter, mods []string, stdin []byte) Output {
+	return Output{Stdout: []byte("Sync successful.\n")}
+}
+
+// Return indexes of tasks matching all terms of the filter.
+func (f *FakeRunner) match(filter []string) ([]int, error) {
+	expr, err := parseFilterArgs(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]Task, len(f.tasks))
+	for i, rec := range f.tasks {
+		buf, _ := json.Marshal(rec)
+		json.Unmarshal(buf, &tasks[i])
+		tasks[i].Id = int32(f.id(i))
+	}
+	m := &Matcher{Config: f.config, Tasks: tasks, Now: f.clock()}
+
+	var matched []int
+	for i := range tasks {
+		ok, err := m.Match(&tasks[i], expr)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, i)
+		}
+	}
+	return matched, nil
+}
+
+// Return index of task with given UUID or -1.
+func (f *FakeRunner) find(uuid string) int {
+	for i, rec := range f.tasks {
+		if rec["uuid"] == uuid {
+			return i
+		}
+	}
+	return -1
+}
+
+// Return working set ID of the task with given index. Only pending and waiting tasks have IDs.
+func (f *FakeRunner) id(idx int) int {
+	id := 0
+	for i, rec := range f.tasks {
+		status, _ := rec["status"].(string)
+		if status != "pending" && status != "waiting" {
+			continue
+		}
+		id++
+		if i == idx {
+			return id
+		}
+	}
+	return 0
+}
+
+func (f *FakeRunner) clock() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+func (f *FakeRunner) now() string {
+	return f.clock().UTC().Format(dateLayout)
+}
+
+// Apply command-line modifications (`key:value`, `+tag`, `-tag` and plain description words) to the record.
+func applyFakeModifications(rec map[string]interface{}, mods []string) error {
+	var words []string
+	for n, mod := range mods {
+		// Everything after `--` is description.
+		if mod == "--" {
+			words = append(words, mods[n+1:]...)
+			break
+		}
+		switch {
+		case strings.HasPrefix(mod, "+") && len(mod) > 1:
+			if !recordHasTag(rec, mod[1:]) {
+				rec["tags"] = append(recordTags(rec), mod[1:])
+			}
+		case strings.HasPrefix(mod, "-") && len(mod) > 1:
+			var tags []interface{}
+			for _, tag := range recordTags(rec) {
+				if tag != mod[1:] {
+					tags = append(tags, tag)
+				}
+			}
+			if len(tags) == 0 {
+				delete(rec, "tags")
+			} else {
+				rec["tags"] = tags
+			}
+		default:
+			key, val, ok := strings.Cut(mod, ":")
+			if !ok || strings.ContainsAny(key, " \t") {
+				words = append(words, mod)
+				continue
+			}
+			if key == "uuid" || key == "id" {
+				return fmt.Errorf("The '%s' attribute does not allow a value of '%s'.", key, val)
+			}
+			val = unquoteFilterValue(val)
+			if val == "" {
+				delete(rec, key)
+			} else {
+				rec[key] = val
+			}
+		}
+	}
+	if len(words) > 0 {
+		rec["description"] = strings.Join(words, " ")
+	}
+	return nil
+}
+
+// Return words of command-line, skipping `--` terminator.
+func fakeWords(mods []string) []string {
+	var words []string
+	for _, mod := range mods {
+		if mod != "--" {
+			words = append(words, mod)
+		}
+	}
+	return words
+}
+
+func recordTags(rec map[string]interface{}) []interface{} {
+	tags, _ := rec["tags"].([]interface{})
+	return tags
+}
+
+func recordHasTag(rec map[string]interface{}, tag string) bool {
+	for _, t := range recordTags(rec) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare records ignoring attributes maintained by the database.
+func recordsEqual(a, b map[string]interface{}) bool {
+	strip := func(rec map[string]interface{}) string {
+		keys := make([]string, 0, len(rec))
+		for k := range rec {
+			if k != "id" && k != "urgency" && k != "modified" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var buf bytes.Buffer
+		for _, k := range keys {
+			v, _ := json.Marshal(rec[k])
+			fmt.Fprintf(&buf, "%s=%s;", k, v)
+		}
+		return buf.String()
+	}
+	return strip(a) == strip(b)
+}
+
+// Convert task to a generic JSON object.
+func taskToRecord(task *Task) map[string]interface{} {
+	rec := map[string]interface{}{}
+	buf, _ := json.Marshal(task)
+	json.Unmarshal(buf, &rec)
+	delete(rec, "id")
+	return rec
+}
+
+func fakeFailure(format string, a ...interface{}) Output {
+	return Output{Stderr: []byte(fmt.Sprintf(format, a...) + "\n"), ExitCode: 1}
+}
@@ -0,0 +1,440 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Taskwarrior filter expressions.
+//
+// Filter expressions are trees of And, Or and Not nodes with attribute, tag and pattern terms in leaves. Every
+// expression renders to `task` command-line arguments, e.g.
+//
+//	And(Project("work"), Or(Tag(VirtualOverdue), AttrMod("priority", ModIs, "H")), WithoutTag("someday"))
+//
+// becomes
+//
+//	project:work and ( +OVERDUE or priority.is:H ) and -someday
+//
+// Values are quoted when taskwarrior would split them, so no shell escaping is needed. For details of the filter
+// language see: https://taskwarrior.org/docs/filter/.
+
+package taskwarrior
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Expr is a taskwarrior filter expression.
+type Expr interface {
+	// Return `task` command-line arguments of the expression.
+	Args() []string
+}
+
+// Attribute modifier, see https://taskwarrior.org/docs/filter/#attribute-modifiers.
+type Modifier string
+
+const (
+	ModEquals     Modifier = ""           // Attribute equals the value (project:work matches subprojects)
+	ModIs         Modifier = "is"         // Attribute exactly equals the value
+	ModNot        Modifier = "not"        // Attribute differs from the value
+	ModBefore     Modifier = "before"     // Date or value is less than the value
+	ModAfter      Modifier = "after"      // Date or value is greater than the value
+	ModUnder      Modifier = "under"      // Same as before, for numbers
+	ModOver       Modifier = "over"       // Same as after, for numbers
+	ModNone       Modifier = "none"       // Attribute is not set
+	ModAny        Modifier = "any"        // Attribute is set
+	ModHas        Modifier = "has"        // Attribute contains the value
+	ModHasnt      Modifier = "hasnt"      // Attribute doesn't contain the value
+	ModStartsWith Modifier = "startswith" // Attribute starts with the value
+	ModEndsWith   Modifier = "endswith"   // Attribute ends with the value
+	ModWord       Modifier = "word"       // Attribute contains the value as a whole word
+	ModNoWord     Modifier = "noword"     // Attribute doesn't contain the value as a whole word
+)
+
+// Modifiers known to the filter parser.
+var filterModifiers = map[Modifier]bool{
+	ModIs: true, ModNot: true, ModBefore: true, ModAfter: true, ModUnder: true, ModOver: true, ModNone: true,
+	ModAny: true, ModHas: true, ModHasnt: true, ModStartsWith: true, ModEndsWith: true, ModWord: true,
+	ModNoWord: true,
+}
+
+// Virtual tags computed by taskwarrior from task attributes.
+const (
+	VirtualActive    = "ACTIVE"    // Task is started
+	VirtualAnnotated = "ANNOTATED" // Task has annotations
+	VirtualBlocked   = "BLOCKED"   // Task depends on unfinished tasks
+	VirtualBlocking  = "BLOCKING"  // Unfinished tasks depend on the task
+	VirtualChild     = "CHILD"     // Task is an instance of recurring task
+	VirtualCompleted = "COMPLETED" // Task is completed
+	VirtualDeleted   = "DELETED"   // Task is deleted
+	VirtualDue       = "DUE"       // Task is due within 7 days
+	VirtualOverdue   = "OVERDUE"   // Task is past its due date
+	VirtualParent    = "PARENT"    // Task is a recurring task template
+	VirtualPending   = "PENDING"   // Task is pending
+	VirtualPriority  = "PRIORITY"  // Task has priority
+	VirtualProject   = "PROJECT"   // Task has project
+	VirtualReady     = "READY"     // Task is pending, not blocked and not scheduled in the future
+	VirtualScheduled = "SCHEDULED" // Task is scheduled
+	VirtualTagged    = "TAGGED"    // Task has tags
+	VirtualToday     = "TODAY"     // Task is due today
+	VirtualTomorrow  = "TOMORROW"  // Task is due tomorrow
+	VirtualUnblocked = "UNBLOCKED" // Task is not blocked
+	VirtualUntil     = "UNTIL"     // Task expires
+	VirtualWaiting   = "WAITING"   // Task is hidden until its wait date
+	VirtualYesterday = "YESTERDAY" // Task was due yesterday
+)
+
+// AndExpr matches tasks matching all its expressions. Empty AndExpr matches every task.
+type AndExpr []Expr
+
+// OrExpr matches tasks matching any of its expressions. Empty OrExpr matches every task.
+type OrExpr []Expr
+
+// NotExpr matches tasks not matching its expression.
+type NotExpr struct {
+	Expr Expr
+}
+
+// TagExpr matches tasks having the tag, or not having it if Exclude is set. Upper case names are virtual tags.
+type TagExpr struct {
+	Tag     string
+	Exclude bool
+}
+
+// AttrExpr compares attribute of the task with the value, e.g. `due.before:20260201T000000Z`. UDA can be used as
+// well as built-in attributes.
+type AttrExpr struct {
+	Name     string
+	Modifier Modifier
+	Value    string
+}
+
+// PatternExpr matches tasks whose description matches the regular expression.
+type PatternExpr struct {
+	Pattern string
+}
+
+// Combine expressions that all have to match.
+func And(exprs ...Expr) Expr {
+	return AndExpr(exprs)
+}
+
+// Combine expressions of which any has to match.
+func Or(exprs ...Expr) Expr {
+	return OrExpr(exprs)
+}
+
+// Negate the expression.
+func Not(expr Expr) Expr {
+	return NotExpr{Expr: expr}
+}
+
+// Match tasks having the tag. Virtual tags, like VirtualOverdue, can be used too.
+func Tag(tag string) Expr {
+	return TagExpr{Tag: tag}
+}
+
+// Match tasks not having the tag.
+func WithoutTag(tag string) Expr {
+	return TagExpr{Tag: tag, Exclude: true}
+}
+
+// Compare attribute with the value. Dates (time.Time and TaskTime) and numbers are formatted the way taskwarrior
+// parses them.
+func Attr(name string, value interface{}) Expr {
+	return AttrMod(name, ModEquals, value)
+}
+
+// Compare attribute with the value using the modifier.
+func AttrMod(name string, mod Modifier, value interface{}) Expr {
+	return AttrExpr{Name: name, Modifier: mod, Value: formatFilterValue(value)}
+}
+
+// Match tasks in the project and its subprojects.
+func Project(project string) Expr {
+	return Attr("project", project)
+}
+
+// Match tasks with the status.
+func Status(status string) Expr {
+	return Attr("status", status)
+}
+
+// Match task with the UUID.
+func UUID(uuid string) Expr {
+	return Attr("uuid", uuid)
+}
+
+// Match tasks with the date attribute before the time.
+func Before(name string, t time.Time) Expr {
+	return AttrMod(name, ModBefore, t)
+}
+
+// Match tasks with the date attribute after the time.
+func After(name string, t time.Time) Expr {
+	return AttrMod(name, ModAfter, t)
+}
+
+// Match tasks with the date attribute within the range. Zero from or to leaves the range open.
+func DateRange(name string, from, to time.Time) Expr {
+	var exprs AndExpr
+	if !from.IsZero() {
+		exprs = append(exprs, After(name, from))
+	}
+	if !to.IsZero() {
+		exprs = append(exprs, Before(name, to))
+	}
+	return exprs
+}
+
+// Match tasks whose description matches the regular expression.
+func DescriptionMatches(pattern string) Expr {
+	return PatternExpr{Pattern: pattern}
+}
+
+func (e AndExpr) Args() []string {
+	return joinFilterArgs(e, "and")
+}
+
+func (e OrExpr) Args() []string {
+	return joinFilterArgs(e, "or")
+}
+
+func (e NotExpr) Args() []string {
+	return append([]string{"!"}, groupFilterArgs(e.Expr)...)
+}
+
+func (e TagExpr) Args() []string {
+	if e.Exclude {
+		return []string{"-" + e.Tag}
+	}
+	return []string{"+" + e.Tag}
+}
+
+func (e AttrExpr) Args() []string {
+	name := e.Name
+	if e.Modifier != ModEquals {
+		name += "." + string(e.Modifier)
+	}
+	return []string{name + ":" + quoteFilterValue(e.Value)}
+}
+
+func (e PatternExpr) Args() []string {
+	return []string{"/" + strings.ReplaceAll(e.Pattern, "/", `\/`) + "/"}
+}
+
+func (e AndExpr) String() string     { return strings.Join(e.Args(), " ") }
+func (e OrExpr) String() string      { return strings.Join(e.Args(), " ") }
+func (e NotExpr) String() string     { return strings.Join(e.Args(), " ") }
+func (e TagExpr) String() string     { return strings.Join(e.Args(), " ") }
+func (e AttrExpr) String() string    { return strings.Join(e.Args(), " ") }
+func (e PatternExpr) String() string { return strings.Join(e.Args(), " ") }
+
+// Render expressions separated by the operator.
+func joinFilterArgs(exprs []Expr, op string) []string {
+	var args []string
+	for i, expr := range exprs {
+		if i > 0 {
+			args = append(args, op)
+		}
+		args = append(args, groupFilterArgs(expr)...)
+	}
+	return args
+}
+
+// Render expression as an operand, in parentheses if it consists of several terms.
+func groupFilterArgs(expr Expr) []string {
+	args := expr.Args()
+	switch e := expr.(type) {
+	case AndExpr:
+		if len(e) > 1 {
+			return append(append([]string{"("}, args...), ")")
+		}
+	case OrExpr:
+		if len(e) > 1 {
+			return append(append([]string{"("}, args...), ")")
+		}
+	}
+	return args
+}
+
+func formatFilterValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(dateLayout)
+	case TaskTime:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Quote value of `name:value` argument if taskwarrior lexer would split it or treat it as an operator. Empty value
+// stays empty, like in `project:`.
+func quoteFilterValue(value string) string {
+	plain := true
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("._-:,@+%#", r) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Remove quotes added by quoteFilterValue.
+func unquoteFilterValue(value string) string {
+	if len(value) < 2 || (value[0] != '"' && value[0] != '\'') || value[len(value)-1] != value[0] {
+		return value
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, r := range value[1 : len(value)-1] {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Term of parsed command line that is not an expression of this package, like task ID or bare word.
+type rawFilterTerm string
+
+func (e rawFilterTerm) Args() []string {
+	return []string{string(e)}
+}
+
+// Parse command-line filter arguments, like the ones rendered by Expr.Args, back into expression. Adjacent terms
+// are combined with `and`, which binds tighter than `or`.
+func parseFilterArgs(args []string) (Expr, error) {
+	p := &filterParser{args: args}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.args) {
+		return nil, fmt.Errorf("unexpected '%s' in filter", p.args[p.pos])
+	}
+	return expr, nil
+}
+
+type filterParser struct {
+	args []string
+	pos  int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.args) {
+		return p.args[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) parseOr() (Expr, error) {
+	var exprs OrExpr
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if p.peek() != "or" {
+			break
+		}
+		p.pos++
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *filterParser) parseAnd() (Expr, error) {
+	var exprs AndExpr
+	for p.pos < len(p.args) && p.peek() != "or" && p.peek() != ")" {
+		if p.peek() == "and" {
+			p.pos++
+			continue
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *filterParser) parseUnary() (Expr, error) {
+	arg := p.peek()
+	p.pos++
+	switch {
+	case arg == "!" || arg == "not":
+		if p.pos >= len(p.args) {
+			return nil, fmt.Errorf("missing operand of '%s' in filter", arg)
+		}
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return NotExpr{Expr: expr}, nil
+	case arg == "(":
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ')' in filter")
+		}
+		p.pos++
+		return expr, nil
+	}
+	return parseFilterTerm(arg), nil
+}
+
+// Parse single filter term.
+func parseFilterTerm(arg string) Expr {
+	switch {
+	case len(arg) > 1 && (arg[0] == '+' || arg[0] == '-') && isFilterTagName(arg[1:]):
+		return TagExpr{Tag: arg[1:], Exclude: arg[0] == '-'}
+	case len(arg) > 1 && arg[0] == '/' && arg[len(arg)-1] == '/':
+		return PatternExpr{Pattern: strings.ReplaceAll(arg[1:len(arg)-1], `\/`, "/")}
+	}
+	if key, val, ok := strings.Cut(arg, ":"); ok && isFilterTagName(key) {
+		name, mod := key, ""
+		if i := strings.LastIndex(key, "."); i >= 0 && filterModifiers[Modifier(key[i+1:])] {
+			name, mod = key[:i], key[i+1:]
+		}
+		return AttrExpr{Name: name, Modifier: Modifier(mod), Value: unquoteFilterValue(val)}
+	}
+	return rawFilterTerm(arg)
+}
+
+func isFilterTagName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return unicode.IsLetter([]rune(s)[0]) || s[0] == '_'
+}
@@ -0,0 +1,103 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpr_Args(t *testing.T) {
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     Expr
+		expected string
+	}{
+		{Project("work"), "project:work"},
+		{Tag(VirtualOverdue), "+OVERDUE"},
+		{WithoutTag("someday"), "-someday"},
+		{And(Project("work"), Tag("next")), "project:work and +next"},
+		{Or(Tag("a"), Tag("b")), "+a or +b"},
+		{And(Project("work"), Or(Tag("a"), Tag("b"))), "project:work and ( +a or +b )"},
+		{Or(And(Tag("a"), Tag("b")), Tag("c")), "( +a and +b ) or +c"},
+		{Not(Or(Tag("a"), Tag("b"))), "! ( +a or +b )"},
+		{Not(Tag("a")), "! +a"},
+		{Before("due", from), "due.before:20260201T000000Z"},
+		{DateRange("due", from, to), "due.after:20260201T000000Z and due.before:20260301T000000Z"},
+		{DateRange("due", time.Time{}, to), "due.before:20260301T000000Z"},
+		{AttrMod("scheduled", ModAfter, MustParseTaskTime("20260206T120000Z")), "scheduled.after:20260206T120000Z"},
+		{AttrMod("estimate", ModOver, 2.5), "estimate.over:2.5"},
+		{AttrMod("estimate", ModUnder, 3), "estimate.under:3"},
+		{AttrMod("description", ModHas, "some text"), `description.has:"some text"`},
+		{AttrMod("description", ModStartsWith, `say "hi"`), `description.startswith:"say \"hi\""`},
+		{AttrMod("description", ModWord, "(x)"), `description.word:"(x)"`},
+		{AttrMod("project", ModEndsWith, "backend"), "project.endswith:backend"},
+		{AttrMod("tags", ModHasnt, "home"), "tags.hasnt:home"},
+		{AttrMod("due", ModNone, nil), "due.none:"},
+		{Attr("sprint", "S-1"), "sprint:S-1"},
+		{DescriptionMatches("fix.*bug"), "/fix.*bug/"},
+		{DescriptionMatches("a/b"), `/a\/b/`},
+		{And(), ""},
+	}
+	for _, test := range tests {
+		got := strings.Join(test.expr.Args(), " ")
+		if got != test.expected {
+			t.Errorf("Incorrect arguments:\nexpected %s\ngot      %s", test.expected, got)
+		}
+	}
+}
+
+func TestParseFilterArgs(t *testing.T) {
+	exprs := []Expr{
+		And(Project("work"), Or(Tag("a"), Not(Tag("b"))), WithoutTag("c")),
+		Or(And(Tag("a"), AttrMod("description", ModHas, `"quoted" text`)), DescriptionMatches("a/b")),
+		AttrMod("annotations.entry", ModBefore, "20260201T000000Z"),
+		Attr("uuid", "00000000-0000-0000-0000-000000000001"),
+	}
+	for _, expr := range exprs {
+		parsed, err := parseFilterArgs(expr.Args())
+		if err != nil {
+			t.Errorf("Can't parse %v: %v", expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, expr) {
+			t.Errorf("Parsed expression differs:\nexpected %#v\ngot      %#v", expr, parsed)
+		}
+	}
+
+	// Implicit and, raw terms
+	parsed, err := parseFilterArgs([]string{"1", "+next", "or", "report"})
+	expected := OrExpr{AndExpr{rawFilterTerm("1"), TagExpr{Tag: "next"}}, rawFilterTerm("report")}
+	if err != nil || !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("Unexpected expression: %#v (%v)", parsed, err)
+	}
+
+	for _, args := range [][]string{{"(", "+a"}, {"+a", ")"}, {"!"}} {
+		if _, err := parseFilterArgs(args); err == nil {
+			t.Errorf("Incorrect filter %v was parsed", args)
+		}
+	}
+}
+
+func TestFilter_Expr(t *testing.T) {
+	tw := newFakeTaskWarrior(t, queryFixture...)
+
+	filter := Filter{Expr: Or(Project("home"), And(Tag("urgent"), Not(Project("work.backend"))))}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "expression", tasks, err, queryFixture[0].Uuid, queryFixture[2].Uuid)
+
+	filter = Filter{Status: "pending", Expr: DescriptionMatches("^Fix")}
+	tasks, err = tw.QueryTasks(filter)
+	assertQueryResult(t, "pattern", tasks, err, queryFixture[1].Uuid)
+
+	args := Filter{Project: "work", Tags: []string{"a", "b"}, UUIDs: []string{"1", "2"}}.Args()
+	expected := "project:work and +a and +b and ( uuid:1 or uuid:2 )"
+	if strings.Join(args, " ") != expected {
+		t.Errorf("Unexpected filter arguments: %v", args)
+	}
+}
@@ -0,0 +1,308 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Dependency graph of tasks.
+//
+// Task.Depends lists UUIDs of tasks that must be done first. A task is blocked while any of them is pending or
+// waiting, and it is blocking the open tasks that depend on it. Dependencies on tasks missing from the graph are
+// ignored, like taskwarrior ignores dependencies on purged tasks.
+
+package taskwarrior
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+)
+
+// DependencyGraph is a graph of dependencies between tasks. It is a snapshot: changes of the tasks made after it
+// was built are not reflected.
+type DependencyGraph struct {
+	tasks    []*Task
+	byUUID   map[string]*Task
+	depends  map[string][]string // Known dependencies of tasks
+	blocking map[string][]string // Tasks depending on the task
+}
+
+// Build dependency graph of given tasks. If several tasks have the same UUID, the last one replaces the others, like
+// it does on import.
+func NewDependencyGraph(tasks []Task) *DependencyGraph {
+	g := &DependencyGraph{
+		byUUID:   map[string]*Task{},
+		depends:  map[string][]string{},
+		blocking: map[string][]string{},
+	}
+	index := map[string]int{}
+	for i := range tasks {
+		if tasks[i].Uuid == "" {
+			continue
+		}
+		if j, ok := index[tasks[i].Uuid]; ok {
+			g.tasks[j] = &tasks[i]
+		} else {
+			index[tasks[i].Uuid] = len(g.tasks)
+			g.tasks = append(g.tasks, &tasks[i])
+		}
+		g.byUUID[tasks[i].Uuid] = &tasks[i]
+	}
+	for _, task := range g.tasks {
+		for _, dep := range task.Depends {
+			if _, ok := g.byUUID[dep]; !ok || containsString(g.depends[task.Uuid], dep) {
+				continue
+			}
+			g.depends[task.Uuid] = append(g.depends[task.Uuid], dep)
+			g.blocking[dep] = append(g.blocking[dep], task.Uuid)
+		}
+	}
+	return g
+}
+
+// Return dependency graph of tasks of the instance.
+func (tw *TaskWarrior) DependencyGraph() *DependencyGraph {
+	return NewDependencyGraph(tw.Tasks)
+}
+
+// Return open tasks the task depends on.
+func (g *DependencyGraph) Blockers(uuid string) []Task {
+	return g.openTasks(g.depends[uuid])
+}
+
+// Return open tasks that depend on the task.
+func (g *DependencyGraph) Blocking(uuid string) []Task {
+	return g.openTasks(g.blocking[uuid])
+}
+
+// Check whether the task depends on an open task.
+func (g *DependencyGraph) IsBlocked(uuid string) bool {
+	return len(g.Blockers(uuid)) > 0
+}
+
+func (g *DependencyGraph) openTasks(uuids []string) []Task {
+	var tasks []Task
+	for _, uuid := range uuids {
+		if task := g.byUUID[uuid]; isPendingStatus(task.Status) {
+			tasks = append(tasks, *task)
+		}
+	}
+	return tasks
+}
+
+// Check whether making the task depend on another one would create a cycle.
+func (g *DependencyGraph) WouldCycle(uuid, dependsOn string) bool {
+	if uuid == dependsOn {
+		return true
+	}
+	return g.path(dependsOn, uuid, map[string]bool{}) != nil
+}
+
+// Return chain of dependencies leading from one task to another, or nil if there is none.
+func (g *DependencyGraph) path(from, to string, visited map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+	if visited[from] {
+		return nil
+	}
+	visited[from] = true
+	for _, dep := range g.depends[from] {
+		if p := g.path(dep, to, visited); p != nil {
+			return append([]string{from}, p...)
+		}
+	}
+	return nil
+}
+
+// Return *CycleError describing a dependency cycle, or nil if dependencies have no cycles.
+func (g *DependencyGraph) DetectCycle() error {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var stack []string
+	var cycle []string
+
+	var visit func(uuid string) bool
+	visit = func(uuid string) bool {
+		state[uuid] = visiting
+		stack = append(stack, uuid)
+		for _, dep := range g.depends[uuid] {
+			switch state[dep] {
+			case visiting:
+				for i := range stack {
+					if stack[i] == dep {
+						cycle = append(append([]string{}, stack[i:]...), dep)
+						return true
+					}
+				}
+			case unvisited:
+				if visit(dep) {
+					return true
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[uuid] = done
+		return false
+	}
+
+	for _, task := range g.tasks {
+		if state[task.Uuid] == unvisited && visit(task.Uuid) {
+			return &CycleError{UUIDs: cycle}
+		}
+	}
+	return nil
+}
+
+// Return all tasks ordered so that every task follows the tasks it depends on. Of the tasks that are ready, the one
+// that comes first in the original order is taken. Returns *CycleError if dependencies have a cycle.
+func (g *DependencyGraph) TopologicalOrder() ([]Task, error) {
+	if err := g.DetectCycle(); err != nil {
+		return nil, err
+	}
+
+	// Kahn's algorithm with ready tasks kept in a heap of their original positions.
+	position := map[string]int{}
+	for i, task := range g.tasks {
+		position[task.Uuid] = i
+	}
+	inDegree := make([]int, len(g.tasks))
+	ready := &intHeap{}
+	for i, task := range g.tasks {
+		inDegree[i] = len(g.depends[task.Uuid])
+		if inDegree[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+	order := make([]Task, 0, len(g.tasks))
+	for ready.Len() > 0 {
+		task := g.tasks[heap.Pop(ready).(int)]
+		order = append(order, *task)
+		for _, uuid := range g.blocking[task.Uuid] {
+			i := position[uuid]
+			if inDegree[i]--; inDegree[i] == 0 {
+				heap.Push(ready, i)
+			}
+		}
+	}
+	return order, nil
+}
+
+// Min-heap of integers for container/heap.
+type intHeap []int
+
+func (h intHeap) Len() int            { return len(h) }
+func (h intHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Return number of open tasks in the longest chain of dependencies that must be done before the task.
+func (g *DependencyGraph) CriticalPathLength(uuid string) int {
+	return len(g.criticalPath(uuid, map[string][]string{}, map[string]bool{})) - 1
+}
+
+// Return the longest chain of open tasks, each one depending on the previous one.
+func (g *DependencyGraph) CriticalPath() []Task {
+	memo := map[string][]string{}
+	var longest []string
+	for _, task := range g.tasks {
+		if !isPendingStatus(task.Status) {
+			continue
+		}
+		if p := g.criticalPath(task.Uuid, memo, map[string]bool{}); len(p) > len(longest) {
+			longest = p
+		}
+	}
+	var tasks []Task
+	for _, uuid := range longest {
+		tasks = append(tasks, *g.byUUID[uuid])
+	}
+	return tasks
+}
+
+// Return the longest chain of open dependencies ending with the task. Dependencies closing a cycle are skipped.
+func (g *DependencyGraph) criticalPath(uuid string, memo map[string][]string, visiting map[string]bool) []string {
+	if p, ok := memo[uuid]; ok {
+		return p
+	}
+	visiting[uuid] = true
+	var longest []string
+	for _, dep := range g.depends[uuid] {
+		if visiting[dep] || !isPendingStatus(g.byUUID[dep].Status) {
+			continue
+		}
+		if p := g.criticalPath(dep, memo, visiting); len(p) > len(longest) {
+			longest = p
+		}
+	}
+	delete(visiting, uuid)
+	p := append(append([]string{}, longest...), uuid)
+	memo[uuid] = p
+	return p
+}
+
+// Return the graph in Graphviz DOT format. Edges lead from a task to the tasks depending on it, closed tasks are
+// dashed.
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	for _, task := range g.tasks {
+		style := ""
+		if !isPendingStatus(task.Status) {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "\t%q [label=%q%s];\n", task.Uuid, graphLabel(task), style)
+	}
+	for _, task := range g.tasks {
+		for _, dep := range g.depends[task.Uuid] {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", dep, task.Uuid)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Return the graph as Mermaid flowchart. Edges lead from a task to the tasks depending on it, closed tasks have
+// `done` class.
+func (g *DependencyGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, task := range g.tasks {
+		label := strings.ReplaceAll(graphLabel(task), `"`, "#quot;")
+		fmt.Fprintf(&b, "\t%s[\"%s\"]", mermaidID(task.Uuid), label)
+		if !isPendingStatus(task.Status) {
+			b.WriteString(":::done")
+		}
+		b.WriteString("\n")
+	}
+	for _, task := range g.tasks {
+		for _, dep := range g.depends[task.Uuid] {
+			fmt.Fprintf(&b, "\t%s --> %s\n", mermaidID(dep), mermaidID(task.Uuid))
+		}
+	}
+	b.WriteString("\tclassDef done stroke-dasharray: 5 5\n")
+	return b.String()
+}
+
+// Return label of the task node: ID or short UUID followed by the description.
+func graphLabel(task *Task) string {
+	if task.Id != 0 {
+		return fmt.Sprintf("%d: %s", task.Id, task.Description)
+	}
+	short := task.Uuid
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	return fmt.Sprintf("%s: %s", short, task.Description)
+}
+
+func mermaidID(uuid string) string {
+	return "t" + strings.ReplaceAll(uuid, "-", "")
+}
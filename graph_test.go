@@ -0,0 +1,180 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	graphA = "00000000-0000-0000-0000-00000000000a"
+	graphB = "00000000-0000-0000-0000-00000000000b"
+	graphC = "00000000-0000-0000-0000-00000000000c"
+	graphD = "00000000-0000-0000-0000-00000000000d"
+	graphE = "00000000-0000-0000-0000-00000000000e"
+	graphF = "00000000-0000-0000-0000-00000000000f"
+)
+
+// Plan: D needs A and C, C needs B, B needs A. F needs completed E.
+func graphFixture() []Task {
+	return []Task{
+		{Id: 4, Uuid: graphD, Description: "Release", Status: "pending", Depends: []string{graphA, graphC}},
+		{Id: 3, Uuid: graphC, Description: "Test", Status: "pending", Depends: []string{graphB}},
+		{Id: 2, Uuid: graphB, Description: "Build", Status: "waiting", Depends: []string{graphA}},
+		{Id: 1, Uuid: graphA, Description: `Write "spec"`, Status: "pending"},
+		{Uuid: graphE, Description: "Research", Status: "completed"},
+		{Id: 5, Uuid: graphF, Description: "Present", Status: "pending",
+			Depends: []string{graphE, "00000000-0000-0000-0000-000000000999"}},
+	}
+}
+
+func uuidsOf(tasks []Task) string {
+	var uuids []string
+	for _, task := range tasks {
+		uuids = append(uuids, task.Uuid[len(task.Uuid)-1:])
+	}
+	return strings.Join(uuids, ",")
+}
+
+func TestDependencyGraph_Queries(t *testing.T) {
+	g := NewDependencyGraph(graphFixture())
+
+	tests := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"Blockers(D)", uuidsOf(g.Blockers(graphD)), "a,c"},
+		{"Blockers(B)", uuidsOf(g.Blockers(graphB)), "a"},
+		{"Blockers(F)", uuidsOf(g.Blockers(graphF)), ""},
+		{"Blocking(A)", uuidsOf(g.Blocking(graphA)), "d,b"},
+		{"Blocking(E)", uuidsOf(g.Blocking(graphE)), "f"},
+		{"CriticalPath", uuidsOf(g.CriticalPath()), "a,b,c,d"},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, test.got)
+		}
+	}
+
+	if !g.IsBlocked(graphD) || g.IsBlocked(graphA) || g.IsBlocked(graphF) {
+		t.Error("Incorrect blocked state")
+	}
+	for uuid, expected := range map[string]int{graphD: 3, graphC: 2, graphA: 0, graphF: 0, "unknown": 0} {
+		if got := g.CriticalPathLength(uuid); got != expected {
+			t.Errorf("CriticalPathLength(%s): expected %d, got %d", uuid, expected, got)
+		}
+	}
+}
+
+func TestDependencyGraph_TopologicalOrder(t *testing.T) {
+	g := NewDependencyGraph(graphFixture())
+	order, err := g.TopologicalOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := uuidsOf(order); got != "a,b,c,d,e,f" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+}
+
+func TestDependencyGraph_DuplicateUUID(t *testing.T) {
+	tasks := graphFixture()
+	copied := tasks[2]
+	copied.Description = "Build again"
+	tasks = append(tasks, copied)
+
+	done := make(chan []Task)
+	go func() {
+		g := NewDependencyGraph(tasks)
+		order, _ := g.TopologicalOrder()
+		done <- order
+	}()
+	select {
+	case order := <-done:
+		if got := uuidsOf(order); got != "a,b,c,d,e,f" {
+			t.Errorf("Unexpected order: %s", got)
+		}
+		if order[1].Description != "Build again" {
+			t.Errorf("Expected the last task with duplicate UUID, got %+v", order[1])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TopologicalOrder hangs on duplicate UUID")
+	}
+}
+
+func TestDependencyGraph_Cycles(t *testing.T) {
+	tasks := graphFixture()
+	g := NewDependencyGraph(tasks)
+	if err := g.DetectCycle(); err != nil {
+		t.Errorf("Unexpected cycle: %v", err)
+	}
+	if !g.WouldCycle(graphA, graphD) || !g.WouldCycle(graphA, graphA) || g.WouldCycle(graphD, graphF) {
+		t.Error("Incorrect cycle prediction")
+	}
+
+	tasks[3].Depends = []string{graphC}
+	g = NewDependencyGraph(tasks)
+	err := g.DetectCycle()
+	var cycle *CycleError
+	if !errors.As(err, &cycle) || !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("Expected cycle error, got %v", err)
+	}
+	if got := strings.Join(cycle.UUIDs, " "); got != graphA+" "+graphC+" "+graphB+" "+graphA {
+		t.Errorf("Unexpected cycle: %s", got)
+	}
+	if _, err := g.TopologicalOrder(); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+	if got := g.CriticalPathLength(graphD); got != 3 {
+		t.Errorf("Unexpected critical path length with cycle: %d", got)
+	}
+}
+
+func TestDependencyGraph_Export(t *testing.T) {
+	tasks := graphFixture()[3:5]
+	tasks[0].Depends = []string{graphE}
+	g := NewDependencyGraph(tasks)
+
+	dot := `digraph dependencies {
+	"00000000-0000-0000-0000-00000000000a" [label="1: Write \"spec\""];
+	"00000000-0000-0000-0000-00000000000e" [label="00000000: Research", style=dashed];
+	"00000000-0000-0000-0000-00000000000e" -> "00000000-0000-0000-0000-00000000000a";
+}
+`
+	if got := g.DOT(); got != dot {
+		t.Errorf("Unexpected DOT output:\n%s", got)
+	}
+
+	mermaid := `flowchart TD
+	t0000000000000000000000000000000a["1: Write #quot;spec#quot;"]
+	t0000000000000000000000000000000e["00000000: Research"]:::done
+	t0000000000000000000000000000000e --> t0000000000000000000000000000000a
+	classDef done stroke-dasharray: 5 5
+`
+	if got := g.Mermaid(); got != mermaid {
+		t.Errorf("Unexpected Mermaid output:\n%s", got)
+	}
+}
+
+func TestTaskWarrior_CommitCycle(t *testing.T) {
+	tw1 := newFakeTaskWarrior(t, graphFixture()...)
+	if err := tw1.FetchAllTasks(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range tw1.Tasks {
+		if tw1.Tasks[i].Uuid == graphA {
+			tw1.Tasks[i].Depends = []string{graphD}
+		}
+	}
+	if _, err := tw1.Commit(); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+	if len(tw1.DirtyTasks()) != 1 {
+		t.Error("Changes should be kept after failed commit")
+	}
+}
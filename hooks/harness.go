@@ -0,0 +1,144 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Test harness that runs hooks like taskwarrior does.
+//
+// Invoke runs a Hook in-process and InvokeBinary runs a hook executable. Both check the output the way taskwarrior
+// does: on-add and on-modify hooks that accept the command must print exactly one task with the UUID of the input
+// task, other hooks must not print tasks. Violations are reported as errors wrapping ErrProtocol.
+
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Returned when hook output violates the hook protocol.
+var ErrProtocol = errors.New("hook protocol violation")
+
+// Result is the outcome of a hook run.
+type Result struct {
+	ExitCode int
+	Task     *taskwarrior.Task // Task printed by accepting on-add or on-modify hook
+	Feedback []string          // Lines shown to the user
+	Output   string            // Whole output of the hook
+}
+
+// Check whether the hook accepted the command.
+func (r *Result) Accepted() bool {
+	return r.ExitCode == 0
+}
+
+// Return context like the one of `task <command>` of given taskwarrior version with default paths.
+func NewContext(command, version string) *Context {
+	return &Context{
+		API:     "2",
+		Args:    "task " + command,
+		Command: command,
+		RC:      taskwarrior.PathExpandTilda("~/.taskrc"),
+		Data:    taskwarrior.PathExpandTilda("~/.task"),
+		Version: version,
+	}
+}
+
+// Run the hook in-process for the event with given input tasks: none for on-launch, the new task for on-add, the
+// original and the modified task for on-modify and changed tasks for on-exit.
+func Invoke(h *Hook, event Event, c *Context, tasks ...taskwarrior.Task) (*Result, error) {
+	input, err := hookInput(event, c, tasks)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	code := h.Run(event, c.Arguments(), bytes.NewReader(input), &out)
+	return parseOutput(event, code, out.String(), tasks)
+}
+
+// Run hook executable like taskwarrior does. The event is detected from the file name. Non-zero exit status is
+// reported in Result, not as an error.
+func InvokeBinary(ctx context.Context, path string, c *Context, tasks ...taskwarrior.Task) (*Result, error) {
+	event, ok := EventOf(path)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a hook: name must start with on-launch, on-add, on-modify or on-exit", path)
+	}
+	input, err := hookInput(event, c, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, path, c.Arguments()...)
+	cmd.Stdin = bytes.NewReader(input)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	code := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return nil, fmt.Errorf("can't run hook %s: %w", path, err)
+		}
+		code = exitErr.ExitCode()
+	}
+	return parseOutput(event, code, out.String(), tasks)
+}
+
+// Encode input tasks for the event.
+func hookInput(event Event, c *Context, tasks []taskwarrior.Task) ([]byte, error) {
+	expected := map[Event]int{OnLaunch: 0, OnAdd: 1, OnModify: 2}
+	if n, ok := expected[event]; ok && len(tasks) != n {
+		return nil, fmt.Errorf("%s hook takes %d task(s), got %d", event, n, len(tasks))
+	}
+	var input bytes.Buffer
+	for i := range tasks {
+		line, err := taskwarrior.EncodeTask(&tasks[i], c.Capabilities())
+		if err != nil {
+			return nil, err
+		}
+		input.Write(append(line, '\n'))
+	}
+	return input.Bytes(), nil
+}
+
+// Split hook output on task and feedback and check it against the protocol.
+func parseOutput(event Event, code int, output string, input []taskwarrior.Task) (*Result, error) {
+	result := &Result{ExitCode: code, Output: output}
+	var tasks []taskwarrior.Task
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "{") {
+			var task taskwarrior.Task
+			if err := json.Unmarshal([]byte(line), &task); err != nil {
+				return result, fmt.Errorf("%w: %s hook printed invalid task: %v", ErrProtocol, event, err)
+			}
+			tasks = append(tasks, task)
+		} else if strings.TrimSpace(line) != "" {
+			result.Feedback = append(result.Feedback, line)
+		}
+	}
+
+	if code != 0 {
+		// Tasks printed by rejecting hooks are ignored
+		return result, nil
+	}
+	switch event {
+	case OnAdd, OnModify:
+		if len(tasks) != 1 {
+			return result, fmt.Errorf("%w: %s hook must print 1 task, printed %d", ErrProtocol, event, len(tasks))
+		}
+		if original := input[len(input)-1].Uuid; tasks[0].Uuid != original {
+			return result, fmt.Errorf("%w: %s hook changed UUID %s to %s", ErrProtocol, event, original, tasks[0].Uuid)
+		}
+		result.Task = &tasks[0]
+	default:
+		if len(tasks) != 0 {
+			return result, fmt.Errorf("%w: %s hook must not print tasks, printed %d", ErrProtocol, event, len(tasks))
+		}
+	}
+	return result, nil
+}
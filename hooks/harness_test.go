@@ -0,0 +1,118 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func TestInvoke(t *testing.T) {
+	hook := &Hook{
+		OnModify: func(c *Context, original, modified taskwarrior.Task) (taskwarrior.Task, string, error) {
+			if original.Status == "pending" && modified.Status == "completed" {
+				return modified, "Well done!", nil
+			}
+			return modified, "", nil
+		},
+		OnExit: func(c *Context, tasks []taskwarrior.Task) (string, error) {
+			if len(tasks) > 1 {
+				return "", errors.New("Too many changes")
+			}
+			return "", nil
+		},
+	}
+	c := NewContext("done", "2.6.2")
+	original := taskwarrior.Task{Uuid: "a", Description: "Buy milk", Status: "pending"}
+	modified := original
+	modified.Status = "completed"
+
+	result, err := Invoke(hook, OnModify, c, original, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Accepted() || result.Task == nil || result.Task.Status != "completed" ||
+		len(result.Feedback) != 1 || result.Feedback[0] != "Well done!" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	result, err = Invoke(hook, OnExit, c, original, modified)
+	if err != nil || result.Accepted() || result.Feedback[0] != "Too many changes" {
+		t.Errorf("Unexpected on-exit result: %+v (%v)", result, err)
+	}
+
+	// Wrong number of input tasks
+	if _, err := Invoke(hook, OnModify, c, original); err == nil {
+		t.Error("on-modify was invoked with single task")
+	}
+
+	// Changed UUID violates the protocol
+	hook.OnAdd = func(c *Context, task taskwarrior.Task) (taskwarrior.Task, string, error) {
+		task.Uuid = "b"
+		return task, "", nil
+	}
+	if _, err := Invoke(hook, OnAdd, c, original); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Expected protocol error, got %v", err)
+	}
+}
+
+// Helper that writes shell script hook to a temporary directory.
+func writeHook(t *testing.T, name, script string) string {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("/bin/sh is not available")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInvokeBinary(t *testing.T) {
+	ctx := context.Background()
+	c := NewContext("add", "2.6.2")
+	task := taskwarrior.Task{Uuid: "a", Description: "Buy milk"}
+
+	// Hook that passes the task through and prints its arguments
+	path := writeHook(t, "on-add-echo", `read task; echo "$task"; echo "$1 $3"`)
+	result, err := InvokeBinary(ctx, path, c, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Accepted() || result.Task == nil || result.Task.Description != "Buy milk" ||
+		len(result.Feedback) != 1 || result.Feedback[0] != "api:2 command:add" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	// Rejecting hook
+	path = writeHook(t, "on-add-reject", `echo "Not today"; exit 3`)
+	result, err = InvokeBinary(ctx, path, c, task)
+	if err != nil || result.ExitCode != 3 || result.Feedback[0] != "Not today" {
+		t.Errorf("Unexpected result of rejection: %+v (%v)", result, err)
+	}
+
+	// Accepting on-add hook must print the task
+	path = writeHook(t, "on-add-silent", `exit 0`)
+	if _, err := InvokeBinary(ctx, path, c, task); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Expected protocol error, got %v", err)
+	}
+	// on-launch hook must not print tasks
+	path = writeHook(t, "on-launch-noisy", `echo '{"uuid":"a"}'`)
+	if _, err := InvokeBinary(ctx, path, c); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Expected protocol error, got %v", err)
+	}
+
+	// Not a hook
+	if _, err := InvokeBinary(ctx, writeHook(t, "script", "exit 0"), c); err == nil {
+		t.Error("Program without event name was invoked")
+	}
+	if _, err := InvokeBinary(ctx, "/nonexistent/on-launch", c); err == nil {
+		t.Error("Missing program was invoked")
+	}
+}
@@ -0,0 +1,268 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Package hooks implements the taskwarrior hook protocol for hook programs written in Go.
+//
+// Taskwarrior runs executables from the hooks directory whose names start with the event name:
+//
+//	on-launch  -- no input, before the command runs
+//	on-add     -- one task JSON line, the task being added
+//	on-modify  -- two task JSON lines, the original and the modified task
+//	on-exit    -- task JSON lines of all tasks added or modified by the command
+//
+// Hooks receive `api:2 args:... command:... rc:... data:... version:...` arguments. On-add and on-modify hooks must
+// print exactly one task JSON line, the task to store. Other output lines are feedback shown to the user. Non-zero
+// exit status rejects the command, and then the task is ignored and the feedback is shown as an error.
+//
+// A single binary may handle several events when it is installed under several names:
+//
+//	func main() {
+//		hook := &hooks.Hook{OnAdd: func(c *hooks.Context, task taskwarrior.Task) (taskwarrior.Task, string, error) {
+//			if task.Project == "" {
+//				return task, "", errors.New("project is required")
+//			}
+//			return task, "", nil
+//		}}
+//		hook.Main()
+//	}
+package hooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Event is the moment taskwarrior runs a hook.
+type Event string
+
+const (
+	OnLaunch Event = "on-launch"
+	OnAdd    Event = "on-add"
+	OnModify Event = "on-modify"
+	OnExit   Event = "on-exit"
+)
+
+// Events in the order taskwarrior runs them.
+var Events = []Event{OnLaunch, OnAdd, OnModify, OnExit}
+
+// Return event of the hook program from its file name, e.g. on-add for `on-add-tags.py`.
+func EventOf(path string) (Event, bool) {
+	name := filepath.Base(path)
+	for _, event := range Events {
+		if strings.HasPrefix(name, string(event)) {
+			return event, true
+		}
+	}
+	return "", false
+}
+
+// Context contains arguments taskwarrior passes to hooks.
+type Context struct {
+	API     string            // Hook API version, "2"
+	Args    string            // Command line, e.g. `task add Buy milk`
+	Command string            // Command name, e.g. `add`
+	RC      string            // Path to the configuration file
+	Data    string            // Path to the data directory
+	Version string            // Taskwarrior version
+	Extra   map[string]string // Unknown arguments
+}
+
+// Parse hook arguments of form `name:value`.
+func ParseArgs(args []string) *Context {
+	c := &Context{}
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, ":")
+		switch name {
+		case "api":
+			c.API = value
+		case "args":
+			c.Args = value
+		case "command":
+			c.Command = value
+		case "rc":
+			c.RC = value
+		case "data":
+			c.Data = value
+		case "version":
+			c.Version = value
+		default:
+			if c.Extra == nil {
+				c.Extra = map[string]string{}
+			}
+			c.Extra[name] = value
+		}
+	}
+	return c
+}
+
+// Return arguments in the form taskwarrior passes them.
+func (c *Context) Arguments() []string {
+	args := []string{
+		"api:" + c.API,
+		"args:" + c.Args,
+		"command:" + c.Command,
+		"rc:" + c.RC,
+		"data:" + c.Data,
+		"version:" + c.Version,
+	}
+	names := make([]string, 0, len(c.Extra))
+	for name := range c.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, name+":"+c.Extra[name])
+	}
+	return args
+}
+
+// Read configuration file of the running taskwarrior.
+func (c *Context) TaskRC() (*taskwarrior.TaskRC, error) {
+	if c.RC == "" {
+		return nil, fmt.Errorf("hook was run without rc argument")
+	}
+	return taskwarrior.ParseTaskRC(c.RC)
+}
+
+// Return capabilities of the running taskwarrior, capabilities of taskwarrior.DefaultVersion if the version is
+// unknown.
+func (c *Context) Capabilities() taskwarrior.Capabilities {
+	v, err := taskwarrior.ParseVersion(c.Version)
+	if err != nil {
+		v = taskwarrior.DefaultVersion
+	}
+	return taskwarrior.CapabilitiesOf(v)
+}
+
+// Handlers of events. A returned error rejects the command, its message is shown to the user. Feedback may contain
+// several lines.
+type (
+	LaunchFunc func(c *Context) (feedback string, err error)
+	AddFunc    func(c *Context, task taskwarrior.Task) (taskwarrior.Task, string, error)
+	ModifyFunc func(c *Context, original, modified taskwarrior.Task) (taskwarrior.Task, string, error)
+	ExitFunc   func(c *Context, tasks []taskwarrior.Task) (feedback string, err error)
+)
+
+// Hook is a hook program. Events without handler are accepted without changes.
+type Hook struct {
+	OnLaunch LaunchFunc
+	OnAdd    AddFunc
+	OnModify ModifyFunc
+	OnExit   ExitFunc
+}
+
+// Run the hook as the current program: the event is detected from the program name, the input is read from stdin
+// and the process exits with the hook status.
+func (h *Hook) Main() {
+	event, ok := EventOf(os.Args[0])
+	if !ok {
+		fmt.Printf("Can't detect hook event from program name %s\n", filepath.Base(os.Args[0]))
+		os.Exit(1)
+	}
+	os.Exit(h.Run(event, os.Args[1:], os.Stdin, os.Stdout))
+}
+
+// Handle the event with given arguments and input and return exit status.
+func (h *Hook) Run(event Event, args []string, stdin io.Reader, stdout io.Writer) int {
+	c := ParseArgs(args)
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	var input []taskwarrior.Task
+	if event != OnLaunch {
+		var err error
+		input, err = readTasks(stdin)
+		if err != nil {
+			writeFeedback(out, "Hook input is invalid: "+err.Error())
+			return 1
+		}
+	}
+	expected := map[Event]int{OnAdd: 1, OnModify: 2}[event]
+	if expected > 0 && len(input) != expected {
+		writeFeedback(out, fmt.Sprintf("Hook expected %d task(s) on input, got %d", expected, len(input)))
+		return 1
+	}
+
+	var task *taskwarrior.Task
+	var feedback string
+	var err error
+	switch event {
+	case OnLaunch:
+		if h.OnLaunch != nil {
+			feedback, err = h.OnLaunch(c)
+		}
+	case OnAdd:
+		task = &input[0]
+		if h.OnAdd != nil {
+			*task, feedback, err = h.OnAdd(c, input[0])
+		}
+	case OnModify:
+		task = &input[1]
+		if h.OnModify != nil {
+			*task, feedback, err = h.OnModify(c, input[0], input[1])
+		}
+	case OnExit:
+		if h.OnExit != nil {
+			feedback, err = h.OnExit(c, input)
+		}
+	default:
+		writeFeedback(out, fmt.Sprintf("Unknown hook event %s", event))
+		return 1
+	}
+
+	if err != nil {
+		writeFeedback(out, feedback)
+		writeFeedback(out, err.Error())
+		return 1
+	}
+	if task != nil {
+		line, err := taskwarrior.EncodeTask(task, c.Capabilities())
+		if err != nil {
+			writeFeedback(out, "Can't encode task: "+err.Error())
+			return 1
+		}
+		out.Write(append(line, '\n'))
+	}
+	writeFeedback(out, feedback)
+	return 0
+}
+
+// Read task JSON lines.
+func readTasks(r io.Reader) ([]taskwarrior.Task, error) {
+	var tasks []taskwarrior.Task
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var task taskwarrior.Task
+		if err := json.Unmarshal([]byte(line), &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// Write non-empty feedback lines. Lines starting with '{' would be taken for tasks, so they are indented.
+func writeFeedback(w io.Writer, feedback string) {
+	for _, line := range strings.Split(strings.TrimRight(feedback, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			line = " " + line
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,112 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func TestEventOf(t *testing.T) {
+	tests := map[string]Event{
+		"/home/user/.task/hooks/on-add":       OnAdd,
+		"on-add-tags.py":                      OnAdd,
+		"/home/user/.task/hooks/on-modify.sh": OnModify,
+		"on-launch-check":                     OnLaunch,
+		"on-exit":                             OnExit,
+	}
+	for path, expected := range tests {
+		if event, ok := EventOf(path); !ok || event != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, event)
+		}
+	}
+	for _, path := range []string{"add", "/home/user/.task/hooks/README", "hook-on-add"} {
+		if _, ok := EventOf(path); ok {
+			t.Errorf("%s was taken for a hook", path)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	args := []string{"api:2", "args:task add Buy milk due:tomorrow", "command:add", "rc:/home/user/.taskrc",
+		"data:/home/user/.task", "version:2.6.2", "extra:value"}
+	c := ParseArgs(args)
+	expected := &Context{API: "2", Args: "task add Buy milk due:tomorrow", Command: "add", RC: "/home/user/.taskrc",
+		Data: "/home/user/.task", Version: "2.6.2", Extra: map[string]string{"extra": "value"}}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, c)
+	}
+	if got := c.Arguments(); !reflect.DeepEqual(got, args) {
+		t.Errorf("Arguments are not preserved: %q", got)
+	}
+	if !c.Capabilities().DependsArray || (&Context{Version: "2.4.5"}).Capabilities().DependsArray {
+		t.Error("Unexpected capabilities")
+	}
+}
+
+func TestHook_Run(t *testing.T) {
+	hook := &Hook{
+		OnAdd: func(c *Context, task taskwarrior.Task) (taskwarrior.Task, string, error) {
+			if task.Project == "" {
+				return task, "Add a project next time", errors.New("Project is required")
+			}
+			task.Tags = append(task.Tags, "hooked")
+			return task, "Tagged\n{not a task}", nil
+		},
+	}
+	args := NewContext("add", "2.6.2").Arguments()
+
+	var out bytes.Buffer
+	code := hook.Run(OnAdd, args, strings.NewReader(`{"uuid":"a","description":"Buy milk","project":"home"}`+"\n"), &out)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if code != 0 || len(lines) != 3 || !strings.Contains(lines[0], `"tags":["hooked"]`) ||
+		lines[1] != "Tagged" || lines[2] != " {not a task}" {
+		t.Errorf("Unexpected output (%d): %q", code, out.String())
+	}
+
+	// Rejection
+	out.Reset()
+	code = hook.Run(OnAdd, args, strings.NewReader(`{"uuid":"a","description":"Buy milk"}`), &out)
+	if code != 1 || out.String() != "Add a project next time\nProject is required\n" {
+		t.Errorf("Unexpected output of rejection (%d): %q", code, out.String())
+	}
+
+	// Events without handler pass the task through
+	out.Reset()
+	code = hook.Run(OnModify, args, strings.NewReader(`{"uuid":"a","description":"old"}`+"\n"+`{"uuid":"a","description":"new"}`), &out)
+	if code != 0 || !strings.Contains(out.String(), `"description":"new"`) {
+		t.Errorf("Unexpected pass-through output (%d): %q", code, out.String())
+	}
+	out.Reset()
+	if code := hook.Run(OnLaunch, args, nil, &out); code != 0 || out.Len() != 0 {
+		t.Errorf("Unexpected on-launch output (%d): %q", code, out.String())
+	}
+
+	// Invalid input
+	for _, input := range []string{"not json", `{"uuid":"a"}` + "\n" + `{"uuid":"b"}`} {
+		out.Reset()
+		if code := hook.Run(OnAdd, args, strings.NewReader(input), &out); code != 1 || out.Len() == 0 {
+			t.Errorf("Invalid input %q was accepted: %q", input, out.String())
+		}
+	}
+}
+
+func TestHook_RunDependsString(t *testing.T) {
+	var out bytes.Buffer
+	input := `{"uuid":"a","description":"x","depends":"b,c"}`
+	code := (&Hook{}).Run(OnAdd, NewContext("add", "2.4.5").Arguments(), strings.NewReader(input), &out)
+	if code != 0 || !strings.Contains(out.String(), `"depends":"b,c"`) {
+		t.Errorf("Taskwarrior 2.4 expects depends string: %q", out.String())
+	}
+	out.Reset()
+	code = (&Hook{}).Run(OnAdd, NewContext("add", "2.6.2").Arguments(), strings.NewReader(input), &out)
+	if code != 0 || !strings.Contains(out.String(), `"depends":["b","c"]`) {
+		t.Errorf("Taskwarrior 2.6 expects depends array: %q", out.String())
+	}
+}
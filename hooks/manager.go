@@ -0,0 +1,261 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Management of installed hooks.
+//
+// Taskwarrior runs every executable file in the hooks directory whose name starts with an event name, in
+// alphabetical order. Hooks are disabled by clearing the executable bits, like taskwarrior documentation suggests.
+
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Installed is a hook file in the hooks directory.
+type Installed struct {
+	Name    string // File name, e.g. `on-add-tags`
+	Path    string // Full path
+	Event   Event
+	Enabled bool // The file is executable
+}
+
+// Manager manages hooks in a hooks directory.
+type Manager struct {
+	Dir     string              // Hooks directory
+	Config  *taskwarrior.TaskRC // Configuration passed to validated hooks, may be nil
+	Version taskwarrior.Version // Taskwarrior version passed to validated hooks, DefaultVersion if zero
+}
+
+// Create manager of the hooks directory of given configuration, see TaskRC.HooksDir.
+func NewManager(config *taskwarrior.TaskRC) *Manager {
+	return &Manager{Dir: config.HooksDir(), Config: config, Version: taskwarrior.DefaultVersion}
+}
+
+// Return hooks in the order taskwarrior runs them. Files that are not hooks are skipped. Missing directory has no
+// hooks.
+func (m *Manager) List() ([]Installed, error) {
+	entries, err := os.ReadDir(m.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []Installed
+	for _, entry := range entries {
+		event, ok := EventOf(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(m.Dir, entry.Name()))
+		if err != nil {
+			// Broken symlink
+			continue
+		}
+		hooks = append(hooks, Installed{
+			Name:    entry.Name(),
+			Path:    filepath.Join(m.Dir, entry.Name()),
+			Event:   event,
+			Enabled: info.Mode()&0111 != 0,
+		})
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].Name < hooks[j].Name })
+	return hooks, nil
+}
+
+// Return installed hook with given file name.
+func (m *Manager) Get(name string) (*Installed, error) {
+	hooks, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		if hooks[i].Name == name {
+			return &hooks[i], nil
+		}
+	}
+	return nil, fmt.Errorf("hook %s is not installed in %s", name, m.Dir)
+}
+
+// Make the hook executable for everybody who can read it.
+func (m *Manager) Enable(name string) error {
+	hook, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(hook.Path)
+	if err != nil {
+		return err
+	}
+	mode := info.Mode().Perm()
+	return os.Chmod(hook.Path, mode|(mode&0444)>>2)
+}
+
+// Clear executable bits of the hook, so taskwarrior skips it.
+func (m *Manager) Disable(name string) error {
+	hook, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(hook.Path)
+	if err != nil {
+		return err
+	}
+	return os.Chmod(hook.Path, info.Mode().Perm()&^0111)
+}
+
+// Copy hook executable to the hooks directory as `<event>-<name>` and enable it. Existing hook with the same name
+// is replaced. The directory is created if necessary.
+func (m *Manager) Install(src string, event Event, name string) (*Installed, error) {
+	if name == "" || strings.ContainsRune(name, filepath.Separator) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid hook name '%s'", name)
+	}
+	if !slices.Contains(Events, event) {
+		return nil, fmt.Errorf("unknown hook event '%s'", event)
+	}
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(m.Dir, ".install-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Chmod(0755); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	fileName := string(event) + "-" + name
+	if err := os.Rename(tmp.Name(), filepath.Join(m.Dir, fileName)); err != nil {
+		return nil, err
+	}
+	return m.Get(fileName)
+}
+
+// Remove the hook file.
+func (m *Manager) Remove(name string) error {
+	hook, err := m.Get(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(hook.Path)
+}
+
+// Run on-launch hook without arguments of a real command, like taskwarrior does before any command. Hooks of other
+// events are not run, since they may have side effects like posting the task somewhere; use ValidateSample for them.
+// Returns error if the hook can't be run, rejects the launch or breaks the protocol.
+func (m *Manager) Validate(ctx context.Context, name string) (*Result, error) {
+	hook, err := m.enabled(name)
+	if err != nil {
+		return nil, err
+	}
+	if hook.Event != OnLaunch {
+		return nil, fmt.Errorf("%s hook %s is not run in dry run, use ValidateSample to run it", hook.Event, name)
+	}
+	return m.invoke(ctx, hook)
+}
+
+// Run the hook with sample input of its event: on-launch hooks get no input, on-add and on-modify hooks get a sample
+// task and on-exit hooks get no changed tasks. Nothing is stored, but the hook does whatever it does with the sample
+// task, e.g. sends a notification about it. Returns error if the hook can't be run, rejects the command or breaks the
+// protocol.
+func (m *Manager) ValidateSample(ctx context.Context, name string) (*Result, error) {
+	hook, err := m.enabled(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.invoke(ctx, hook)
+}
+
+// Return installed hook, or error if it is disabled.
+func (m *Manager) enabled(name string) (*Installed, error) {
+	hook, err := m.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !hook.Enabled {
+		return nil, fmt.Errorf("hook %s is disabled", name)
+	}
+	return hook, nil
+}
+
+// Run the hook with sample input of its event.
+func (m *Manager) invoke(ctx context.Context, hook *Installed) (*Result, error) {
+	c := m.context(hook.Event)
+	now := time.Now()
+	var input []taskwarrior.Task
+	sample := taskwarrior.Task{
+		Uuid:        "00000000-0000-4000-8000-000000000000",
+		Description: "Hook validation",
+		Status:      "pending",
+		Entry:       taskwarrior.NewTaskTime(now),
+	}
+	switch hook.Event {
+	case OnAdd:
+		input = []taskwarrior.Task{sample}
+	case OnModify:
+		modified := sample
+		modified.Description = "Hook validation (modified)"
+		modified.Modified = taskwarrior.NewTaskTime(now)
+		input = []taskwarrior.Task{sample, modified}
+	}
+
+	result, err := InvokeBinary(ctx, hook.Path, c, input...)
+	if err != nil {
+		return result, err
+	}
+	if !result.Accepted() {
+		return result, fmt.Errorf("hook %s exited with status %d: %s", hook.Name, result.ExitCode, strings.Join(result.Feedback, "; "))
+	}
+	return result, nil
+}
+
+// Return arguments passed to validated hooks.
+func (m *Manager) context(event Event) *Context {
+	command := map[Event]string{OnAdd: "add", OnModify: "modify"}[event]
+	version := m.Version
+	if version == (taskwarrior.Version{}) {
+		version = taskwarrior.DefaultVersion
+	}
+	c := NewContext(command, version.String())
+	if command == "" {
+		c.Args = "task"
+	}
+	if m.Config != nil {
+		if m.Config.ConfigPath != "" {
+			c.RC = m.Config.ConfigPath
+		}
+		if m.Config.DataLocation != "" {
+			c.Data = taskwarrior.PathExpandTilda(m.Config.DataLocation)
+		}
+	}
+	return c
+}
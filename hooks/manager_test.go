@@ -0,0 +1,133 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package hooks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	rc := &taskwarrior.TaskRC{}
+	rc.MapTaskRC("data.location=" + dir)
+	m := NewManager(rc)
+	if m.Dir != filepath.Join(dir, "hooks") {
+		t.Fatalf("Unexpected hooks directory %s", m.Dir)
+	}
+
+	// Missing directory has no hooks
+	if hooks, err := m.List(); err != nil || len(hooks) != 0 {
+		t.Errorf("Unexpected hooks: %v (%v)", hooks, err)
+	}
+
+	src := writeHook(t, "hook", `read task; echo "$task"`)
+	hook, err := m.Install(src, OnAdd, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.Name != "on-add-echo" || hook.Event != OnAdd || !hook.Enabled {
+		t.Errorf("Unexpected installed hook: %+v", hook)
+	}
+	if _, err := m.Install(src, OnModify, "echo"); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(m.Dir, "README"), []byte("not a hook"), 0644)
+	os.WriteFile(filepath.Join(m.Dir, "on-exit-disabled.sh"), []byte("#!/bin/sh\n"), 0644)
+
+	hooks, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, hook := range hooks {
+		names = append(names, hook.Name)
+	}
+	if strings.Join(names, " ") != "on-add-echo on-exit-disabled.sh on-modify-echo" || hooks[1].Enabled {
+		t.Errorf("Unexpected hooks: %+v", hooks)
+	}
+
+	// Enable and disable
+	if err := m.Enable("on-exit-disabled.sh"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(hooks[1].Path); info.Mode().Perm() != 0755 {
+		t.Errorf("Unexpected mode of enabled hook: %v", info.Mode())
+	}
+	if err := m.Disable("on-add-echo"); err != nil {
+		t.Fatal(err)
+	}
+	if hook, _ := m.Get("on-add-echo"); hook.Enabled {
+		t.Error("Hook was not disabled")
+	}
+	if err := m.Enable("on-add-missing"); err == nil {
+		t.Error("Missing hook was enabled")
+	}
+
+	// Invalid installations
+	if _, err := m.Install(src, "on-delete", "x"); err == nil {
+		t.Error("Unknown event was accepted")
+	}
+	if _, err := m.Install(src, OnAdd, "../x"); err == nil {
+		t.Error("Name with path separator was accepted")
+	}
+
+	if err := m.Remove("on-modify-echo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.Dir, "on-modify-echo")); !os.IsNotExist(err) {
+		t.Error("Hook was not removed")
+	}
+}
+
+func TestManager_Validate(t *testing.T) {
+	m := &Manager{Dir: t.TempDir(), Version: taskwarrior.Version{Major: 2, Minor: 6, Patch: 2}}
+	ctx := context.Background()
+	install := func(event Event, name, script string) {
+		if _, err := m.Install(writeHook(t, "hook", script), event, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	install(OnLaunch, "ok", `echo "Launched with $6"`)
+	install(OnLaunch, "fail", `echo "Database is locked"; exit 1`)
+	install(OnAdd, "echo", `read task; echo "$task"`)
+	install(OnAdd, "silent", `read task`)
+	install(OnModify, "echo", `read old; read new; echo "$new"`)
+	install(OnExit, "ok", `cat > /dev/null`)
+
+	result, err := m.Validate(ctx, "on-launch-ok")
+	if err != nil || len(result.Feedback) != 1 || result.Feedback[0] != "Launched with version:2.6.2" {
+		t.Errorf("Unexpected result: %+v (%v)", result, err)
+	}
+	// Hooks of other events are run only on request
+	if _, err := m.Validate(ctx, "on-add-echo"); err == nil {
+		t.Error("on-add hook was run in dry run")
+	}
+	if result, err := m.ValidateSample(ctx, "on-add-echo"); err != nil || result.Task.Description != "Hook validation" {
+		t.Errorf("Unexpected on-add result: %+v (%v)", result, err)
+	}
+	if result, err := m.ValidateSample(ctx, "on-modify-echo"); err != nil || !strings.Contains(result.Task.Description, "modified") {
+		t.Errorf("Unexpected on-modify result: %+v (%v)", result, err)
+	}
+	if _, err := m.ValidateSample(ctx, "on-exit-ok"); err != nil {
+		t.Errorf("Unexpected on-exit error: %v", err)
+	}
+
+	if _, err := m.Validate(ctx, "on-launch-fail"); err == nil || !strings.Contains(err.Error(), "Database is locked") {
+		t.Errorf("Expected rejection, got %v", err)
+	}
+	if _, err := m.ValidateSample(ctx, "on-add-silent"); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Expected protocol error, got %v", err)
+	}
+	m.Disable("on-launch-ok")
+	if _, err := m.Validate(ctx, "on-launch-ok"); err == nil {
+		t.Error("Disabled hook was validated")
+	}
+}
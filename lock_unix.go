@@ -0,0 +1,56 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Locking of data files with flock(2), like taskwarrior 2.x does with `locking=on`.
+
+//go:build unix
+
+package taskwarrior
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Period of lock attempts while the file is locked by another process.
+const lockRetryInterval = 10 * time.Millisecond
+
+// Take exclusive lock of existing file, waiting until given context is done. Returns function that releases the lock.
+func lockFile(ctx context.Context, path string) (func(), error) {
+	for {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+			if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				f.Close()
+				return nil, ctx.Err()
+			case <-time.After(lockRetryInterval):
+			}
+		}
+		if err != nil {
+			f.Close()
+			return nil, &os.PathError{Op: "flock", Path: path, Err: err}
+		}
+
+		// The file could be replaced while we were waiting, then the lock is taken on the old one.
+		locked, errLocked := f.Stat()
+		current, errCurrent := os.Stat(path)
+		if errLocked == nil && errCurrent == nil && os.SameFile(locked, current) {
+			return func() { f.Close() }, nil
+		}
+		f.Close()
+		if errCurrent != nil {
+			return nil, errCurrent
+		}
+	}
+}
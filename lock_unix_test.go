@@ -0,0 +1,74 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+//go:build unix
+
+package taskwarrior
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDataFileBackend_Locking(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, PendingDataFile), nil, 0600)
+	os.WriteFile(filepath.Join(dir, CompletedDataFile), nil, 0600)
+	backend := NewDataFileBackend(&TaskRC{DataLocation: dir})
+
+	// Another process holds the lock
+	unlock, err := lockFile(context.Background(), filepath.Join(dir, CompletedDataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := backend.Add(ctx, &Task{Description: "Buy milk"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to wait for the lock, got %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := backend.Add(context.Background(), &Task{Description: "Buy milk"})
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Add fails after the lock is released: %v", err)
+	}
+
+	// Lock of a replaced file is taken again
+	unlock, err = lockFile(context.Background(), filepath.Join(dir, PendingDataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_, err := backend.Add(context.Background(), &Task{Description: "Call mom"})
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	WriteDataFile(filepath.Join(dir, PendingDataFile), nil)
+	unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Add fails after the file is replaced: %v", err)
+	}
+	tasks, _ := ReadDataFile(filepath.Join(dir, PendingDataFile))
+	if len(tasks) != 1 || tasks[0].Description != "Call mom" {
+		t.Errorf("Unexpected pending tasks: %+v", tasks)
+	}
+
+	// Locked files are rewritten in place, so a process waiting for the lock writes into the same file
+	before, _ := os.Stat(filepath.Join(dir, PendingDataFile))
+	if _, err := backend.Add(context.Background(), &Task{Description: "Write report"}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(filepath.Join(dir, PendingDataFile))
+	if !os.SameFile(before, after) {
+		t.Error("Pending data file was replaced")
+	}
+}
@@ -0,0 +1,488 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Evaluation of filter expressions in memory.
+//
+// Matcher follows taskwarrior semantics, so local filtering gives the same results as `task <filter> export`:
+//
+//   - `name:value` is a partial match: strings match by prefix (project:work matches work.backend) and dates match
+//     the whole day (due:today).
+//   - String comparisons respect search.case.sensitive, patterns respect regex option.
+//   - Virtual tags are computed from task attributes, the current time and, for BLOCKED and BLOCKING, other tasks.
+//   - Bare terms are task IDs, UUIDs or description patterns.
+
+package taskwarrior
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Matcher evaluates filter expressions against tasks.
+type Matcher struct {
+	Config *TaskRC   // Options search.case.sensitive, regex, due and UDA types; defaults if nil
+	Now    time.Time // Time used for dates and virtual tags, time.Now if zero
+	Tasks  []Task    // All tasks of the database, used for dependency virtual tags
+
+	pending  map[string]bool // UUIDs of pending and waiting tasks
+	blocking map[string]bool // UUIDs of tasks pending tasks depend on
+	patterns map[string]*regexp.Regexp
+}
+
+// Create matcher for given tasks and configuration.
+func NewMatcher(tasks []Task, config *TaskRC) *Matcher {
+	return &Matcher{Config: config, Tasks: tasks}
+}
+
+// Return tasks of the matcher that match the expression, in their original order.
+func (m *Matcher) Filter(expr Expr) ([]Task, error) {
+	var matched []Task
+	for i := range m.Tasks {
+		ok, err := m.Match(&m.Tasks[i], expr)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, m.Tasks[i])
+		}
+	}
+	return matched, nil
+}
+
+// Check whether the task matches the expression. Nil expression matches every task.
+func (m *Matcher) Match(task *Task, expr Expr) (bool, error) {
+	switch e := expr.(type) {
+	case nil:
+		return true, nil
+	case AndExpr:
+		for _, sub := range e {
+			if ok, err := m.Match(task, sub); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case OrExpr:
+		for _, sub := range e {
+			if ok, err := m.Match(task, sub); ok || err != nil {
+				return ok, err
+			}
+		}
+		return len(e) == 0, nil
+	case NotExpr:
+		ok, err := m.Match(task, e.Expr)
+		return !ok && err == nil, err
+	case TagExpr:
+		return m.hasTag(task, e.Tag) != e.Exclude, nil
+	case AttrExpr:
+		return m.matchAttr(task, e)
+	case PatternExpr:
+		return m.matchPattern(task.Description, e.Pattern)
+	case rawFilterTerm:
+		return m.matchTerm(task, string(e))
+	}
+	return false, fmt.Errorf("unsupported filter expression: %v", expr)
+}
+
+// Return tasks of the instance matching the filter, evaluated without calling taskwarrior.
+func (tw *TaskWarrior) FilterTasks(filter Filter) ([]Task, error) {
+	return NewMatcher(tw.Tasks, tw.Config).Filter(filter.Expression())
+}
+
+func (m *Matcher) now() time.Time {
+	if m.Now.IsZero() {
+		return time.Now()
+	}
+	return m.Now
+}
+
+func (m *Matcher) caseSensitive() bool {
+	return parseBool(m.Config.valueOrDefault("search.case.sensitive"))
+}
+
+// Fold string for comparison according to search.case.sensitive option.
+func (m *Matcher) fold(s string) string {
+	if m.caseSensitive() {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// Virtual tags, see https://taskwarrior.org/docs/tags/#virtual-tags.
+var virtualTags = map[string]func(m *Matcher, task *Task) bool{
+	VirtualActive:    func(m *Matcher, t *Task) bool { return isPendingStatus(t.Status) && !t.Start.IsZero() },
+	VirtualAnnotated: func(m *Matcher, t *Task) bool { return len(t.Annotations) > 0 },
+	VirtualBlocked:   (*Matcher).isBlocked,
+	VirtualBlocking:  (*Matcher).isBlocking,
+	VirtualChild:     func(m *Matcher, t *Task) bool { return t.Parent != "" },
+	VirtualCompleted: func(m *Matcher, t *Task) bool { return t.Status == "completed" },
+	VirtualDeleted:   func(m *Matcher, t *Task) bool { return t.Status == "deleted" },
+	VirtualDue: func(m *Matcher, t *Task) bool {
+		days, err := strconv.Atoi(m.Config.valueOrDefault("due"))
+		if err != nil {
+			days = 7
+		}
+		now := m.now()
+		return m.isOpen(t) && !t.Due.IsZero() && !t.Due.Before(startOfDay(now)) &&
+			!t.Due.After(now.AddDate(0, 0, days))
+	},
+	VirtualOverdue:   func(m *Matcher, t *Task) bool { return t.IsOverdue(m.now()) },
+	VirtualParent:    func(m *Matcher, t *Task) bool { return t.Status == "recurring" },
+	VirtualPending:   func(m *Matcher, t *Task) bool { return t.Status == "pending" },
+	VirtualPriority:  func(m *Matcher, t *Task) bool { return t.Priority != "" },
+	VirtualProject:   func(m *Matcher, t *Task) bool { return t.Project != "" },
+	VirtualScheduled: func(m *Matcher, t *Task) bool { return !t.Scheduled.IsZero() },
+	VirtualTagged:    func(m *Matcher, t *Task) bool { return len(t.Tags) > 0 },
+	VirtualUnblocked: func(m *Matcher, t *Task) bool { return !m.isBlocked(t) },
+	VirtualUntil:     func(m *Matcher, t *Task) bool { return !t.Until.IsZero() },
+	VirtualReady: func(m *Matcher, t *Task) bool {
+		return t.Status == "pending" && !m.isBlocked(t) && (t.Scheduled.IsZero() || !t.Scheduled.After(m.now()))
+	},
+	VirtualWaiting: func(m *Matcher, t *Task) bool {
+		return t.Status == "waiting" || (t.Status == "pending" && t.Wait.After(m.now()))
+	},
+	VirtualToday:     func(m *Matcher, t *Task) bool { return m.isOpen(t) && m.dueOnDay(t, 0) },
+	VirtualTomorrow:  func(m *Matcher, t *Task) bool { return m.isOpen(t) && m.dueOnDay(t, 1) },
+	VirtualYesterday: func(m *Matcher, t *Task) bool { return m.isOpen(t) && m.dueOnDay(t, -1) },
+}
+
+func (m *Matcher) hasTag(task *Task, tag string) bool {
+	if virtual, ok := virtualTags[tag]; ok {
+		return virtual(m, task)
+	}
+	for _, t := range task.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) isOpen(task *Task) bool {
+	return task.Status != "completed" && task.Status != "deleted"
+}
+
+func (m *Matcher) dueOnDay(task *Task, offset int) bool {
+	if task.Due.IsZero() {
+		return false
+	}
+	day := startOfDay(m.now()).AddDate(0, 0, offset)
+	due := task.Due.In(m.now().Location())
+	return !due.Before(day) && due.Before(day.AddDate(0, 0, 1))
+}
+
+// Build indexes of dependencies between tasks.
+func (m *Matcher) indexDependencies() {
+	if m.pending != nil {
+		return
+	}
+	m.pending = map[string]bool{}
+	m.blocking = map[string]bool{}
+	for i := range m.Tasks {
+		if isPendingStatus(m.Tasks[i].Status) {
+			m.pending[m.Tasks[i].Uuid] = true
+			for _, dep := range m.Tasks[i].Depends {
+				m.blocking[dep] = true
+			}
+		}
+	}
+}
+
+func (m *Matcher) isBlocked(task *Task) bool {
+	m.indexDependencies()
+	for _, dep := range task.Depends {
+		if m.pending[dep] {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) isBlocking(task *Task) bool {
+	m.indexDependencies()
+	return isPendingStatus(task.Status) && m.blocking[task.Uuid]
+}
+
+func (m *Matcher) matchPattern(s, pattern string) (bool, error) {
+	if !parseBool(m.Config.valueOrDefault("regex")) {
+		return strings.Contains(m.fold(s), m.fold(pattern)), nil
+	}
+	if !m.caseSensitive() {
+		pattern = "(?i)" + pattern
+	}
+	re, ok := m.patterns[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, fmt.Errorf("invalid pattern: %w", err)
+		}
+		if m.patterns == nil {
+			m.patterns = map[string]*regexp.Regexp{}
+		}
+		m.patterns[pattern] = re
+	}
+	return re.MatchString(s), nil
+}
+
+var (
+	idListRegexp     = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+	uuidPrefixRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}(-[0-9a-fA-F-]*)?$`)
+)
+
+// Match bare term: list of IDs, UUID or description pattern.
+func (m *Matcher) matchTerm(task *Task, term string) (bool, error) {
+	switch {
+	case idListRegexp.MatchString(term):
+		for _, r := range strings.Split(term, ",") {
+			from, to, isRange := strings.Cut(r, "-")
+			if !isRange {
+				to = from
+			}
+			lo, _ := strconv.Atoi(from)
+			hi, _ := strconv.Atoi(to)
+			if task.Id != 0 && int(task.Id) >= lo && int(task.Id) <= hi {
+				return true, nil
+			}
+		}
+		return false, nil
+	case uuidPrefixRegexp.MatchString(term):
+		return strings.HasPrefix(strings.ToLower(task.Uuid), strings.ToLower(term)), nil
+	}
+	return m.matchPattern(task.Description, term)
+}
+
+// Kind of attribute value.
+type attrKind int
+
+const (
+	attrString attrKind = iota
+	attrNumber
+	attrDate
+	attrList
+)
+
+// Typed value of task attribute.
+type attrValue struct {
+	kind attrKind
+	set  bool
+	str  string
+	num  float64
+	date time.Time
+	list []string
+}
+
+// Return value of built-in attribute or UDA.
+func (m *Matcher) attribute(task *Task, name string) attrValue {
+	str := func(s string) attrValue { return attrValue{kind: attrString, set: s != "", str: s} }
+	date := func(t TaskTime) attrValue { return attrValue{kind: attrDate, set: !t.IsZero(), date: t.Time} }
+	list := func(l []string) attrValue { return attrValue{kind: attrList, set: len(l) > 0, list: l} }
+
+	switch name {
+	case "id":
+		return attrValue{kind: attrNumber, set: task.Id != 0, num: float64(task.Id)}
+	case "description":
+		return str(task.Description)
+	case "project":
+		return str(task.Project)
+	case "status":
+		return str(task.Status)
+	case "uuid":
+		return str(task.Uuid)
+	case "priority":
+		return str(task.Priority)
+	case "recur":
+		return str(task.Recur)
+	case "mask":
+		return str(task.Mask)
+	case "parent":
+		return str(task.Parent)
+	case "urgency":
+		return attrValue{kind: attrNumber, set: true, num: float64(task.Urgency)}
+	case "imask":
+		return attrValue{kind: attrNumber, set: task.Imask != 0 || task.Parent != "", num: float64(task.Imask)}
+	case "due":
+		return date(task.Due)
+	case "start":
+		return date(task.Start)
+	case "end":
+		return date(task.End)
+	case "entry":
+		return date(task.Entry)
+	case "until":
+		return date(task.Until)
+	case "wait":
+		return date(task.Wait)
+	case "scheduled":
+		return date(task.Scheduled)
+	case "modified":
+		return date(task.Modified)
+	case "tags":
+		return list(task.Tags)
+	case "depends":
+		return list(task.Depends)
+	case "annotations":
+		var descriptions []string
+		for _, a := range task.Annotations {
+			descriptions = append(descriptions, a.Description)
+		}
+		return list(descriptions)
+	}
+
+	value, ok := task.UDA[name]
+	if !ok || value == nil {
+		kind := attrString
+		if m.Config != nil {
+			switch m.Config.UDA[name] {
+			case "numeric":
+				kind = attrNumber
+			case "date":
+				kind = attrDate
+			}
+		}
+		return attrValue{kind: kind}
+	}
+	switch v := value.(type) {
+	case float64:
+		return attrValue{kind: attrNumber, set: true, num: v}
+	case TaskTime:
+		return date(v)
+	case time.Time:
+		return date(NewTaskTime(v))
+	}
+	s := fmt.Sprint(value)
+	if m.Config != nil {
+		switch m.Config.UDA[name] {
+		case "numeric":
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return attrValue{kind: attrNumber, set: true, num: f}
+			}
+		case "date":
+			if t, err := ParseTaskTime(s); err == nil {
+				return date(t)
+			}
+		}
+	}
+	return str(s)
+}
+
+func (m *Matcher) matchAttr(task *Task, e AttrExpr) (bool, error) {
+	v := m.attribute(task, e.Name)
+	switch e.Modifier {
+	case ModNone:
+		return !v.set, nil
+	case ModAny:
+		return v.set, nil
+	}
+	if e.Value == "" {
+		switch e.Modifier {
+		case ModEquals, ModIs:
+			return !v.set, nil
+		case ModNot:
+			return v.set, nil
+		}
+	}
+
+	switch v.kind {
+	case attrList:
+		switch e.Modifier {
+		case ModEquals, ModIs, ModHas:
+			return containsString(v.list, e.Value), nil
+		case ModNot, ModHasnt:
+			return !containsString(v.list, e.Value), nil
+		}
+		return m.compareStrings(strings.Join(v.list, ","), e.Modifier, e.Value)
+	case attrNumber:
+		num, err := strconv.ParseFloat(e.Value, 64)
+		if err != nil {
+			return m.compareStrings(v.str, e.Modifier, e.Value)
+		}
+		switch e.Modifier {
+		case ModEquals, ModIs:
+			return v.set && v.num == num, nil
+		case ModNot:
+			return !v.set || v.num != num, nil
+		case ModBefore, ModUnder:
+			return v.set && v.num < num, nil
+		case ModAfter, ModOver:
+			return v.set && v.num > num, nil
+		}
+		return m.compareStrings(strconv.FormatFloat(v.num, 'f', -1, 64), e.Modifier, e.Value)
+	case attrDate:
+		date, err := m.parseDate(e.Value)
+		if err != nil {
+			return false, err
+		}
+		switch e.Modifier {
+		case ModEquals:
+			return v.set && sameDay(v.date, date), nil
+		case ModIs:
+			return v.set && v.date.Equal(date), nil
+		case ModNot:
+			return !v.set || !sameDay(v.date, date), nil
+		case ModBefore, ModUnder:
+			return v.set && v.date.Before(date), nil
+		case ModAfter, ModOver:
+			return v.set && v.date.After(date), nil
+		}
+		return false, fmt.Errorf("modifier '%s' can't be used with date attribute '%s'", e.Modifier, e.Name)
+	}
+	return m.compareStrings(v.str, e.Modifier, e.Value)
+}
+
+func (m *Matcher) compareStrings(s string, mod Modifier, value string) (bool, error) {
+	s, value = m.fold(s), m.fold(value)
+	switch mod {
+	case ModEquals, ModStartsWith:
+		return strings.HasPrefix(s, value), nil
+	case ModIs:
+		return s == value, nil
+	case ModNot:
+		return !strings.HasPrefix(s, value), nil
+	case ModBefore, ModUnder:
+		return s < value, nil
+	case ModAfter, ModOver:
+		return s > value, nil
+	case ModHas:
+		return strings.Contains(s, value), nil
+	case ModHasnt:
+		return !strings.Contains(s, value), nil
+	case ModEndsWith:
+		return strings.HasSuffix(s, value), nil
+	case ModWord, ModNoWord:
+		re := regexp.MustCompile(`(^|\W)` + regexp.QuoteMeta(value) + `(\W|$)`)
+		return re.MatchString(s) == (mod == ModWord), nil
+	}
+	return false, fmt.Errorf("unsupported modifier: %s", mod)
+}
+
+// Parse date value of filter, see ParseDate.
+func (m *Matcher) parseDate(value string) (time.Time, error) {
+	return ParseDate(value, m.now(), m.Config)
+}
+
+// Return midnight of the day in location of the time.
+func startOfDay(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, t.Location())
+}
+
+// Check whether times are on the same day in location of the second one.
+func sameDay(a, b time.Time) bool {
+	a = a.In(b.Location())
+	return startOfDay(a).Equal(startOfDay(b))
+}
+
+func isPendingStatus(status string) bool {
+	return status == "pending" || status == "waiting"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Tasks used for filter conformance tests. Dates are relative to now and far from day boundaries.
+func conformanceTasks(now time.Time) []Task {
+	entry := NewTaskTime(now.Add(-30 * 24 * time.Hour).Truncate(time.Second))
+	date := func(d time.Duration) TaskTime { return NewTaskTime(now.Add(d).Truncate(time.Second)) }
+	return []Task{
+		{Uuid: "00000000-0000-0000-0000-000000000001", Description: "Write quarterly report", Status: "pending",
+			Entry: entry, Project: "work", Priority: "H", Tags: []string{"next", "office"}, Due: date(-72 * time.Hour)},
+		{Uuid: "00000000-0000-0000-0000-000000000002", Description: "Fix backend bug", Status: "pending",
+			Entry: entry, Project: "work.backend", Due: date(30 * 24 * time.Hour),
+			Depends: []string{"00000000-0000-0000-0000-000000000001"}},
+		{Uuid: "00000000-0000-0000-0000-000000000003", Description: "Buy groceries", Status: "pending",
+			Entry: entry, Project: "Home", Tags: []string{"errand"}, Start: date(-time.Hour),
+			Annotations: []Annotation{{Entry: entry, Description: "milk and bread"}}},
+		{Uuid: "00000000-0000-0000-0000-000000000004", Description: "Read book about Go", Status: "completed",
+			Entry: entry, End: date(-48 * time.Hour), Tags: []string{"next"}},
+		{Uuid: "00000000-0000-0000-0000-000000000005", Description: "Old idea", Status: "deleted",
+			Entry: entry, End: date(-48 * time.Hour), Project: "workshop"},
+		{Uuid: "00000000-0000-0000-0000-000000000006", Description: "Plan holiday", Status: "pending",
+			Entry: entry, Scheduled: date(10 * 24 * time.Hour), Priority: "L"},
+	}
+}
+
+// Filters of conformance tests with expected results.
+var conformanceCases = []struct {
+	expr     Expr
+	config   map[string]string
+	expected []int
+}{
+	{Project("work"), nil, []int{1, 2, 5}},
+	{AttrMod("project", ModIs, "work"), nil, []int{1}},
+	{And(Project("work"), Not(AttrMod("project", ModIs, "workshop"))), nil, []int{1, 2}},
+	{Project("home"), nil, nil},
+	{Project("home"), map[string]string{"search.case.sensitive": "no"}, []int{3}},
+	{Tag("next"), nil, []int{1, 4}},
+	{WithoutTag("next"), nil, []int{2, 3, 5, 6}},
+	{And(Status("pending"), Or(Tag("errand"), AttrMod("priority", ModIs, "H"))), nil, []int{1, 3}},
+	{Tag(VirtualOverdue), nil, []int{1}},
+	{Tag(VirtualBlocked), nil, []int{2}},
+	{Tag(VirtualBlocking), nil, []int{1}},
+	{Tag(VirtualActive), nil, []int{3}},
+	{Tag(VirtualAnnotated), nil, []int{3}},
+	{Tag(VirtualScheduled), nil, []int{6}},
+	{And(Tag(VirtualReady), Tag(VirtualPending)), nil, []int{1, 3}},
+	{Tag(VirtualCompleted), nil, []int{4}},
+	{AttrMod("description", ModHas, "bug"), nil, []int{2}},
+	{AttrMod("description", ModStartsWith, "Buy"), nil, []int{3}},
+	{AttrMod("description", ModEndsWith, "Go"), nil, []int{4}},
+	{AttrMod("description", ModWord, "book"), nil, []int{4}},
+	{AttrMod("description", ModNoWord, "book"), nil, []int{1, 2, 3, 5, 6}},
+	{DescriptionMatches("^(Fix|Buy) "), nil, []int{2, 3}},
+	{DescriptionMatches("report"), nil, []int{1}},
+	{AttrMod("due", ModAny, nil), nil, []int{1, 2}},
+	{AttrMod("due", ModNone, nil), nil, []int{3, 4, 5, 6}},
+	{AttrMod("priority", ModAny, nil), nil, []int{1, 6}},
+	{AttrMod("tags", ModHas, "office"), nil, []int{1}},
+}
+
+// Check that local evaluation and `task` export with the same filters give expected results.
+func testFilterConformance(t *testing.T, tw *TaskWarrior, uuids []string) {
+	t.Helper()
+	if err := tw.FetchAllTasks(); err != nil {
+		t.Fatalf("FetchAllTasks fails with following error: %v", err)
+	}
+	for _, c := range conformanceCases {
+		var expected []string
+		for _, i := range c.expected {
+			expected = append(expected, uuids[i-1])
+		}
+
+		for key, val := range c.config {
+			tw.Config.Override(key, val)
+		}
+		filter := Filter{Expr: c.expr}
+		local, err := tw.FilterTasks(filter)
+		if err != nil {
+			t.Errorf("%v: local evaluation fails: %v", c.expr, err)
+		}
+		remote, err := tw.QueryTasks(filter)
+		if err != nil {
+			t.Errorf("%v: query fails: %v", c.expr, err)
+		}
+		for key := range c.config {
+			tw.Config.Override(key, DefaultConfig[key])
+		}
+
+		if got := sortedUUIDs(local); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("%v %v: local evaluation returned %v, expected %v", c.expr, c.config, got, expected)
+		}
+		if got := sortedUUIDs(remote); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("%v %v: query returned %v, expected %v", c.expr, c.config, got, expected)
+		}
+	}
+}
+
+func sortedUUIDs(tasks []Task) []string {
+	var uuids []string
+	for _, task := range tasks {
+		uuids = append(uuids, task.Uuid)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+func TestFilterConformance_Fake(t *testing.T) {
+	tasks := conformanceTasks(time.Now())
+	var uuids []string
+	for _, task := range tasks {
+		uuids = append(uuids, task.Uuid)
+	}
+	testFilterConformance(t, newFakeTaskWarrior(t, tasks...), uuids)
+}
+
+// Same checks with real taskwarrior, if it is installed.
+func TestFilterConformance_CLI(t *testing.T) {
+	if _, err := exec.LookPath("task"); err != nil {
+		t.Skip("task binary is not installed")
+	}
+
+	dir := t.TempDir()
+	rc := filepath.Join(dir, "taskrc")
+	content := fmt.Sprintf("data.location=%s\nconfirmation=off\nhooks=off\nnews.version=99.99.99\n", dir)
+	if err := os.WriteFile(rc, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tw, err := NewTaskWarrior(rc)
+	if err != nil {
+		t.Fatalf("NewTaskWarrior fails with following error: %v", err)
+	}
+
+	tasks := conformanceTasks(time.Now())
+	var uuids []string
+	for i := range tasks {
+		tw.AddTask(&tasks[i])
+		uuids = append(uuids, tasks[i].Uuid)
+	}
+	if _, err := tw.Commit(); err != nil {
+		t.Fatalf("Commit fails with following error: %v", err)
+	}
+	testFilterConformance(t, tw, uuids)
+}
+
+func TestMatcher_DateVirtualTags(t *testing.T) {
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	at := func(day, hour int) TaskTime { return NewTaskTime(time.Date(2026, 2, day, hour, 0, 0, 0, time.UTC)) }
+	tasks := []Task{
+		{Uuid: "1", Status: "pending", Due: at(9, 18)},
+		{Uuid: "2", Status: "pending", Due: at(10, 8)},
+		{Uuid: "3", Status: "pending", Due: at(10, 20)},
+		{Uuid: "4", Status: "pending", Due: at(11, 1)},
+		{Uuid: "5", Status: "pending", Due: at(20, 1)},
+		{Uuid: "6", Status: "completed", Due: at(10, 8)},
+		{Uuid: "7", Status: "pending", Wait: at(12, 0)},
+		{Uuid: "8", Status: "waiting", Wait: at(12, 0), Until: at(28, 0)},
+	}
+	m := &Matcher{Tasks: tasks, Now: now}
+
+	tests := []struct {
+		expr     Expr
+		expected string
+	}{
+		{Tag(VirtualYesterday), "[1]"},
+		{Tag(VirtualToday), "[2 3]"},
+		{Tag(VirtualTomorrow), "[4]"},
+		{Tag(VirtualOverdue), "[1 2]"},
+		{Tag(VirtualDue), "[2 3 4]"},
+		{Tag(VirtualWaiting), "[7 8]"},
+		{Tag(VirtualUntil), "[8]"},
+		{Attr("due", "today"), "[2 3 6]"},
+		{Attr("due", "2026-02-11"), "[4]"},
+		{AttrMod("due", ModIs, "20260210T080000Z"), "[2 6]"},
+		{AttrMod("due", ModNot, "today"), "[1 4 5 7 8]"},
+		{DateRange("due", now, now.Add(24*time.Hour)), "[3 4]"},
+		{AttrMod("due", ModBefore, "yesterday"), "[]"},
+		{AttrMod("due", ModAfter, "tomorrow"), "[4 5]"},
+	}
+	for _, test := range tests {
+		matched, err := m.Filter(test.expr)
+		if err != nil {
+			t.Errorf("%v: %v", test.expr, err)
+			continue
+		}
+		if got := fmt.Sprint(sortedUUIDs(matched)); got != test.expected {
+			t.Errorf("%v: expected %s, got %s", test.expr, test.expected, got)
+		}
+	}
+
+	if _, err := m.Filter(Attr("due", "sometime")); err == nil {
+		t.Error("Invalid date was accepted")
+	}
+}
+
+func TestMatcher_Terms(t *testing.T) {
+	config := &TaskRC{UDA: map[string]string{"estimate": "numeric", "review": "date"}}
+	tasks := []Task{
+		{Id: 1, Uuid: "a1b2c3d4-0000-0000-0000-000000000001", Description: "Alpha",
+			UDA: map[string]interface{}{"estimate": 2.0, "review": MustParseTaskTime("20260201T000000Z")}},
+		{Id: 2, Uuid: "a1b2c3d4-0000-0000-0000-000000000002", Description: "Beta",
+			UDA: map[string]interface{}{"estimate": "5", "sprint": "S1"}},
+		{Id: 3, Uuid: "ffffffff-0000-0000-0000-000000000003", Description: "Gamma"},
+	}
+	m := NewMatcher(tasks, config)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"1"}, "[a1b2c3d4-0000-0000-0000-000000000001]"},
+		{[]string{"2-3"}, "[a1b2c3d4-0000-0000-0000-000000000002 ffffffff-0000-0000-0000-000000000003]"},
+		{[]string{"1,3"}, "[a1b2c3d4-0000-0000-0000-000000000001 ffffffff-0000-0000-0000-000000000003]"},
+		{[]string{"a1b2c3d4"}, "[a1b2c3d4-0000-0000-0000-000000000001 a1b2c3d4-0000-0000-0000-000000000002]"},
+		{[]string{"amm"}, "[ffffffff-0000-0000-0000-000000000003]"},
+		{[]string{"estimate.over:3"}, "[a1b2c3d4-0000-0000-0000-000000000002]"},
+		{[]string{"estimate.under:3"}, "[a1b2c3d4-0000-0000-0000-000000000001]"},
+		{[]string{"estimate:2"}, "[a1b2c3d4-0000-0000-0000-000000000001]"},
+		{[]string{"estimate:"}, "[ffffffff-0000-0000-0000-000000000003]"},
+		{[]string{"review.before:2026-02-02"}, "[a1b2c3d4-0000-0000-0000-000000000001]"},
+		{[]string{"sprint:S1"}, "[a1b2c3d4-0000-0000-0000-000000000002]"},
+		{[]string{"sprint.not:S1"}, "[a1b2c3d4-0000-0000-0000-000000000001 ffffffff-0000-0000-0000-000000000003]"},
+	}
+	for _, test := range tests {
+		expr, err := parseFilterArgs(test.args)
+		if err != nil {
+			t.Fatalf("Can't parse %v: %v", test.args, err)
+		}
+		matched, err := m.Filter(expr)
+		if err != nil {
+			t.Errorf("%v: %v", test.args, err)
+			continue
+		}
+		if got := fmt.Sprint(sortedUUIDs(matched)); got != test.expected {
+			t.Errorf("%v: expected %s, got %s", test.args, test.expected, got)
+		}
+	}
+}
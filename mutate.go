@@ -0,0 +1,204 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Changes of single tasks with `task` commands.
+//
+// Every method runs one command for the task with given UUID, with confirmations turned off, and returns the task
+// read back with `task export`. Copies of the task in TaskWarrior.Tasks are replaced with the updated version, so
+// they are not imported again by Commit.
+
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Mark task as completed with `task done`.
+func (tw *TaskWarrior) Done(uuid string) (*Task, error) {
+	return tw.DoneContext(context.Background(), uuid)
+}
+
+// Same as Done, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) DoneContext(ctx context.Context, uuid string) (*Task, error) {
+	return tw.mutate(ctx, uuid, "done")
+}
+
+// Mark task as deleted with `task delete`.
+func (tw *TaskWarrior) Delete(uuid string) (*Task, error) {
+	return tw.DeleteContext(context.Background(), uuid)
+}
+
+// Same as Delete, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) DeleteContext(ctx context.Context, uuid string) (*Task, error) {
+	return tw.mutate(ctx, uuid, "delete")
+}
+
+// Start working on task with `task start`.
+func (tw *TaskWarrior) Start(uuid string) (*Task, error) {
+	return tw.StartContext(context.Background(), uuid)
+}
+
+// Same as Start, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) StartContext(ctx context.Context, uuid string) (*Task, error) {
+	return tw.mutate(ctx, uuid, "start")
+}
+
+// Stop working on task with `task stop`.
+func (tw *TaskWarrior) Stop(uuid string) (*Task, error) {
+	return tw.StopContext(context.Background(), uuid)
+}
+
+// Same as Stop, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) StopContext(ctx context.Context, uuid string) (*Task, error) {
+	return tw.mutate(ctx, uuid, "stop")
+}
+
+// Change attributes of task with `task modify`. Changes map attribute names to new values: strings, numbers,
+// time.Time or TaskTime. Empty value removes the attribute.
+//
+// Example:
+//
+//	tw.Modify(uuid, map[string]interface{}{"project": "work", "due": time.Now().Add(time.Hour), "priority": ""})
+func (tw *TaskWarrior) Modify(uuid string, changes map[string]interface{}) (*Task, error) {
+	return tw.ModifyContext(context.Background(), uuid, changes)
+}
+
+// Same as Modify, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) ModifyContext(ctx context.Context, uuid string, changes map[string]interface{}) (*Task, error) {
+	return tw.mutate(ctx, uuid, "modify", modificationArgs(changes)...)
+}
+
+// Add annotation to task with `task annotate`.
+func (tw *TaskWarrior) Annotate(uuid, text string) (*Task, error) {
+	return tw.AnnotateContext(context.Background(), uuid, text)
+}
+
+// Same as Annotate, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) AnnotateContext(ctx context.Context, uuid, text string) (*Task, error) {
+	return tw.mutate(ctx, uuid, "annotate", "--", text)
+}
+
+// Remove annotation with given text from task with `task denotate`.
+func (tw *TaskWarrior) Denotate(uuid, text string) (*Task, error) {
+	return tw.DenotateContext(context.Background(), uuid, text)
+}
+
+// Same as Denotate, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) DenotateContext(ctx context.Context, uuid, text string) (*Task, error) {
+	return tw.mutate(ctx, uuid, "denotate", "--", text)
+}
+
+// Add tags to task.
+func (tw *TaskWarrior) Tag(uuid string, tags ...string) (*Task, error) {
+	return tw.TagContext(context.Background(), uuid, tags...)
+}
+
+// Same as Tag, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) TagContext(ctx context.Context, uuid string, tags ...string) (*Task, error) {
+	var args []string
+	for _, tag := range tags {
+		args = append(args, "+"+tag)
+	}
+	return tw.mutate(ctx, uuid, "modify", args...)
+}
+
+// Remove tags from task.
+func (tw *TaskWarrior) Untag(uuid string, tags ...string) (*Task, error) {
+	return tw.UntagContext(context.Background(), uuid, tags...)
+}
+
+// Same as Untag, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) UntagContext(ctx context.Context, uuid string, tags ...string) (*Task, error) {
+	var args []string
+	for _, tag := range tags {
+		args = append(args, "-"+tag)
+	}
+	return tw.mutate(ctx, uuid, "modify", args...)
+}
+
+// Create copy of task with `task duplicate`, applying changes like Modify does. Returns the new task.
+func (tw *TaskWarrior) Duplicate(uuid string, changes map[string]interface{}) (*Task, error) {
+	return tw.DuplicateContext(context.Background(), uuid, changes)
+}
+
+// Same as Duplicate, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) DuplicateContext(ctx context.Context, uuid string, changes map[string]interface{}) (*Task, error) {
+	out, err := tw.command(ctx, uuid, "duplicate", modificationArgs(changes)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// New task is reported as `Created task <id>.`
+	match := createdTaskRegexp.FindSubmatch(out)
+	if match == nil {
+		return nil, fmt.Errorf("can't find duplicate of task %s in output: %s", uuid, out)
+	}
+	id, _ := strconv.Atoi(string(match[1]))
+	tasks, err := tw.export(ctx, strconv.Itoa(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) != 1 {
+		return nil, fmt.Errorf("task %d: %w", id, ErrNoMatchingTasks)
+	}
+	task := &tasks[0]
+	tw.Tasks = append(tw.Tasks, *task)
+	tw.markSaved(task)
+	return task, nil
+}
+
+var createdTaskRegexp = regexp.MustCompile(`Created task (\d+)`)
+
+// Run command for the task and return the task read back from the database.
+func (tw *TaskWarrior) mutate(ctx context.Context, uuid, command string, args ...string) (*Task, error) {
+	if _, err := tw.command(ctx, uuid, command, args...); err != nil {
+		return nil, err
+	}
+	tasks, err := tw.export(ctx, "uuid:"+uuid)
+	if err != nil {
+		return nil, err
+	}
+	task, err := findTask(tasks, uuid)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tw.Tasks {
+		if tw.Tasks[i].Uuid == task.Uuid {
+			tw.Tasks[i] = *task
+			tw.markSaved(&tw.Tasks[i])
+		}
+	}
+	return task, nil
+}
+
+// Run command for the task with given UUID without confirmations.
+func (tw *TaskWarrior) command(ctx context.Context, uuid, command string, args ...string) ([]byte, error) {
+	if tw == nil {
+		return nil, fmt.Errorf("Uninitialized taskwarrior database!")
+	}
+	if uuid == "" {
+		return nil, fmt.Errorf("task UUID is required")
+	}
+	rcOpt := "rc:" + tw.Config.ConfigPath
+	cmdArgs := append([]string{rcOpt, "rc.confirmation=off", "uuid:" + uuid, command}, args...)
+	return tw.run(ctx, nil, cmdArgs...)
+}
+
+// Render changes as `name:value` arguments in sorted order.
+func modificationArgs(changes map[string]interface{}) []string {
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, len(names))
+	for _, name := range names {
+		args = append(args, name+":"+quoteFilterValue(formatFilterValue(changes[name])))
+	}
+	return args
+}
@@ -0,0 +1,167 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tasks used as database content for mutation tests.
+var mutateFixture = []Task{
+	{Description: "Write report", Status: "pending", Project: "work", Tags: []string{"next"},
+		Uuid: "00000000-0000-0000-0000-000000000001", Entry: MustParseTaskTime("20260206T120000Z")},
+	{Description: "Buy groceries", Status: "pending",
+		Uuid: "00000000-0000-0000-0000-000000000002", Entry: MustParseTaskTime("20260206T120000Z")},
+}
+
+func newMutateTaskWarrior(t *testing.T) *TaskWarrior {
+	tw := newFakeTaskWarrior(t, mutateFixture...)
+	tw.Runner.(*FakeRunner).Now = func() time.Time { return time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC) }
+	return tw
+}
+
+func TestTaskWarrior_Done(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	if err := tw.FetchAllTasks(); err != nil {
+		t.Fatalf("FetchAllTasks fails with following error: %v", err)
+	}
+
+	task, err := tw.Done(mutateFixture[0].Uuid)
+	if err != nil {
+		t.Fatalf("Done fails with following error: %v", err)
+	}
+	if task.Status != "completed" || task.End.String() != "20260210T120000Z" || task.Id != 0 {
+		t.Errorf("Task was not completed: %+v", task)
+	}
+
+	// Command line
+	calls := tw.Runner.(*FakeRunner).Calls
+	expected := []string{"rc:./fixtures/taskrc/simple_1", "rc.confirmation=off", "uuid:" + mutateFixture[0].Uuid, "done"}
+	if !reflect.DeepEqual(calls[1].Args, expected) {
+		t.Errorf("Unexpected arguments: %v", calls[1].Args)
+	}
+
+	// Loaded copy is updated and not committed again
+	if tw.Tasks[0].Status != "completed" || len(tw.DirtyTasks()) != 0 {
+		t.Errorf("Loaded task was not updated: %+v", tw.Tasks[0])
+	}
+
+	// Completed task can't be completed again
+	var cmdErr *CommandError
+	if _, err := tw.Done(mutateFixture[0].Uuid); !errors.As(err, &cmdErr) {
+		t.Errorf("Expected CommandError, got %v", err)
+	}
+
+	_, err = tw.Done("00000000-0000-0000-0000-00000000ffff")
+	if !errors.Is(err, ErrNoMatchingTasks) {
+		t.Errorf("Expected ErrNoMatchingTasks, got %v", err)
+	}
+	if _, err := tw.Done(""); err == nil {
+		t.Error("Done works without UUID")
+	}
+}
+
+func TestTaskWarrior_Delete(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	task, err := tw.Delete(mutateFixture[1].Uuid)
+	if err != nil || task.Status != "deleted" || task.End.IsZero() {
+		t.Errorf("Task was not deleted: %+v (%v)", task, err)
+	}
+}
+
+func TestTaskWarrior_StartStop(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	task, err := tw.Start(mutateFixture[0].Uuid)
+	if err != nil || task.Start.String() != "20260210T120000Z" {
+		t.Errorf("Task was not started: %+v (%v)", task, err)
+	}
+	task, err = tw.Stop(mutateFixture[0].Uuid)
+	if err != nil || !task.Start.IsZero() {
+		t.Errorf("Task was not stopped: %+v (%v)", task, err)
+	}
+}
+
+func TestTaskWarrior_Modify(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	task, err := tw.Modify(mutateFixture[0].Uuid, map[string]interface{}{
+		"description": "Write annual report",
+		"due":         time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),
+		"project":     "",
+		"estimate":    2.5,
+	})
+	if err != nil {
+		t.Fatalf("Modify fails with following error: %v", err)
+	}
+	if task.Description != "Write annual report" || task.Project != "" || task.Due.String() != "20260301T090000Z" {
+		t.Errorf("Task was modified incorrectly: %+v", task)
+	}
+	if task.UDA["estimate"] != "2.5" {
+		t.Errorf("UDA was not modified: %v", task.UDA)
+	}
+
+	calls := tw.Runner.(*FakeRunner).Calls
+	expected := []string{"rc:./fixtures/taskrc/simple_1", "rc.confirmation=off", "uuid:" + mutateFixture[0].Uuid,
+		"modify", `description:"Write annual report"`, "due:20260301T090000Z", "estimate:2.5", "project:"}
+	if !reflect.DeepEqual(calls[0].Args, expected) {
+		t.Errorf("Unexpected arguments: %v", calls[0].Args)
+	}
+}
+
+func TestTaskWarrior_Annotate(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	uuid := mutateFixture[1].Uuid
+
+	tw.Annotate(uuid, "milk")
+	task, err := tw.Annotate(uuid, "due:tomorrow is not an attribute")
+	if err != nil || len(task.Annotations) != 2 {
+		t.Fatalf("Task was not annotated: %+v (%v)", task, err)
+	}
+	if task.Annotations[1].Description != "due:tomorrow is not an attribute" || !task.Due.IsZero() {
+		t.Errorf("Annotation text was parsed as modification: %+v", task)
+	}
+
+	task, err = tw.Denotate(uuid, "milk")
+	if err != nil || len(task.Annotations) != 1 {
+		t.Errorf("Annotation was not removed: %+v (%v)", task, err)
+	}
+	if _, err := tw.Denotate(uuid, "bread"); err == nil {
+		t.Error("Denotate of missing annotation succeeded")
+	}
+}
+
+func TestTaskWarrior_Tag(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	uuid := mutateFixture[0].Uuid
+
+	task, err := tw.Tag(uuid, "a", "b")
+	if err != nil || !reflect.DeepEqual(task.Tags, []string{"next", "a", "b"}) {
+		t.Errorf("Tags were not added: %v (%v)", task.Tags, err)
+	}
+	task, err = tw.Untag(uuid, "next", "b")
+	if err != nil || !reflect.DeepEqual(task.Tags, []string{"a"}) {
+		t.Errorf("Tags were not removed: %v (%v)", task.Tags, err)
+	}
+}
+
+func TestTaskWarrior_Duplicate(t *testing.T) {
+	tw := newMutateTaskWarrior(t)
+	tw.Done(mutateFixture[0].Uuid)
+
+	task, err := tw.Duplicate(mutateFixture[0].Uuid, map[string]interface{}{"project": "home"})
+	if err != nil {
+		t.Fatalf("Duplicate fails with following error: %v", err)
+	}
+	if task.Uuid == mutateFixture[0].Uuid || task.Description != "Write report" || task.Project != "home" {
+		t.Errorf("Unexpected duplicate: %+v", task)
+	}
+	if task.Status != "pending" || !task.End.IsZero() || task.Id != 2 {
+		t.Errorf("Duplicate should be a new pending task: %+v", task)
+	}
+	if len(tw.Tasks) != 1 || tw.Tasks[0].Uuid != task.Uuid {
+		t.Errorf("Duplicate was not added to loaded tasks: %v", tw.Tasks)
+	}
+}
@@ -53,99 +53,96 @@ func TestQueryTasks_NilTaskWarrior(t *testing.T) {
 	}
 }
 
-func TestQueryTasks_EmptyFilters(t *testing.T) {
-	// Test QueryTasks with no filters - should return all tasks
-	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
+// Tasks used as database content for query tests.
+var queryFixture = []Task{
+	{Description: "Write report", Status: "pending", Project: "work",
+		Uuid: "00000000-0000-0000-0000-000000000001", Entry: MustParseTaskTime("20260206T120000Z"), Tags: []string{"urgent", "work"}},
+	{Description: "Fix backend", Status: "pending", Project: "work.backend",
+		Uuid: "00000000-0000-0000-0000-000000000002", Entry: MustParseTaskTime("20260206T120000Z"), Tags: []string{"urgent"}},
+	{Description: "Buy groceries", Status: "completed", Project: "home",
+		Uuid: "00000000-0000-0000-0000-000000000003", Entry: MustParseTaskTime("20260206T120000Z"), Tags: []string{"work"}},
+}
+
+// Helper that checks that query returned tasks with expected UUIDs.
+func assertQueryResult(t *testing.T, name string, tasks []Task, err error, expected ...string) {
+	t.Helper()
 	if err != nil {
-		t.Skip("Could not initialize TaskWarrior from fixture")
+		t.Fatalf("QueryTasks with %s returned error: %v", name, err)
+	}
+	if len(tasks) != len(expected) {
+		t.Fatalf("QueryTasks with %s: expected %d tasks, got %d", name, len(expected), len(tasks))
 	}
+	for i, uuid := range expected {
+		if tasks[i].Uuid != uuid {
+			t.Errorf("QueryTasks with %s: expected task %s at %d, got %s", name, uuid, i, tasks[i].Uuid)
+		}
+	}
+}
 
-	filter := Filter{}
+func TestQueryTasks_EmptyFilters(t *testing.T) {
+	// Test QueryTasks with no filters - should return all tasks
+	tw := newFakeTaskWarrior(t, queryFixture...)
 
-	// Note: This test requires taskwarrior to be installed and the fixture to have tasks
-	// We'll just verify it doesn't crash
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		// Expected if taskwarrior command fails
-		t.Logf("QueryTasks with empty filters returned error (expected if no tasks): %v", err)
-	}
+	filter := Filter{}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "empty filters", tasks, err,
+		queryFixture[0].Uuid, queryFixture[1].Uuid, queryFixture[2].Uuid)
 
+	// In-memory tasks don't affect the query
 	tw.Tasks = append(tw.Tasks, Task{
 		Description: "Test task",
 		Status:      "pending",
-		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Uuid:        "00000000-0000-0000-0000-000000000009",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	})
-
-	// Test with in-memory tasks when taskwarrior command fails
-	// (This will use the FetchAllTasks results)
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		t.Logf("QueryTasks with empty filters on in-memory tasks: %v", err)
-	}
+	tasks, err = tw.QueryTasks(filter)
+	assertQueryResult(t, "empty filters", tasks, err,
+		queryFixture[0].Uuid, queryFixture[1].Uuid, queryFixture[2].Uuid)
 }
 
 func TestFilter_Project(t *testing.T) {
-	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
-	if err != nil {
-		t.Skip("Could not initialize TaskWarrior from fixture")
-	}
+	tw := newFakeTaskWarrior(t, queryFixture...)
 
-	// Test project filter
+	// Test project filter, subprojects are matched too
 	filter := Filter{Project: "work"}
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		t.Logf("QueryTasks with project filter: %v", err)
-	}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "project filter", tasks, err, queryFixture[0].Uuid, queryFixture[1].Uuid)
 }
 
 func TestFilter_Tags(t *testing.T) {
-	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
-	if err != nil {
-		t.Skip("Could not initialize TaskWarrior from fixture")
-	}
+	tw := newFakeTaskWarrior(t, queryFixture...)
 
 	// Test tags filter
 	filter := Filter{Tags: []string{"urgent", "work"}}
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		t.Logf("QueryTasks with tags filter: %v", err)
-	}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "tags filter", tasks, err, queryFixture[0].Uuid)
 }
 
 func TestFilter_Status(t *testing.T) {
-	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
-	if err != nil {
-		t.Skip("Could not initialize TaskWarrior from fixture")
-	}
+	tw := newFakeTaskWarrior(t, queryFixture...)
 
 	// Test status filter
 	filter := Filter{Status: "pending"}
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		t.Logf("QueryTasks with status filter: %v", err)
-	}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "status filter", tasks, err, queryFixture[0].Uuid, queryFixture[1].Uuid)
 }
 
 func TestFilter_UUIDs(t *testing.T) {
-	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
-	if err != nil {
-		t.Skip("Could not initialize TaskWarrior from fixture")
-	}
+	tw := newFakeTaskWarrior(t, queryFixture...)
 
 	// Test UUIDs filter
 	filter := Filter{UUIDs: []string{"00000000-0000-0000-0000-000000000001"}}
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		t.Logf("QueryTasks with UUIDs filter: %v", err)
-	}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "UUIDs filter", tasks, err, queryFixture[0].Uuid)
+
+	// Any of UUIDs matches
+	filter = Filter{UUIDs: []string{queryFixture[0].Uuid, queryFixture[2].Uuid}}
+	tasks, err = tw.QueryTasks(filter)
+	assertQueryResult(t, "UUIDs filter", tasks, err, queryFixture[0].Uuid, queryFixture[2].Uuid)
 }
 
 func TestFilter_Combined(t *testing.T) {
-	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
-	if err != nil {
-		t.Skip("Could not initialize TaskWarrior from fixture")
-	}
+	tw := newFakeTaskWarrior(t, queryFixture...)
 
 	// Test combined filters
 	filter := Filter{
@@ -154,8 +151,6 @@ func TestFilter_Combined(t *testing.T) {
 		Status:  "pending",
 	}
 
-	_, err = tw.QueryTasks(filter)
-	if err != nil {
-		t.Logf("QueryTasks with combined filters: %v", err)
-	}
+	tasks, err := tw.QueryTasks(filter)
+	assertQueryResult(t, "combined filters", tasks, err, queryFixture[0].Uuid, queryFixture[1].Uuid)
 }
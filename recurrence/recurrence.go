@@ -0,0 +1,185 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Package recurrence expands recurring taskwarrior tasks into their instances.
+//
+// A recurring task is stored as a parent template with status "recurring", `recur` period, `due` date of the first
+// instance and optional `until` date. Instances are pending child tasks with `parent` set to the template UUID and
+// `imask` set to their index. The template `mask` has one character per generated instance: '-' for pending, '+'
+// for completed, 'X' for deleted and 'W' for waiting ones.
+//
+// Like taskwarrior 2.6, Expand creates instances from the first due date up to recurrence.limit instances in the
+// future, skipping ones already recorded in the mask. See https://taskwarrior.org/docs/recurrence/.
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Period is an interval between instances of recurring task.
+type Period struct {
+	taskwarrior.Duration
+}
+
+// Parse recurrence period: any positive taskwarrior duration, like daily, weekdays, biweekly, quarterly, 3wk or
+// P1M.
+func ParsePeriod(s string) (Period, error) {
+	d, err := taskwarrior.ParseDuration(s)
+	if err != nil {
+		return Period{}, err
+	}
+	if d.Approximate() <= 0 {
+		return Period{}, fmt.Errorf("'%s' is not a valid recurrence period", s)
+	}
+	return Period{d}, nil
+}
+
+// Return date of the occurrence following given one, see taskwarrior.Duration.AddTo.
+func (p Period) Next(t time.Time) time.Time {
+	return p.AddTo(t)
+}
+
+// Return number of future instances to generate, from recurrence.limit option (TaskRC.RecallAfter). Defaults to 1.
+func Limit(config *taskwarrior.TaskRC) int {
+	if config == nil {
+		return 1
+	}
+	n, err := strconv.Atoi(config.RecallAfter)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Return due dates of instances of the recurring task: all dates from the first due date up to limit dates after
+// now, excluding ones after the until date.
+func Dates(parent *taskwarrior.Task, limit int, now time.Time) ([]time.Time, error) {
+	period, err := parentPeriod(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []time.Time
+	future := 0
+	for date := parent.Due.Time; future < limit; date = period.Next(date) {
+		if !parent.Until.IsZero() && date.After(parent.Until.Time) {
+			break
+		}
+		dates = append(dates, date)
+		if date.After(now) {
+			future++
+		}
+	}
+	return dates, nil
+}
+
+// Return due dates of next n occurrences of the recurring task after now, without changing anything.
+func Preview(parent *taskwarrior.Task, n int, now time.Time) ([]time.Time, error) {
+	dates, err := Dates(parent, n, now)
+	if err != nil {
+		return nil, err
+	}
+	var upcoming []time.Time
+	for _, date := range dates {
+		if date.After(now) {
+			upcoming = append(upcoming, date)
+		}
+	}
+	return upcoming, nil
+}
+
+// Create instances of the recurring task that are due up to limit instances in the future and not recorded in its
+// mask yet. The mask of the parent is extended with the new instances.
+func Expand(parent *taskwarrior.Task, limit int, now time.Time) ([]taskwarrior.Task, error) {
+	dates, err := Dates(parent, limit, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []taskwarrior.Task
+	for i := len(parent.Mask); i < len(dates); i++ {
+		child := newChild(parent, i, dates[i], now)
+		children = append(children, child)
+		if child.Status == "waiting" {
+			parent.Mask += "W"
+		} else {
+			parent.Mask += "-"
+		}
+	}
+	return children, nil
+}
+
+// Same as Expand with the limit from configuration.
+func Generate(parent *taskwarrior.Task, config *taskwarrior.TaskRC, now time.Time) ([]taskwarrior.Task, error) {
+	return Expand(parent, Limit(config), now)
+}
+
+// Update mask of the recurring task from statuses of its instances.
+func UpdateMask(parent *taskwarrior.Task, children []taskwarrior.Task) {
+	mask := []byte(parent.Mask)
+	for _, child := range children {
+		if child.Parent != parent.Uuid || child.Imask < 0 || child.Imask >= len(mask) {
+			continue
+		}
+		switch child.Status {
+		case "pending":
+			mask[child.Imask] = '-'
+		case "completed":
+			mask[child.Imask] = '+'
+		case "deleted":
+			mask[child.Imask] = 'X'
+		case "waiting":
+			mask[child.Imask] = 'W'
+		}
+	}
+	parent.Mask = string(mask)
+}
+
+func parentPeriod(parent *taskwarrior.Task) (Period, error) {
+	if parent.Recur == "" {
+		return Period{}, fmt.Errorf("task %s is not recurring", parent.Uuid)
+	}
+	if parent.Due.IsZero() {
+		return Period{}, fmt.Errorf("recurring task %s has no due date", parent.Uuid)
+	}
+	return ParsePeriod(parent.Recur)
+}
+
+// Create instance of the recurring task with given index and due date.
+func newChild(parent *taskwarrior.Task, index int, due time.Time, now time.Time) taskwarrior.Task {
+	child := *parent
+	child.Id = 0
+	child.Uuid = taskwarrior.NewUUID()
+	child.Status = "pending"
+	child.Parent = parent.Uuid
+	child.Imask = index
+	child.Mask = ""
+	child.Urgency = 0
+	child.Due = taskwarrior.NewTaskTime(due)
+	child.Entry = taskwarrior.NewTaskTime(now)
+	child.Modified = taskwarrior.NewTaskTime(now)
+	child.Start = taskwarrior.TaskTime{}
+	child.End = taskwarrior.TaskTime{}
+	child.Tags = append([]string(nil), parent.Tags...)
+	child.Depends = append([]string(nil), parent.Depends...)
+	child.Annotations = append([]taskwarrior.Annotation(nil), parent.Annotations...)
+	if parent.UDA != nil {
+		child.UDA = map[string]interface{}{}
+		for k, v := range parent.UDA {
+			child.UDA[k] = v
+		}
+	}
+
+	// Wait date keeps its distance to the due date.
+	if !parent.Wait.IsZero() {
+		child.Wait = taskwarrior.NewTaskTime(due.Add(parent.Wait.Sub(parent.Due.Time)))
+		if child.Wait.After(now) {
+			child.Status = "waiting"
+		}
+	}
+	return child
+}
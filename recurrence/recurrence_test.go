@@ -0,0 +1,217 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func date(s string) time.Time {
+	return taskwarrior.MustParseTaskTime(s).Time
+}
+
+func TestParsePeriod(t *testing.T) {
+	cases := map[string]taskwarrior.Duration{
+		"daily":     {Days: 1},
+		"weekdays":  {Weekdays: true},
+		"biweekly":  {Days: 14},
+		"quarterly": {Months: 3},
+		"annual":    {Months: 12},
+		"3wk":       {Days: 21},
+		"2 mo":      {Months: 2},
+		"10d":       {Days: 10},
+		"12h":       {Time: 12 * time.Hour},
+		"week":      {Days: 7},
+		"P1M":       {Months: 1},
+		"P1Y2M3D":   {Months: 14, Days: 3},
+		"P2W":       {Days: 14},
+		"PT90M":     {Time: 90 * time.Minute},
+	}
+	for s, expected := range cases {
+		got, err := ParsePeriod(s)
+		if err != nil {
+			t.Errorf("ParsePeriod(%q): %v", s, err)
+		} else if got.Duration != expected {
+			t.Errorf("ParsePeriod(%q): expected %+v, got %+v", s, expected, got)
+		}
+	}
+
+	for _, s := range []string{"", "sometimes", "0d", "P", "PT", "3x", "-1d"} {
+		if _, err := ParsePeriod(s); err == nil {
+			t.Errorf("ParsePeriod(%q): expected error", s)
+		}
+	}
+}
+
+func TestPeriod_Next(t *testing.T) {
+	cases := []struct {
+		period   string
+		from     string
+		expected string
+	}{
+		{"daily", "20260131T090000Z", "20260201T090000Z"},
+		{"weekdays", "20260213T090000Z", "20260216T090000Z"}, // Friday
+		{"weekdays", "20260214T090000Z", "20260216T090000Z"}, // Saturday
+		{"weekdays", "20260216T090000Z", "20260217T090000Z"},
+		{"monthly", "20260131T090000Z", "20260228T090000Z"},
+		{"monthly", "20240131T090000Z", "20240229T090000Z"},
+		{"quarterly", "20261130T090000Z", "20270228T090000Z"},
+		{"yearly", "20240229T090000Z", "20250228T090000Z"},
+		{"P1M", "20260315T090000Z", "20260415T090000Z"},
+		{"3wk", "20260101T090000Z", "20260122T090000Z"},
+	}
+	for _, c := range cases {
+		p, err := ParsePeriod(c.period)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Next(date(c.from)); !got.Equal(date(c.expected)) {
+			t.Errorf("%s after %s: expected %s, got %s", c.period, c.from, c.expected, got)
+		}
+	}
+}
+
+func recurringParent() taskwarrior.Task {
+	return taskwarrior.Task{
+		Uuid:        "5f8b3a34-2a48-4d6a-b6a1-7f0d5b8f1c01",
+		Description: "Pay rent",
+		Status:      "recurring",
+		Project:     "home",
+		Tags:        []string{"bills"},
+		Recur:       "monthly",
+		Due:         taskwarrior.MustParseTaskTime("20260101T090000Z"),
+		Wait:        taskwarrior.MustParseTaskTime("20251229T090000Z"),
+		Entry:       taskwarrior.MustParseTaskTime("20251201T090000Z"),
+	}
+}
+
+func TestExpand(t *testing.T) {
+	parent := recurringParent()
+	parent.Mask = "+-"
+	now := date("20260210T120000Z")
+
+	children, err := Expand(&parent, 2, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// January and February instances are in the mask, March and April are the two future ones.
+	if len(children) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(children))
+	}
+	if parent.Mask != "+-WW" {
+		t.Errorf("Incorrect mask: %s", parent.Mask)
+	}
+	for i, expected := range []string{"20260301T090000Z", "20260401T090000Z"} {
+		child := children[i]
+		if child.Due.String() != expected {
+			t.Errorf("Instance %d: expected due %s, got %s", i, expected, child.Due)
+		}
+		if child.Parent != parent.Uuid || child.Imask != i+2 || child.Mask != "" || child.Uuid == "" {
+			t.Errorf("Instance %d: incorrect parent fields %+v", i, child)
+		}
+		if child.Project != "home" || len(child.Tags) != 1 || child.Recur != "monthly" {
+			t.Errorf("Instance %d: attributes are not copied %+v", i, child)
+		}
+	}
+	if children[0].Status != "waiting" || children[0].Wait.String() != "20260226T090000Z" {
+		t.Errorf("Incorrect wait of first instance: %s %s", children[0].Status, children[0].Wait)
+	}
+	if children[1].Status != "waiting" {
+		t.Errorf("Incorrect status of second instance: %s", children[1].Status)
+	}
+
+	// Nothing new until time passes.
+	children, err = Expand(&parent, 2, now)
+	if err != nil || len(children) != 0 {
+		t.Errorf("Expected no new instances, got %d (%v)", len(children), err)
+	}
+}
+
+func TestExpand_Until(t *testing.T) {
+	parent := recurringParent()
+	parent.Wait = taskwarrior.TaskTime{}
+	parent.Until = taskwarrior.MustParseTaskTime("20260301T090000Z")
+
+	children, err := Expand(&parent, 5, date("20251215T000000Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != 3 || parent.Mask != "---" {
+		t.Errorf("Expected 3 instances up to until date, got %d with mask %s", len(children), parent.Mask)
+	}
+	if children[0].Status != "pending" || children[0].Imask != 0 {
+		t.Errorf("Incorrect first instance: %+v", children[0])
+	}
+}
+
+func TestGenerate_Limit(t *testing.T) {
+	config := &taskwarrior.TaskRC{RecallAfter: "3"}
+	if Limit(config) != 3 || Limit(nil) != 1 || Limit(&taskwarrior.TaskRC{RecallAfter: "x"}) != 1 {
+		t.Error("Incorrect recurrence limit")
+	}
+
+	parent := recurringParent()
+	parent.Recur = "weekdays"
+	parent.Due = taskwarrior.MustParseTaskTime("20260213T090000Z")
+	children, err := Generate(&parent, config, date("20260212T000000Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dues []string
+	for _, child := range children {
+		dues = append(dues, child.Due.String())
+	}
+	expected := []string{"20260213T090000Z", "20260216T090000Z", "20260217T090000Z"}
+	if len(dues) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, dues)
+	}
+	for i := range expected {
+		if dues[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, dues)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	parent := recurringParent()
+	parent.Recur = "P1M"
+	parent.Mask = "-"
+	dates, err := Preview(&parent, 3, date("20260115T000000Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"20260201T090000Z", "20260301T090000Z", "20260401T090000Z"}
+	if len(dates) != 3 {
+		t.Fatalf("Expected 3 dates, got %v", dates)
+	}
+	for i := range dates {
+		if !dates[i].Equal(date(expected[i])) {
+			t.Errorf("Expected %s, got %s", expected[i], dates[i])
+		}
+	}
+	if parent.Mask != "-" {
+		t.Error("Preview changed the task")
+	}
+
+	if _, err := Preview(&taskwarrior.Task{Recur: "daily"}, 1, time.Now()); err == nil {
+		t.Error("Expected error for task without due date")
+	}
+}
+
+func TestUpdateMask(t *testing.T) {
+	parent := recurringParent()
+	parent.Mask = "---"
+	UpdateMask(&parent, []taskwarrior.Task{
+		{Parent: parent.Uuid, Imask: 0, Status: "completed"},
+		{Parent: parent.Uuid, Imask: 2, Status: "deleted"},
+		{Parent: "other", Imask: 1, Status: "completed"},
+		{Parent: parent.Uuid, Imask: 7, Status: "completed"},
+	})
+	if parent.Mask != "+-X" {
+		t.Errorf("Incorrect mask: %s", parent.Mask)
+	}
+}
@@ -0,0 +1,85 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Command execution layer. Every call of the `task` binary goes through the Runner interface, so the library can be
+// used (and tested) with a replacement implementation instead of the system taskwarrior.
+
+package taskwarrior
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Time to wait for output pipes to be closed after the process was killed, e.g. by children spawned from hooks.
+const execWaitDelay = time.Second
+
+// Invocation describes a single `task` command call.
+type Invocation struct {
+	Args  []string // Command-line arguments, without the binary name
+	Stdin []byte   // Data written to the standard input, may be nil
+	Env   []string // Additional environment variables in "KEY=value" form
+}
+
+// Output contains the results of finished `task` command.
+type Output struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+// Runner executes taskwarrior commands.
+//
+// Run returns an error only when the command could not be executed at all or the context was done before the
+// command finished. Non-zero exit status of the command itself is reported through Output.ExitCode.
+type Runner interface {
+	Run(ctx context.Context, inv Invocation) (Output, error)
+}
+
+// ExecRunner runs the real taskwarrior binary with os/exec.
+type ExecRunner struct {
+	Binary string // Path or name of the binary, "task" if empty
+}
+
+// Default Runner used by TaskWarrior instances without their own one.
+var DefaultRunner Runner = &ExecRunner{}
+
+// Run executes taskwarrior binary with given arguments. The process is killed when the context is done.
+func (r *ExecRunner) Run(ctx context.Context, inv Invocation) (Output, error) {
+	binary := r.Binary
+	if binary == "" {
+		binary = "task"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, inv.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = execWaitDelay
+	if inv.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(inv.Stdin)
+	}
+	if len(inv.Env) > 0 {
+		cmd.Env = append(os.Environ(), inv.Env...)
+	}
+
+	err := cmd.Run()
+	out := Output{Stdout: stdout.Bytes(), Stderr: stderr.Bytes()}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return out, ctxErr
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		out.ExitCode = exitErr.ExitCode()
+		return out, nil
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+		return out, fmt.Errorf("%w: %w", ErrTaskBinaryNotFound, err)
+	}
+	return out, err
+}
@@ -0,0 +1,137 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecRunner_Run(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	runner := &ExecRunner{Binary: "sh"}
+
+	// Stdout, stderr, stdin and environment
+	out, err := runner.Run(context.Background(), Invocation{
+		Args:  []string{"-c", `cat; echo "$GREETING"; echo oops >&2`},
+		Stdin: []byte("input\n"),
+		Env:   []string{"GREETING=hello"},
+	})
+	if err != nil {
+		t.Fatalf("Run fails with following error: %v", err)
+	}
+	if string(out.Stdout) != "input\nhello\n" {
+		t.Errorf("Unexpected stdout: '%s'", out.Stdout)
+	}
+	if string(out.Stderr) != "oops\n" {
+		t.Errorf("Unexpected stderr: '%s'", out.Stderr)
+	}
+
+	// Non-zero exit status is not an error
+	out, err = runner.Run(context.Background(), Invocation{Args: []string{"-c", "exit 3"}})
+	if err != nil {
+		t.Errorf("Run returned error for non-zero exit status: %v", err)
+	}
+	if out.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", out.ExitCode)
+	}
+
+	// Killed on context timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = runner.Run(ctx, Invocation{Args: []string{"-c", "sleep 10"}})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded error, got %v", err)
+	}
+
+	// Missing binary
+	_, err = (&ExecRunner{Binary: "/does/not/exist/task"}).Run(context.Background(), Invocation{})
+	if err == nil {
+		t.Error("Run should fail for non-existent binary")
+	}
+}
+
+func TestFakeRunner_AddModify(t *testing.T) {
+	fake := NewFakeRunner()
+	fake.Now = func() time.Time { return time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC) }
+
+	out, _ := fake.Run(context.Background(), Invocation{Args: []string{"rc:/tmp/taskrc", "add", "Buy", "milk", "project:home", "+shop"}})
+	if out.ExitCode != 0 || string(out.Stdout) != "Created task 1.\n" {
+		t.Fatalf("Unexpected add result: %d '%s' '%s'", out.ExitCode, out.Stdout, out.Stderr)
+	}
+
+	tasks := fake.Tasks()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(tasks))
+	}
+	if tasks[0].Description != "Buy milk" || tasks[0].Project != "home" || tasks[0].Status != "pending" {
+		t.Errorf("Task was added incorrectly: %+v", tasks[0])
+	}
+	if len(tasks[0].Tags) != 1 || tasks[0].Tags[0] != "shop" {
+		t.Errorf("Unexpected tags: %v", tasks[0].Tags)
+	}
+	if tasks[0].Entry.String() != "20260206T120000Z" {
+		t.Errorf("Unexpected entry: %s", tasks[0].Entry)
+	}
+
+	out, _ = fake.Run(context.Background(), Invocation{Args: []string{"1", "modify", "project:", "-shop", "priority:H"}})
+	if out.ExitCode != 0 {
+		t.Fatalf("Modify fails: %s", out.Stderr)
+	}
+	tasks = fake.Tasks()
+	if tasks[0].Project != "" || len(tasks[0].Tags) != 0 || tasks[0].Priority != "H" {
+		t.Errorf("Task was modified incorrectly: %+v", tasks[0])
+	}
+
+	// Nothing to modify
+	out, _ = fake.Run(context.Background(), Invocation{Args: []string{"project:none", "modify", "priority:L"}})
+	if out.ExitCode == 0 || !strings.Contains(string(out.Stderr), "No matches") {
+		t.Errorf("Modify without matches should fail, got %d '%s'", out.ExitCode, out.Stderr)
+	}
+
+	if len(fake.Calls) != 3 {
+		t.Errorf("Expected 3 recorded calls, got %d", len(fake.Calls))
+	}
+}
+
+func TestFakeRunner_ImportExport(t *testing.T) {
+	fake := NewFakeRunner(Task{
+		Description: "Existing",
+		Status:      "pending",
+		Uuid:        "00000000-0000-0000-0000-000000000001",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
+	})
+
+	stdin := `{"uuid":"00000000-0000-0000-0000-000000000001","description":"Existing","status":"pending","entry":"20260206T120000Z"}
+{"uuid":"00000000-0000-0000-0000-000000000002","description":"New","status":"completed","entry":"20260206T120000Z"}`
+	out, _ := fake.Run(context.Background(), Invocation{Args: []string{"import", "-"}, Stdin: []byte(stdin)})
+	if out.ExitCode != 0 {
+		t.Fatalf("Import fails: %s", out.Stderr)
+	}
+	if !strings.Contains(string(out.Stdout), " skip 00000000-0000-0000-0000-000000000001") ||
+		!strings.Contains(string(out.Stdout), " add  00000000-0000-0000-0000-000000000002") {
+		t.Errorf("Unexpected import output: '%s'", out.Stdout)
+	}
+
+	out, _ = fake.Run(context.Background(), Invocation{Args: []string{"status:completed", "export"}})
+	var tasks []Task
+	if err := json.Unmarshal(out.Stdout, &tasks); err != nil {
+		t.Fatalf("Can't parse export output: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].Description != "New" || tasks[0].Id != 0 {
+		t.Errorf("Unexpected export result: %+v", tasks)
+	}
+
+	// Unknown commands are reported as failure
+	out, _ = fake.Run(context.Background(), Invocation{Args: []string{"burndown"}})
+	if out.ExitCode == 0 {
+		t.Error("Unsupported command should fail")
+	}
+}
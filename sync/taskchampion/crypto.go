@@ -0,0 +1,102 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Encryption of data sent to the remote server.
+//
+// The key is derived from the encryption secret with PBKDF2-HMAC-SHA256 using the client ID as salt. Sealed data
+// consists of the envelope version byte, a random 12-byte nonce and ChaCha20-Poly1305 ciphertext with tag. The
+// additional data binds the ciphertext to a version: the application ID byte followed by the 16 bytes of the version
+// UUID (the parent version for history segments, the snapshot version for snapshots).
+
+package taskchampion
+
+import (
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	pbkdf2Iterations = 600000
+	envelopeVersion  = 1
+	taskAppID        = 1
+)
+
+// Cryptor encrypts and decrypts history segments and snapshots.
+type Cryptor struct {
+	aead cipher.AEAD
+}
+
+// Create cryptor with key derived from the secret and salt. The remote server uses client ID bytes as salt.
+func NewCryptor(salt []byte, secret string) (*Cryptor, error) {
+	if secret == "" {
+		return nil, errors.New("encryption secret is empty")
+	}
+	key, err := pbkdf2.Key(sha256.New, secret, salt, pbkdf2Iterations, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Cryptor{aead: aead}, nil
+}
+
+// Encrypt data bound to given version.
+func (c *Cryptor) Seal(versionID string, data []byte) ([]byte, error) {
+	aad, err := makeAAD(versionID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+chacha20poly1305.NonceSize, 1+chacha20poly1305.NonceSize+len(data)+c.aead.Overhead())
+	out[0] = envelopeVersion
+	nonce := out[1:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(out, nonce, data, aad), nil
+}
+
+// Decrypt data bound to given version.
+func (c *Cryptor) Open(versionID string, sealed []byte) ([]byte, error) {
+	aad, err := makeAAD(versionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < 1+chacha20poly1305.NonceSize+c.aead.Overhead() {
+		return nil, errors.New("sealed data is too short")
+	}
+	if sealed[0] != envelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version %d", sealed[0])
+	}
+	nonce := sealed[1 : 1+chacha20poly1305.NonceSize]
+	data, err := c.aead.Open(nil, nonce, sealed[1+chacha20poly1305.NonceSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("can't decrypt data of version %s (wrong encryption secret?): %w", versionID, err)
+	}
+	return data, nil
+}
+
+func makeAAD(versionID string) ([]byte, error) {
+	id, err := uuidBytes(versionID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{taskAppID}, id...), nil
+}
+
+// Return 16 bytes of the UUID.
+func uuidBytes(s string) ([]byte, error) {
+	if !reUUID.MatchString(s) {
+		return nil, fmt.Errorf("'%s' is not a UUID", s)
+	}
+	return hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+}
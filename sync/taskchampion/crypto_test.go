@@ -0,0 +1,71 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskchampion
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCryptor(t *testing.T) {
+	clientID := "0c8bd1b0-6a4e-4c6c-a10c-7c0a1b3e2c11"
+	salt, _ := uuidBytes(clientID)
+	cryptor, err := NewCryptor(salt, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version := "5b9e2c34-0a43-4a55-9a0e-2f0a4c6a8d01"
+	data := []byte(`{"operations":[]}`)
+	sealed, err := cryptor.Seal(version, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed[0] != envelopeVersion || len(sealed) != 1+12+len(data)+16 || bytes.Contains(sealed, data) {
+		t.Errorf("Unexpected sealed data: %x", sealed)
+	}
+	if again, _ := cryptor.Seal(version, data); bytes.Equal(again, sealed) {
+		t.Error("Nonce is reused")
+	}
+
+	opened, err := cryptor.Open(version, sealed)
+	if err != nil || !bytes.Equal(opened, data) {
+		t.Errorf("Unexpected opened data: %q (%v)", opened, err)
+	}
+
+	// Data is bound to the version
+	if _, err := cryptor.Open(NilVersionID, sealed); err == nil {
+		t.Error("Data of another version was decrypted")
+	}
+	// Key depends on secret and salt
+	other, _ := NewCryptor(salt, "other")
+	if _, err := other.Open(version, sealed); err == nil {
+		t.Error("Data was decrypted with wrong secret")
+	}
+	otherSalt, _ := uuidBytes(version)
+	other, _ = NewCryptor(otherSalt, "s3cret")
+	if _, err := other.Open(version, sealed); err == nil {
+		t.Error("Data was decrypted with wrong salt")
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := cryptor.Open(version, tampered); err == nil {
+		t.Error("Tampered data was decrypted")
+	}
+	tampered = append([]byte{2}, sealed[1:]...)
+	if _, err := cryptor.Open(version, tampered); err == nil {
+		t.Error("Unknown envelope version was accepted")
+	}
+	if _, err := cryptor.Open(version, sealed[:10]); err == nil {
+		t.Error("Truncated data was accepted")
+	}
+
+	if _, err := NewCryptor(salt, ""); err == nil {
+		t.Error("Empty secret was accepted")
+	}
+	if _, err := cryptor.Seal("not-a-uuid", data); err == nil {
+		t.Error("Invalid version ID was accepted")
+	}
+}
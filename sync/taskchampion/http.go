@@ -0,0 +1,174 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Client of the remote sync server (taskchampion-sync-server).
+//
+// The protocol consists of four requests, all carrying the client ID in X-Client-Id header:
+//
+//	POST /v1/client/add-version/<parent>     -- 200 with X-Version-Id, or 409 with X-Parent-Version-Id
+//	GET  /v1/client/get-child-version/<parent> -- 200 with X-Version-Id and X-Parent-Version-Id, 404 or 410
+//	POST /v1/client/add-snapshot/<version>   -- 200
+//	GET  /v1/client/snapshot                 -- 200 with X-Version-Id, or 404
+//
+// Response to add-version may ask for a snapshot with `X-Snapshot-Request: urgency=low` or `urgency=high`.
+
+package taskchampion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Content types of request bodies.
+const (
+	HistorySegmentContentType = "application/vnd.taskchampion.history-segment"
+	SnapshotContentType       = "application/vnd.taskchampion.snapshot"
+)
+
+// Largest accepted response body.
+const maxBodySize = 100 * 1024 * 1024
+
+// HTTPServer is a Server accessed over HTTP. Data is encrypted before it is sent.
+type HTTPServer struct {
+	URL      string       // Address of the server, e.g. `https://sync.example.com`
+	ClientID string       // UUID of the version chain
+	Client   *http.Client // HTTP client, http.DefaultClient if nil
+	cryptor  *Cryptor
+}
+
+// Create client of the server at given URL. Deriving the key takes noticeable time, so the client should be reused.
+func NewHTTPServer(url, clientID, secret string) (*HTTPServer, error) {
+	salt, err := uuidBytes(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client ID: %w", err)
+	}
+	cryptor, err := NewCryptor(salt, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPServer{URL: strings.TrimSuffix(url, "/"), ClientID: clientID, cryptor: cryptor}, nil
+}
+
+func (s *HTTPServer) AddVersion(ctx context.Context, parentID string, segment []byte) (string, SnapshotUrgency, error) {
+	sealed, err := s.cryptor.Seal(parentID, segment)
+	if err != nil {
+		return "", SnapshotNone, err
+	}
+	resp, body, err := s.do(ctx, http.MethodPost, "/v1/client/add-version/"+parentID, HistorySegmentContentType, sealed)
+	if err != nil {
+		return "", SnapshotNone, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		id := resp.Header.Get("X-Version-Id")
+		if !reUUID.MatchString(id) {
+			return "", SnapshotNone, fmt.Errorf("server returned invalid version ID '%s'", id)
+		}
+		return id, parseUrgency(resp.Header.Get("X-Snapshot-Request")), nil
+	case http.StatusConflict:
+		return "", SnapshotNone, &ConflictError{ExpectedParentID: resp.Header.Get("X-Parent-Version-Id")}
+	}
+	return "", SnapshotNone, statusError(resp, body)
+}
+
+func (s *HTTPServer) GetChildVersion(ctx context.Context, parentID string) (*Version, error) {
+	resp, body, err := s.do(ctx, http.MethodGet, "/v1/client/get-child-version/"+parentID, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		segment, err := s.cryptor.Open(parentID, body)
+		if err != nil {
+			return nil, err
+		}
+		return &Version{ID: resp.Header.Get("X-Version-Id"), ParentID: parentID, HistorySegment: segment}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusGone:
+		return nil, ErrVersionGone
+	}
+	return nil, statusError(resp, body)
+}
+
+func (s *HTTPServer) AddSnapshot(ctx context.Context, versionID string, data []byte) error {
+	sealed, err := s.cryptor.Seal(versionID, data)
+	if err != nil {
+		return err
+	}
+	resp, body, err := s.do(ctx, http.MethodPost, "/v1/client/add-snapshot/"+versionID, SnapshotContentType, sealed)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp, body)
+	}
+	return nil
+}
+
+func (s *HTTPServer) GetSnapshot(ctx context.Context) (*Snapshot, error) {
+	resp, body, err := s.do(ctx, http.MethodGet, "/v1/client/snapshot", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		id := resp.Header.Get("X-Version-Id")
+		data, err := s.cryptor.Open(id, body)
+		if err != nil {
+			return nil, err
+		}
+		return &Snapshot{VersionID: id, Data: data}, nil
+	case http.StatusNotFound:
+		return nil, nil
+	}
+	return nil, statusError(resp, body)
+}
+
+// Send request and read the whole response body.
+func (s *HTTPServer) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("X-Client-Id", s.ClientID)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+func parseUrgency(header string) SnapshotUrgency {
+	switch strings.TrimSpace(header) {
+	case "urgency=low":
+		return SnapshotLow
+	case "urgency=high":
+		return SnapshotHigh
+	}
+	return SnapshotNone
+}
+
+func statusError(resp *http.Response, body []byte) error {
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 200 {
+		msg = msg[:200]
+	}
+	return fmt.Errorf("sync server: %s %s: %s", resp.Request.Method, resp.Request.URL.Path, strings.TrimSpace(resp.Status+" "+msg))
+}
@@ -0,0 +1,118 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskchampion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Check behaviour of the version chain common to all servers.
+func testServerChain(t *testing.T, server Server) (string, string) {
+	ctx := context.Background()
+
+	if v, err := server.GetChildVersion(ctx, NilVersionID); v != nil || err != nil {
+		t.Fatalf("Empty server returned version %+v (%v)", v, err)
+	}
+
+	first, _, err := server.AddVersion(ctx, NilVersionID, []byte("first"))
+	if err != nil || !reUUID.MatchString(first) {
+		t.Fatalf("Unexpected first version %q (%v)", first, err)
+	}
+	second, _, err := server.AddVersion(ctx, first, []byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The parent must be the latest version
+	_, _, err = server.AddVersion(ctx, first, []byte("conflict"))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.ExpectedParentID != second {
+		t.Errorf("Expected conflict with parent %s, got %v", second, err)
+	}
+	_, _, err = server.AddVersion(ctx, NilVersionID, []byte("conflict"))
+	if !errors.As(err, &conflict) {
+		t.Errorf("Expected conflict, got %v", err)
+	}
+
+	v, err := server.GetChildVersion(ctx, NilVersionID)
+	if err != nil || v == nil || v.ID != first || v.ParentID != NilVersionID || string(v.HistorySegment) != "first" {
+		t.Errorf("Unexpected child of nil version: %+v (%v)", v, err)
+	}
+	v, err = server.GetChildVersion(ctx, first)
+	if err != nil || v == nil || v.ID != second || string(v.HistorySegment) != "second" {
+		t.Errorf("Unexpected child of first version: %+v (%v)", v, err)
+	}
+	if v, err := server.GetChildVersion(ctx, second); v != nil || err != nil {
+		t.Errorf("Latest version has child %+v (%v)", v, err)
+	}
+	return first, second
+}
+
+func TestHTTPServer(t *testing.T) {
+	ts := NewTestServer()
+	defer ts.Close()
+	ts.SnapshotAfter = 3
+	clientID := taskwarrior.NewUUID()
+	server, err := NewHTTPServer(ts.URL, clientID, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	first, second := testServerChain(t, server)
+
+	// The server stores only encrypted data
+	ts.mu.Lock()
+	stored := ts.clients[clientID].versions[NilVersionID].segment
+	ts.mu.Unlock()
+	if bytes.Contains(stored, []byte("first")) || stored[0] != envelopeVersion {
+		t.Errorf("Server stores plain text: %q", stored)
+	}
+
+	// Other client can't read the data
+	other, _ := NewHTTPServer(ts.URL, taskwarrior.NewUUID(), "s3cret")
+	if v, err := other.GetChildVersion(ctx, NilVersionID); v != nil || err != nil {
+		t.Errorf("Client sees versions of another client: %+v (%v)", v, err)
+	}
+	wrong, _ := NewHTTPServer(ts.URL, clientID, "wrong")
+	if _, err := wrong.GetChildVersion(ctx, NilVersionID); err == nil {
+		t.Error("Version was decrypted with wrong secret")
+	}
+
+	// Snapshot is requested after SnapshotAfter versions
+	third, urgency, err := server.AddVersion(ctx, second, []byte("third"))
+	if err != nil || urgency != SnapshotHigh {
+		t.Errorf("Expected snapshot request, got %v (%v)", urgency, err)
+	}
+	if s, err := server.GetSnapshot(ctx); s != nil || err != nil {
+		t.Errorf("Unexpected snapshot %+v (%v)", s, err)
+	}
+	if err := server.AddSnapshot(ctx, second, []byte("tasks")); err != nil {
+		t.Fatal(err)
+	}
+	s, err := server.GetSnapshot(ctx)
+	if err != nil || s == nil || s.VersionID != second || string(s.Data) != "tasks" {
+		t.Errorf("Unexpected snapshot %+v (%v)", s, err)
+	}
+	if err := server.AddSnapshot(ctx, NilVersionID, []byte("tasks")); err == nil {
+		t.Error("Snapshot of unknown version was accepted")
+	}
+
+	// Versions before the snapshot are removed
+	ts.Compact(clientID)
+	if n := ts.Versions(clientID); n != 1 {
+		t.Errorf("Expected 1 version after compaction, got %d", n)
+	}
+	if _, err := server.GetChildVersion(ctx, first); !errors.Is(err, ErrVersionGone) {
+		t.Errorf("Expected gone version, got %v", err)
+	}
+	if v, err := server.GetChildVersion(ctx, second); err != nil || v == nil || v.ID != third {
+		t.Errorf("Version after snapshot was lost: %+v (%v)", v, err)
+	}
+}
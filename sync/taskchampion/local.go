@@ -0,0 +1,115 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Local server in a directory, used by taskwarrior with `sync.local.server_dir`.
+//
+// Versions are stored unencrypted in taskchampion-local-sync-server.sqlite3:
+//
+//	data (key, value)                              -- latest_version_id
+//	versions (version_id, parent_version_id, data) -- history segments
+//
+// The local server doesn't keep snapshots. Like TaskChampionBackend, it uses database/sql with
+// taskwarrior.TaskChampionDriver.
+
+package taskchampion
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Name of the local server database inside `sync.local.server_dir`.
+const LocalServerFile = "taskchampion-local-sync-server.sqlite3"
+
+// LocalServer is a Server stored in a local SQLite database.
+type LocalServer struct {
+	DB *sql.DB
+}
+
+// Open local server in given directory, creating the database if necessary.
+func OpenLocalServer(dir string) (*LocalServer, error) {
+	path := filepath.Join(dir, LocalServerFile)
+	db, err := sql.Open(taskwarrior.TaskChampionDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s (is SQLite driver '%s' imported?): %w", path, taskwarrior.TaskChampionDriver, err)
+	}
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS data (key STRING PRIMARY KEY, value STRING)`,
+		`CREATE TABLE IF NOT EXISTS versions (version_id STRING PRIMARY KEY, parent_version_id STRING, data STRING)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("can't initialize %s: %w", path, err)
+		}
+	}
+	return &LocalServer{DB: db}, nil
+}
+
+// Close the database.
+func (s *LocalServer) Close() error {
+	return s.DB.Close()
+}
+
+func (s *LocalServer) AddVersion(ctx context.Context, parentID string, segment []byte) (string, SnapshotUrgency, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", SnapshotNone, err
+	}
+	defer tx.Rollback()
+
+	latest, err := latestVersionID(ctx, tx)
+	if err != nil {
+		return "", SnapshotNone, err
+	}
+	if latest != NilVersionID && parentID != latest {
+		return "", SnapshotNone, &ConflictError{ExpectedParentID: latest}
+	}
+
+	id := taskwarrior.NewUUID()
+	_, err = tx.ExecContext(ctx, `INSERT INTO versions (version_id, parent_version_id, data) VALUES (?, ?, ?)`,
+		id, parentID, segment)
+	if err != nil {
+		return "", SnapshotNone, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO data (key, value) VALUES ('latest_version_id', ?)`, id)
+	if err != nil {
+		return "", SnapshotNone, err
+	}
+	return id, SnapshotNone, tx.Commit()
+}
+
+func (s *LocalServer) GetChildVersion(ctx context.Context, parentID string) (*Version, error) {
+	v := &Version{ParentID: parentID}
+	err := s.DB.QueryRowContext(ctx, `SELECT version_id, data FROM versions WHERE parent_version_id = ?`, parentID).
+		Scan(&v.ID, &v.HistorySegment)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Snapshots are not stored.
+func (s *LocalServer) AddSnapshot(ctx context.Context, versionID string, data []byte) error {
+	return nil
+}
+
+func (s *LocalServer) GetSnapshot(ctx context.Context) (*Snapshot, error) {
+	return nil, nil
+}
+
+func latestVersionID(ctx context.Context, tx *sql.Tx) (string, error) {
+	var id string
+	err := tx.QueryRowContext(ctx, `SELECT value FROM data WHERE key = 'latest_version_id'`).Scan(&id)
+	if err == sql.ErrNoRows {
+		return NilVersionID, nil
+	}
+	return id, err
+}
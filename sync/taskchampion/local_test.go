@@ -0,0 +1,42 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskchampion
+
+import (
+	"context"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestLocalServer(t *testing.T) {
+	dir := t.TempDir()
+	server, err := OpenLocalServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	_, second := testServerChain(t, server)
+
+	// Snapshots are not kept
+	if err := server.AddSnapshot(context.Background(), second, []byte("tasks")); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := server.GetSnapshot(context.Background()); s != nil || err != nil {
+		t.Errorf("Unexpected snapshot %+v (%v)", s, err)
+	}
+
+	// Versions are persistent
+	server.Close()
+	server, err = OpenLocalServer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var latest string
+	server.DB.QueryRow(`SELECT value FROM data WHERE key = 'latest_version_id'`).Scan(&latest)
+	if latest != second {
+		t.Errorf("Expected latest version %s, got %s", second, latest)
+	}
+}
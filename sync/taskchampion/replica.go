@@ -0,0 +1,458 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Synchronization of taskchampion.sqlite3 replica.
+//
+// Sync downloads versions following the base version stored in sync_meta, applies their operations to the tasks
+// table and uploads operations recorded since the last sync as a new version. Operations made by both sides are
+// transformed like TaskChampion does:
+//
+//   - a deletion wins over any other change of the same task;
+//   - of two updates of the same property, the one with the later timestamp wins;
+//   - creations and deletions of the same task by both sides are applied once.
+//
+// Uploaded operations are marked as synced (replicas with `synced` column) or removed from the log.
+
+package taskchampion
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Operation is a change of a task sent to the server.
+type Operation struct {
+	Kind      string    // "Create", "Delete" or "Update"
+	UUID      string    // Changed task
+	Property  string    // Changed attribute of Update
+	Value     *string   // New value of Update, nil when the attribute is removed
+	Timestamp time.Time // Time of Update
+}
+
+type operationFields struct {
+	UUID      string  `json:"uuid"`
+	Property  string  `json:"property,omitempty"`
+	Value     *string `json:"value"`
+	Timestamp string  `json:"timestamp,omitempty"`
+}
+
+// Encode operation like TaskChampion, e.g. `{"Create":{"uuid":"..."}}`.
+func (op Operation) MarshalJSON() ([]byte, error) {
+	switch op.Kind {
+	case "Create", "Delete":
+		return json.Marshal(map[string]map[string]string{op.Kind: {"uuid": op.UUID}})
+	case "Update":
+		return json.Marshal(map[string]operationFields{op.Kind: {
+			UUID:      op.UUID,
+			Property:  op.Property,
+			Value:     op.Value,
+			Timestamp: op.Timestamp.UTC().Format(time.RFC3339Nano),
+		}})
+	}
+	return nil, fmt.Errorf("unknown operation '%s'", op.Kind)
+}
+
+// Decode operation. Fields of replica operations that are not sent to the server, like old_value, are ignored.
+func (op *Operation) UnmarshalJSON(data []byte) error {
+	var raw map[string]operationFields
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 1 {
+		return fmt.Errorf("invalid operation %s", data)
+	}
+	for kind, fields := range raw {
+		*op = Operation{Kind: kind, UUID: fields.UUID, Property: fields.Property, Value: fields.Value}
+		switch kind {
+		case "Create", "Delete":
+		case "Update":
+			t, err := time.Parse(time.RFC3339Nano, fields.Timestamp)
+			if err != nil {
+				return fmt.Errorf("invalid timestamp of operation: %w", err)
+			}
+			op.Timestamp = t
+		default:
+			return fmt.Errorf("unknown operation '%s'", kind)
+		}
+	}
+	return nil
+}
+
+// Content of a version.
+type historySegment struct {
+	Operations []Operation `json:"operations"`
+}
+
+// Replica is taskwarrior 3 task database synchronized with a server.
+type Replica struct {
+	DB             *sql.DB
+	AvoidSnapshots bool // Ignore low urgency snapshot requests
+}
+
+// Open replica in data directory of given configuration.
+func OpenReplica(config *taskwarrior.TaskRC) (*Replica, error) {
+	backend, err := taskwarrior.NewTaskChampionBackend(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Replica{DB: backend.DB}, nil
+}
+
+// Close the replica.
+func (r *Replica) Close() error {
+	return r.DB.Close()
+}
+
+// Synchronize the replica with the server. The replica is not changed if the synchronization fails.
+func (r *Replica) Sync(ctx context.Context, server Server) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	base, err := baseVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+	local, lastOp, err := localOperations(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	var urgency SnapshotUrgency
+	touched := map[string]bool{}
+	for {
+		// Apply versions uploaded by other replicas
+		for {
+			v, err := server.GetChildVersion(ctx, base)
+			if errors.Is(err, ErrVersionGone) && base == NilVersionID {
+				snapshot, err := server.GetSnapshot(ctx)
+				if err != nil {
+					return err
+				}
+				if snapshot == nil {
+					return errors.New("server has neither the first version nor a snapshot")
+				}
+				if err := applySnapshot(ctx, tx, snapshot.Data, touched); err != nil {
+					return err
+				}
+				base = snapshot.VersionID
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				break
+			}
+			var segment historySegment
+			if err := json.Unmarshal(v.HistorySegment, &segment); err != nil {
+				return fmt.Errorf("invalid history segment of version %s: %w", v.ID, err)
+			}
+			for _, op := range segment.Operations {
+				var remote *Operation
+				remote, local = transform(op, local)
+				if remote == nil {
+					continue
+				}
+				if err := applyOperation(ctx, tx, *remote); err != nil {
+					return err
+				}
+				touched[remote.UUID] = true
+			}
+			base = v.ID
+		}
+
+		if len(local) == 0 {
+			break
+		}
+		segment, err := json.Marshal(historySegment{Operations: local})
+		if err != nil {
+			return err
+		}
+		id, u, err := server.AddVersion(ctx, base, segment)
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			// Another replica was faster, download its version first
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		base, urgency, local = id, u, nil
+		break
+	}
+
+	if err := setBaseVersion(ctx, tx, base); err != nil {
+		return err
+	}
+	if err := markSynced(ctx, tx, lastOp); err != nil {
+		return err
+	}
+	if err := updateWorkingSet(ctx, tx, touched); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if urgency == SnapshotHigh || (urgency == SnapshotLow && !r.AvoidSnapshots) {
+		snapshot, err := makeSnapshot(ctx, r.DB)
+		if err != nil {
+			return err
+		}
+		return server.AddSnapshot(ctx, base, snapshot)
+	}
+	return nil
+}
+
+// Transform remote operation against local operations that were not uploaded yet. Returns the remote operation to
+// apply, or nil if it loses, and the local operations still to upload.
+func transform(remote Operation, local []Operation) (*Operation, []Operation) {
+	result := &remote
+	var kept []Operation
+	for _, op := range local {
+		if result == nil || op.UUID != remote.UUID {
+			kept = append(kept, op)
+			continue
+		}
+		switch {
+		case remote.Kind == op.Kind && remote.Kind != "Update":
+			// Both sides created or deleted the task
+			result = nil
+		case remote.Kind == "Delete":
+			// Local change of the deleted task is dropped
+		case op.Kind == "Delete":
+			result = nil
+			kept = append(kept, op)
+		case remote.Kind == "Update" && op.Kind == "Update" && remote.Property == op.Property:
+			if op.Timestamp.After(remote.Timestamp) {
+				result = nil
+				kept = append(kept, op)
+			}
+		default:
+			kept = append(kept, op)
+		}
+	}
+	return result, kept
+}
+
+// Apply operation to the tasks table.
+func applyOperation(ctx context.Context, tx *sql.Tx, op Operation) error {
+	switch op.Kind {
+	case "Create":
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO tasks (uuid, data) VALUES (?, '{}')`, op.UUID)
+		return err
+	case "Delete":
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE uuid = ?`, op.UUID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM working_set WHERE uuid = ?`, op.UUID)
+		return err
+	}
+
+	var data string
+	err := tx.QueryRowContext(ctx, `SELECT data FROM tasks WHERE uuid = ?`, op.UUID).Scan(&data)
+	if err == sql.ErrNoRows {
+		// Update of a task deleted locally
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	attrs := map[string]string{}
+	if err := json.Unmarshal([]byte(data), &attrs); err != nil {
+		return fmt.Errorf("task %s: %w", op.UUID, err)
+	}
+	if op.Value == nil {
+		delete(attrs, op.Property)
+	} else {
+		attrs[op.Property] = *op.Value
+	}
+	return saveTask(ctx, tx, op.UUID, attrs)
+}
+
+func saveTask(ctx context.Context, tx *sql.Tx, uuid string, attrs map[string]string) error {
+	buf, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO tasks (uuid, data) VALUES (?, ?)`, uuid, string(buf))
+	return err
+}
+
+// Add pending and waiting tasks among given ones to the working set.
+func updateWorkingSet(ctx context.Context, tx *sql.Tx, uuids map[string]bool) error {
+	sorted := make([]string, 0, len(uuids))
+	for uuid := range uuids {
+		sorted = append(sorted, uuid)
+	}
+	sort.Strings(sorted)
+
+	for _, uuid := range sorted {
+		var data string
+		err := tx.QueryRowContext(ctx, `SELECT data FROM tasks WHERE uuid = ?`, uuid).Scan(&data)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		var attrs map[string]string
+		if err := json.Unmarshal([]byte(data), &attrs); err != nil {
+			return fmt.Errorf("task %s: %w", uuid, err)
+		}
+		if attrs["status"] != "pending" && attrs["status"] != "waiting" {
+			continue
+		}
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM working_set WHERE uuid = ?`, uuid).Scan(&n); err != nil {
+			return err
+		}
+		if n == 0 {
+			_, err = tx.ExecContext(ctx, `INSERT INTO working_set (id, uuid) `+
+				`VALUES ((SELECT COALESCE(MAX(id), 0) + 1 FROM working_set), ?)`, uuid)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Return operations recorded since the last sync and ID of the last of them.
+func localOperations(ctx context.Context, tx *sql.Tx) ([]Operation, int64, error) {
+	query := `SELECT id, data FROM operations ORDER BY id`
+	if synced, err := hasSyncedColumn(ctx, tx); err != nil {
+		return nil, 0, err
+	} else if synced {
+		query = `SELECT id, data FROM operations WHERE NOT synced ORDER BY id`
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var ops []Operation
+	var last int64
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&last, &data); err != nil {
+			return nil, 0, err
+		}
+		if !bytes.HasPrefix(bytes.TrimSpace([]byte(data)), []byte("{")) {
+			// UndoPoint is not synchronized
+			continue
+		}
+		var op Operation
+		if err := json.Unmarshal([]byte(data), &op); err != nil {
+			return nil, 0, fmt.Errorf("operation %d: %w", last, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, last, rows.Err()
+}
+
+// Mark operations up to given ID as uploaded.
+func markSynced(ctx context.Context, tx *sql.Tx, last int64) error {
+	if last == 0 {
+		return nil
+	}
+	synced, err := hasSyncedColumn(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if synced {
+		_, err = tx.ExecContext(ctx, `UPDATE operations SET synced = 1 WHERE id <= ?`, last)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM operations WHERE id <= ?`, last)
+	}
+	return err
+}
+
+// Check whether the operations table has `synced` column of TaskChampion 0.7 and newer.
+func hasSyncedColumn(ctx context.Context, tx *sql.Tx) (bool, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('operations') WHERE name = 'synced'`).Scan(&n)
+	return n > 0, err
+}
+
+func baseVersion(ctx context.Context, tx *sql.Tx) (string, error) {
+	var id string
+	err := tx.QueryRowContext(ctx, `SELECT value FROM sync_meta WHERE key = 'base_version'`).Scan(&id)
+	if err == sql.ErrNoRows {
+		return NilVersionID, nil
+	}
+	return id, err
+}
+
+func setBaseVersion(ctx context.Context, tx *sql.Tx, id string) error {
+	_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO sync_meta (key, value) VALUES ('base_version', ?)`, id)
+	return err
+}
+
+// Return snapshot of all tasks: zlib-compressed JSON object mapping UUIDs to task attributes.
+func makeSnapshot(ctx context.Context, db *sql.DB) ([]byte, error) {
+	rows, err := db.QueryContext(ctx, `SELECT uuid, data FROM tasks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := map[string]json.RawMessage{}
+	for rows.Next() {
+		var uuid, data string
+		if err := rows.Scan(&uuid, &data); err != nil {
+			return nil, err
+		}
+		tasks[uuid] = json.RawMessage(data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Store tasks from the snapshot.
+func applySnapshot(ctx context.Context, tx *sql.Tx, snapshot []byte, touched map[string]bool) error {
+	r, err := zlib.NewReader(bytes.NewReader(snapshot))
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	var tasks map[string]map[string]string
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	for uuid, attrs := range tasks {
+		if err := saveTask(ctx, tx, uuid, attrs); err != nil {
+			return err
+		}
+		touched[uuid] = true
+	}
+	return nil
+}
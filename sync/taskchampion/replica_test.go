@@ -0,0 +1,284 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskchampion
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+	_ "modernc.org/sqlite"
+)
+
+// Helper that creates empty replica with taskwarrior 3 schema and backend writing to it.
+func newTestReplica(t *testing.T, now *time.Time) (*Replica, *taskwarrior.TaskChampionBackend) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dir, taskwarrior.TaskChampionFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := []string{
+		`CREATE TABLE operations (id INTEGER PRIMARY KEY AUTOINCREMENT, data STRING)`,
+		`CREATE TABLE sync_meta (key STRING PRIMARY KEY, value STRING)`,
+		`CREATE TABLE tasks (uuid STRING PRIMARY KEY, data STRING)`,
+		`CREATE TABLE working_set (id INTEGER PRIMARY KEY, uuid STRING)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() { db.Close() })
+	backend := &taskwarrior.TaskChampionBackend{DB: db, Now: func() time.Time { return *now }}
+	return &Replica{DB: db}, backend
+}
+
+func mustSync(t *testing.T, replica *Replica, server Server) {
+	t.Helper()
+	if err := replica.Sync(context.Background(), server); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+}
+
+func mustGet(t *testing.T, backend *taskwarrior.TaskChampionBackend, uuid string) *taskwarrior.Task {
+	t.Helper()
+	task, err := backend.Get(context.Background(), uuid)
+	if err != nil {
+		t.Fatalf("Task %s is missing: %v", uuid, err)
+	}
+	return task
+}
+
+func TestReplica_Sync(t *testing.T) {
+	ts := NewTestServer()
+	defer ts.Close()
+	server, err := NewHTTPServer(ts.URL, taskwarrior.NewUUID(), "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	laptop, laptopTasks := newTestReplica(t, &now)
+	phone, phoneTasks := newTestReplica(t, &now)
+
+	// Task created on the laptop appears on the phone
+	milk, err := laptopTasks.Add(ctx, &taskwarrior.Task{Description: "Buy milk", Tags: []string{"shop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSync(t, laptop, server)
+	mustSync(t, phone, server)
+	got := mustGet(t, phoneTasks, milk.Uuid)
+	if got.Description != "Buy milk" || got.Id != 1 || len(got.Tags) != 1 || got.Status != "pending" {
+		t.Errorf("Unexpected synced task: %+v", got)
+	}
+
+	// Uploaded operations are removed and the base version is stored
+	var ops int
+	laptop.DB.QueryRow(`SELECT COUNT(*) FROM operations`).Scan(&ops)
+	var base string
+	laptop.DB.QueryRow(`SELECT value FROM sync_meta WHERE key = 'base_version'`).Scan(&base)
+	if ops != 0 || !reUUID.MatchString(base) || base == NilVersionID {
+		t.Errorf("Unexpected replica state after sync: %d operations, base %s", ops, base)
+	}
+
+	// Concurrent changes of different properties are merged
+	now = now.Add(time.Minute)
+	task := mustGet(t, phoneTasks, milk.Uuid)
+	task.Priority = "H"
+	phoneTasks.Modify(ctx, task)
+	now = now.Add(time.Minute)
+	task = mustGet(t, laptopTasks, milk.Uuid)
+	task.Project = "home"
+	laptopTasks.Modify(ctx, task)
+
+	mustSync(t, phone, server)
+	mustSync(t, laptop, server) // Pulls the phone version before the upload
+	mustSync(t, phone, server)
+	for name, backend := range map[string]*taskwarrior.TaskChampionBackend{"laptop": laptopTasks, "phone": phoneTasks} {
+		got := mustGet(t, backend, milk.Uuid)
+		if got.Priority != "H" || got.Project != "home" {
+			t.Errorf("%s: changes were not merged: %+v", name, got)
+		}
+	}
+
+	// Of two changes of the same property the later one wins, regardless of sync order
+	now = now.Add(time.Minute)
+	task = mustGet(t, laptopTasks, milk.Uuid)
+	task.Description = "Buy milk (laptop)"
+	laptopTasks.Modify(ctx, task)
+	now = now.Add(time.Minute)
+	task = mustGet(t, phoneTasks, milk.Uuid)
+	task.Description = "Buy milk (phone)"
+	phoneTasks.Modify(ctx, task)
+
+	mustSync(t, phone, server)
+	mustSync(t, laptop, server)
+	mustSync(t, phone, server)
+	for name, backend := range map[string]*taskwarrior.TaskChampionBackend{"laptop": laptopTasks, "phone": phoneTasks} {
+		if got := mustGet(t, backend, milk.Uuid); got.Description != "Buy milk (phone)" {
+			t.Errorf("%s: expected the later change, got %q", name, got.Description)
+		}
+	}
+
+	// Sync without changes doesn't add versions
+	versions := ts.Versions(server.ClientID)
+	mustSync(t, laptop, server)
+	if ts.Versions(server.ClientID) != versions {
+		t.Error("Sync without changes added a version")
+	}
+}
+
+func TestReplica_SyncSnapshot(t *testing.T) {
+	ts := NewTestServer()
+	defer ts.Close()
+	ts.SnapshotAfter = 1
+	server, err := NewHTTPServer(ts.URL, taskwarrior.NewUUID(), "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	laptop, laptopTasks := newTestReplica(t, &now)
+
+	first, _ := laptopTasks.Add(ctx, &taskwarrior.Task{Description: "First"})
+	mustSync(t, laptop, server)
+	second, _ := laptopTasks.Add(ctx, &taskwarrior.Task{Description: "Second"})
+	mustSync(t, laptop, server)
+
+	// The server dropped versions included in the snapshot, new replica starts from it
+	ts.Compact(server.ClientID)
+	if ts.Versions(server.ClientID) != 0 {
+		t.Fatalf("Versions were not compacted: %d", ts.Versions(server.ClientID))
+	}
+	phone, phoneTasks := newTestReplica(t, &now)
+	mustSync(t, phone, server)
+	for _, uuid := range []string{first.Uuid, second.Uuid} {
+		if got := mustGet(t, phoneTasks, uuid); got.Id == 0 {
+			t.Errorf("Task from snapshot is not in the working set: %+v", got)
+		}
+	}
+
+	// And continues with the versions after it
+	third, _ := laptopTasks.Add(ctx, &taskwarrior.Task{Description: "Third"})
+	mustSync(t, laptop, server)
+	mustSync(t, phone, server)
+	mustGet(t, phoneTasks, third.Uuid)
+}
+
+func TestReplica_SyncLocal(t *testing.T) {
+	server, err := OpenLocalServer(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	ctx := context.Background()
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	laptop, laptopTasks := newTestReplica(t, &now)
+	phone, phoneTasks := newTestReplica(t, &now)
+
+	milk, _ := laptopTasks.Add(ctx, &taskwarrior.Task{Description: "Buy milk"})
+	mustSync(t, laptop, server)
+	mustSync(t, phone, server)
+	if err := phoneTasks.Delete(ctx, milk.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	mustSync(t, phone, server)
+	mustSync(t, laptop, server)
+	if got := mustGet(t, laptopTasks, milk.Uuid); got.Status != "deleted" {
+		t.Errorf("Deletion was not synced: %+v", got)
+	}
+}
+
+func TestReplica_SyncFailure(t *testing.T) {
+	ts := NewTestServer()
+	server, err := NewHTTPServer(ts.URL, taskwarrior.NewUUID(), "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	replica, tasks := newTestReplica(t, &now)
+	tasks.Add(context.Background(), &taskwarrior.Task{Description: "Buy milk"})
+
+	if err := replica.Sync(context.Background(), server); err == nil {
+		t.Fatal("Sync with stopped server succeeded")
+	}
+	var ops int
+	replica.DB.QueryRow(`SELECT COUNT(*) FROM operations`).Scan(&ops)
+	if ops == 0 {
+		t.Error("Operations were lost after failed sync")
+	}
+}
+
+func TestTransform(t *testing.T) {
+	t1 := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Second)
+	value := "x"
+	update := func(uuid, property string, ts time.Time) Operation {
+		return Operation{Kind: "Update", UUID: uuid, Property: property, Value: &value, Timestamp: ts}
+	}
+
+	tests := []struct {
+		name       string
+		remote     Operation
+		local      []Operation
+		applied    bool
+		localAfter int
+	}{
+		{"other task", update("a", "p", t1), []Operation{update("b", "p", t2)}, true, 1},
+		{"other property", update("a", "p", t1), []Operation{update("a", "q", t2)}, true, 1},
+		{"later local update", update("a", "p", t1), []Operation{update("a", "p", t2)}, false, 1},
+		{"later remote update", update("a", "p", t2), []Operation{update("a", "p", t1)}, true, 0},
+		{"remote delete", Operation{Kind: "Delete", UUID: "a"}, []Operation{update("a", "p", t2), update("a", "q", t2)}, true, 0},
+		{"local delete", update("a", "p", t2), []Operation{{Kind: "Delete", UUID: "a"}}, false, 1},
+		{"both create", Operation{Kind: "Create", UUID: "a"}, []Operation{{Kind: "Create", UUID: "a"}, update("a", "p", t1)}, false, 1},
+	}
+	for _, test := range tests {
+		remote, local := transform(test.remote, test.local)
+		if (remote != nil) != test.applied || len(local) != test.localAfter {
+			t.Errorf("%s: remote %+v, local %+v", test.name, remote, local)
+		}
+	}
+}
+
+func TestOperation_JSON(t *testing.T) {
+	value := "Buy milk"
+	ops := []Operation{
+		{Kind: "Create", UUID: "a"},
+		{Kind: "Update", UUID: "a", Property: "description", Value: &value, Timestamp: time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)},
+		{Kind: "Update", UUID: "a", Property: "priority", Timestamp: time.Date(2026, 2, 10, 12, 0, 0, 5, time.UTC)},
+		{Kind: "Delete", UUID: "a"},
+	}
+	expected := `{"operations":[{"Create":{"uuid":"a"}},` +
+		`{"Update":{"uuid":"a","property":"description","value":"Buy milk","timestamp":"2026-02-10T12:00:00Z"}},` +
+		`{"Update":{"uuid":"a","property":"priority","value":null,"timestamp":"2026-02-10T12:00:00.000000005Z"}},` +
+		`{"Delete":{"uuid":"a"}}]}`
+	buf, err := json.Marshal(historySegment{Operations: ops})
+	if err != nil || string(buf) != expected {
+		t.Errorf("Unexpected history segment: %s (%v)", buf, err)
+	}
+
+	var segment historySegment
+	if err := json.Unmarshal(buf, &segment); err != nil || len(segment.Operations) != 4 {
+		t.Fatalf("Can't decode history segment: %v", err)
+	}
+	if got := segment.Operations[1]; *got.Value != value || !got.Timestamp.Equal(ops[1].Timestamp) {
+		t.Errorf("Unexpected decoded operation: %+v", got)
+	}
+
+	// Replica operations carry old values that are not synchronized
+	var op Operation
+	err = json.Unmarshal([]byte(`{"Delete":{"uuid":"a","old_task":{"description":"x"}}}`), &op)
+	if err != nil || op.Kind != "Delete" || op.UUID != "a" {
+		t.Errorf("Unexpected replica operation: %+v (%v)", op, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Undo":{"uuid":"a"}}`), &op); err == nil {
+		t.Error("Unknown operation was accepted")
+	}
+}
@@ -0,0 +1,131 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Package taskchampion implements TaskChampion sync used by taskwarrior 3.
+//
+// The server stores a chain of versions. Each version contains a history segment, the list of operations made by
+// one replica, and refers to its parent version. Replicas download versions following the one they synced last,
+// apply their operations and upload local operations as a new version. A version is accepted only if its parent is
+// the latest version, otherwise the replica downloads new versions and tries again. Snapshots of all tasks let new
+// replicas start without downloading the whole chain.
+//
+// The remote server never sees tasks in plain text: history segments and snapshots are encrypted with
+// ChaCha20-Poly1305 and a key derived from `sync.encryption_secret`. The local server in `sync.local.server_dir` stores
+// versions unencrypted, like taskwarrior does.
+//
+// TestServer is an in-memory HTTP server for tests.
+package taskchampion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// ID of the parent of the first version.
+const NilVersionID = "00000000-0000-0000-0000-000000000000"
+
+// Returned by Server.GetChildVersion when the server no longer has the parent version.
+var ErrVersionGone = errors.New("version is no longer available on the server")
+
+// ConflictError is returned by Server.AddVersion when the parent of the new version is not the latest version.
+type ConflictError struct {
+	ExpectedParentID string // Latest version on the server
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict: expected parent %s", e.ExpectedParentID)
+}
+
+// SnapshotUrgency is the server request to upload a snapshot after adding a version.
+type SnapshotUrgency int
+
+const (
+	SnapshotNone SnapshotUrgency = iota
+	SnapshotLow
+	SnapshotHigh
+)
+
+// Version is a single element of the version chain.
+type Version struct {
+	ID             string
+	ParentID       string
+	HistorySegment []byte // Decrypted history segment
+}
+
+// Snapshot contains all tasks as of the version.
+type Snapshot struct {
+	VersionID string
+	Data      []byte // Decrypted snapshot
+}
+
+// Server stores the version chain of one client.
+type Server interface {
+	// Add version after given parent and return its ID. Returns *ConflictError if the parent is not the latest version.
+	AddVersion(ctx context.Context, parentID string, segment []byte) (string, SnapshotUrgency, error)
+
+	// Return version following the parent, or nil if the parent is the latest version. Returns ErrVersionGone if the
+	// parent was removed from the server.
+	GetChildVersion(ctx context.Context, parentID string) (*Version, error)
+
+	// Store snapshot of tasks as of given version.
+	AddSnapshot(ctx context.Context, versionID string, data []byte) error
+
+	// Return the latest snapshot or nil if there is none.
+	GetSnapshot(ctx context.Context) (*Snapshot, error)
+}
+
+// Config contains sync settings of taskwarrior 3.
+type Config struct {
+	LocalDir         string // Directory of the local server, `sync.local.server_dir`
+	ServerURL        string // Address of the remote server, `sync.server.url`
+	ClientID         string // UUID identifying the version chain on the server, `sync.server.client_id`
+	EncryptionSecret string // Secret used to encrypt data for the remote server, `sync.encryption_secret`
+}
+
+var reUUID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Read sync settings from taskrc. `sync.server.origin` of taskwarrior 3.0 is accepted instead of `sync.server.url`.
+func ConfigFromTaskRC(rc *taskwarrior.TaskRC) (*Config, error) {
+	config := &Config{
+		LocalDir:         taskwarrior.PathExpandTilda(rc.Get("sync.local.server_dir")),
+		ServerURL:        rc.Get("sync.server.url"),
+		ClientID:         rc.Get("sync.server.client_id"),
+		EncryptionSecret: rc.Get("sync.encryption_secret"),
+	}
+	if config.ServerURL == "" {
+		config.ServerURL = rc.Get("sync.server.origin")
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	switch {
+	case c.LocalDir != "":
+		return nil
+	case c.ServerURL == "":
+		return errors.New("neither sync.local.server_dir nor sync.server.url is set")
+	case !reUUID.MatchString(c.ClientID):
+		return fmt.Errorf("sync.server.client_id must be a UUID, got '%s'", c.ClientID)
+	case c.EncryptionSecret == "":
+		return errors.New("sync.encryption_secret is not set")
+	}
+	return nil
+}
+
+// Open server described by the settings: the local server if LocalDir is set, the remote one otherwise.
+func OpenServer(config *Config) (Server, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if config.LocalDir != "" {
+		return OpenLocalServer(config.LocalDir)
+	}
+	return NewHTTPServer(config.ServerURL, config.ClientID, config.EncryptionSecret)
+}
@@ -0,0 +1,74 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskchampion
+
+import (
+	"testing"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func TestConfigFromTaskRC(t *testing.T) {
+	tests := map[string]Config{
+		"sync.local.server_dir=/tmp/sync": {LocalDir: "/tmp/sync"},
+		"sync.server.url=https://sync.example.com\n" +
+			"sync.server.client_id=0c8bd1b0-6a4e-4c6c-a10c-7c0a1b3e2c11\n" +
+			"sync.encryption_secret=s3cret": {
+			ServerURL: "https://sync.example.com", ClientID: "0c8bd1b0-6a4e-4c6c-a10c-7c0a1b3e2c11", EncryptionSecret: "s3cret",
+		},
+		"sync.server.origin=http://localhost:8080\n" +
+			"sync.server.client_id=0c8bd1b0-6a4e-4c6c-a10c-7c0a1b3e2c11\n" +
+			"sync.encryption_secret=s3cret": {
+			ServerURL: "http://localhost:8080", ClientID: "0c8bd1b0-6a4e-4c6c-a10c-7c0a1b3e2c11", EncryptionSecret: "s3cret",
+		},
+	}
+	for buf, expected := range tests {
+		rc := &taskwarrior.TaskRC{}
+		if err := rc.MapTaskRC(buf); err != nil {
+			t.Fatal(err)
+		}
+		config, err := ConfigFromTaskRC(rc)
+		if err != nil || *config != expected {
+			t.Errorf("%q: expected %+v, got %+v (%v)", buf, expected, config, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"sync.server.url=https://sync.example.com\nsync.encryption_secret=s3cret",
+		"sync.server.url=https://sync.example.com\nsync.server.client_id=client\nsync.encryption_secret=s3cret",
+		"sync.server.url=https://sync.example.com\nsync.server.client_id=0c8bd1b0-6a4e-4c6c-a10c-7c0a1b3e2c11",
+	}
+	for _, buf := range invalid {
+		rc := &taskwarrior.TaskRC{}
+		rc.MapTaskRC(buf)
+		if _, err := ConfigFromTaskRC(rc); err == nil {
+			t.Errorf("%q: invalid configuration was accepted", buf)
+		}
+	}
+}
+
+func TestOpenServer(t *testing.T) {
+	server, err := OpenServer(&Config{LocalDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local, ok := server.(*LocalServer); !ok {
+		t.Errorf("Expected local server, got %T", server)
+	} else {
+		local.Close()
+	}
+
+	server, err = OpenServer(&Config{ServerURL: "http://localhost:8080/", ClientID: taskwarrior.NewUUID(), EncryptionSecret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote, ok := server.(*HTTPServer); !ok || remote.URL != "http://localhost:8080" {
+		t.Errorf("Expected HTTP server, got %#v", server)
+	}
+
+	if _, err := OpenServer(&Config{}); err == nil {
+		t.Error("Empty configuration was accepted")
+	}
+}
@@ -0,0 +1,178 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// In-memory sync server for tests.
+//
+// TestServer implements the HTTP protocol of taskchampion-sync-server on a local port. Like the real server, it only
+// stores encrypted data and never sees tasks.
+
+package taskchampion
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// TestServer is an in-memory sync server listening on a local port.
+type TestServer struct {
+	URL           string // Base URL of the server
+	SnapshotAfter int    // Request a snapshot when this many versions were added since the last one, 0 never
+
+	server  *httptest.Server
+	mu      sync.Mutex
+	clients map[string]*testClient
+}
+
+// Version chain of one client.
+type testClient struct {
+	latest        string
+	versions      map[string]testVersion // By parent ID
+	snapshotID    string
+	snapshot      []byte
+	sinceSnapshot int
+}
+
+type testVersion struct {
+	id      string
+	segment []byte
+}
+
+// Start server. The server is stopped with Close.
+func NewTestServer() *TestServer {
+	s := &TestServer{clients: map[string]*testClient{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	return s
+}
+
+// Stop the server.
+func (s *TestServer) Close() {
+	s.server.Close()
+}
+
+// Remove versions older than the latest snapshot of the client, like the real server does to save space. Replicas
+// that synced before the snapshot get ErrVersionGone and new replicas start from the snapshot.
+func (s *TestServer) Compact(clientID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.clients[clientID]
+	if c == nil || c.snapshotID == "" {
+		return
+	}
+	for parent := NilVersionID; ; {
+		v, ok := c.versions[parent]
+		if !ok {
+			break
+		}
+		delete(c.versions, parent)
+		if v.id == c.snapshotID {
+			break
+		}
+		parent = v.id
+	}
+}
+
+// Return number of versions stored for the client.
+func (s *TestServer) Versions(clientID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.clients[clientID]; c != nil {
+		return len(c.versions)
+	}
+	return 0
+}
+
+func (s *TestServer) handle(w http.ResponseWriter, r *http.Request) {
+	clientID := r.Header.Get("X-Client-Id")
+	if !reUUID.MatchString(clientID) {
+		http.Error(w, "missing or invalid X-Client-Id", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.clients[clientID]
+	if c == nil {
+		c = &testClient{latest: NilVersionID, versions: map[string]testVersion{}}
+		s.clients[clientID] = c
+	}
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/v1/client/add-version/"):
+		if r.Header.Get("Content-Type") != HistorySegmentContentType {
+			http.Error(w, "unexpected content type", http.StatusBadRequest)
+			return
+		}
+		parent := strings.TrimPrefix(path, "/v1/client/add-version/")
+		if c.latest != NilVersionID && parent != c.latest {
+			w.Header().Set("X-Parent-Version-Id", c.latest)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		id := taskwarrior.NewUUID()
+		c.versions[parent] = testVersion{id: id, segment: body}
+		c.latest = id
+		c.sinceSnapshot++
+		w.Header().Set("X-Version-Id", id)
+		if s.SnapshotAfter > 0 && c.sinceSnapshot >= s.SnapshotAfter {
+			w.Header().Set("X-Snapshot-Request", "urgency=high")
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v1/client/get-child-version/"):
+		parent := strings.TrimPrefix(path, "/v1/client/get-child-version/")
+		v, ok := c.versions[parent]
+		switch {
+		case ok:
+			w.Header().Set("Content-Type", HistorySegmentContentType)
+			w.Header().Set("X-Version-Id", v.id)
+			w.Header().Set("X-Parent-Version-Id", parent)
+			w.Write(v.segment)
+		case parent == c.latest:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusGone)
+		}
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/v1/client/add-snapshot/"):
+		id := strings.TrimPrefix(path, "/v1/client/add-snapshot/")
+		if !s.knownVersion(c, id) {
+			http.Error(w, "unknown version", http.StatusBadRequest)
+			return
+		}
+		c.snapshotID, c.snapshot, c.sinceSnapshot = id, body, 0
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodGet && path == "/v1/client/snapshot":
+		if c.snapshotID == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", SnapshotContentType)
+		w.Header().Set("X-Version-Id", c.snapshotID)
+		w.Write(c.snapshot)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *TestServer) knownVersion(c *testClient, id string) bool {
+	for _, v := range c.versions {
+		if v.id == id {
+			return true
+		}
+	}
+	return false
+}
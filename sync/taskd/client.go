@@ -0,0 +1,197 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Taskd sync client.
+
+package taskd
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Client name sent to the server when Client.Name is empty.
+const DefaultClientName = "go-taskwarrior"
+
+// Client talks to taskd server. Each request uses its own connection, like taskwarrior does.
+type Client struct {
+	Config *Config
+	Name   string     // Client name sent in requests, DefaultClientName if empty
+	Dialer net.Dialer // Dialer of TCP connections
+}
+
+// Create client for given connection settings.
+func NewClient(config *Config) *Client {
+	return &Client{Config: config}
+}
+
+// SyncResult is the server response to sync request.
+type SyncResult struct {
+	Tasks   []taskwarrior.Task // Tasks changed by other clients since the previous sync
+	SyncKey string             // Key to send with the next sync
+	Code    int                // Response code: 200 when tasks were received, 201 when there are no changes
+	Status  string             // Status message
+}
+
+// Send local changes to the server and receive changes made by other clients since the sync identified by syncKey.
+// Empty syncKey requests all tasks of the account. Error responses are returned as *ResponseError.
+func (c *Client) Sync(ctx context.Context, syncKey string, tasks []taskwarrior.Task) (*SyncResult, error) {
+	payload, err := composePayload(tasks, syncKey)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.request(ctx, "sync", payload)
+	if err != nil {
+		return nil, err
+	}
+	result := &SyncResult{Code: responseCode(resp), Status: resp.Header["status"]}
+	if result.Code != 200 && result.Code != 201 {
+		return nil, &ResponseError{Code: result.Code, Status: result.Status}
+	}
+	result.Tasks, result.SyncKey, err = parsePayload(resp.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if result.SyncKey == "" {
+		result.SyncKey = syncKey
+	}
+	return result, nil
+}
+
+// Send request of given type and read the response.
+func (c *Client) request(ctx context.Context, typ, payload string) (*Message, error) {
+	if c.Config == nil {
+		return nil, errors.New("taskd client is not configured")
+	}
+	raw, err := c.Dialer.DialContext(ctx, "tcp", c.Config.Server)
+	if err != nil {
+		return nil, err
+	}
+	conn := tls.Client(raw, c.Config.TLS)
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	name := c.Name
+	if name == "" {
+		name = DefaultClientName
+	}
+	req := &Message{
+		Header: map[string]string{
+			"type":     typ,
+			"org":      c.Config.Credentials.Org,
+			"user":     c.Config.Credentials.User,
+			"key":      c.Config.Credentials.Key,
+			"protocol": ProtocolVersion,
+			"client":   name,
+		},
+		Payload: payload,
+	}
+	if err := WriteMessage(conn, req); err != nil {
+		return nil, contextError(ctx, err)
+	}
+	resp, err := ReadMessage(bufio.NewReader(conn))
+	if err != nil {
+		return nil, contextError(ctx, err)
+	}
+	return resp, nil
+}
+
+// Prefer context error over the error of closed connection.
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Synchronize taskwarrior 2.x data directory like `task sync` does. Tasks and the sync key are read from
+// backlog.data, tasks received from the server replace local tasks with the same UUID in pending.data and
+// completed.data, and the sent lines of backlog.data are replaced with the new sync key. Data files are changed with
+// DataFileBackend.Update, so they are locked like taskwarrior locks them, and changes recorded during the request are
+// kept for the next sync.
+func (c *Client) SyncDataDir(ctx context.Context, dir string) (*SyncResult, error) {
+	backend := &taskwarrior.DataFileBackend{Dir: dir}
+	var sent []string
+	err := backend.Update(ctx, func(files *taskwarrior.DataFiles) error {
+		sent = files.Backlog
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	local, key, err := parsePayload(strings.Join(sent, "\n"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, taskwarrior.BacklogDataFile), err)
+	}
+	result, err := c.Sync(ctx, key, local)
+	if err != nil {
+		return nil, err
+	}
+
+	err = backend.Update(ctx, func(files *taskwarrior.DataFiles) error {
+		files.Pending, files.Completed = mergeTasks(files.Pending, files.Completed, result.Tasks)
+		files.Backlog = append([]string{result.SyncKey}, removeLines(files.Backlog, sent)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Replace pending and completed tasks with received ones having the same UUID, moving them between files by status.
+func mergeTasks(pending, completed, received []taskwarrior.Task) ([]taskwarrior.Task, []taskwarrior.Task) {
+	if len(received) == 0 {
+		return pending, completed
+	}
+	byUUID := map[string]taskwarrior.Task{}
+	var order []string
+	for _, tasks := range [][]taskwarrior.Task{pending, completed, received} {
+		for _, task := range tasks {
+			if _, ok := byUUID[task.Uuid]; !ok {
+				order = append(order, task.Uuid)
+			}
+			byUUID[task.Uuid] = task
+		}
+	}
+
+	pending, completed = nil, nil
+	for _, uuid := range order {
+		task := byUUID[uuid]
+		task.Id = 0
+		switch strings.ToLower(task.Status) {
+		case "pending", "waiting", "recurring":
+			pending = append(pending, task)
+		default:
+			completed = append(completed, task)
+		}
+	}
+	return pending, completed
+}
+
+// Return lines without the removed ones. Each removed line drops a single occurrence.
+func removeLines(lines, removed []string) []string {
+	count := map[string]int{}
+	for _, line := range removed {
+		count[line]++
+	}
+	var kept []string
+	for _, line := range lines {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
@@ -0,0 +1,239 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func newTestServer(t *testing.T) *Server {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestClient_Sync(t *testing.T) {
+	server := newTestServer(t)
+	creds := server.AddUser("Public", "John Doe")
+	laptop := NewClient(server.ClientConfig(creds))
+	phone := NewClient(server.ClientConfig(creds))
+	ctx := context.Background()
+
+	// Initial sync of the first client
+	milk := taskwarrior.Task{Uuid: "a0000000-0000-4000-8000-000000000001", Description: "Buy milk", Status: "pending"}
+	first, err := laptop.Sync(ctx, "", []taskwarrior.Task{milk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Code != 201 || len(first.Tasks) != 0 || first.SyncKey == "" {
+		t.Errorf("Unexpected first sync result: %+v", first)
+	}
+
+	// The second client receives the task and sends its own
+	bread := taskwarrior.Task{Uuid: "a0000000-0000-4000-8000-000000000002", Description: "Buy bread", Status: "pending"}
+	second, err := phone.Sync(ctx, "", []taskwarrior.Task{bread})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Code != 200 || len(second.Tasks) != 1 || second.Tasks[0].Description != "Buy milk" {
+		t.Errorf("Unexpected second sync result: %+v", second)
+	}
+
+	// The first client receives only the changes made after its sync
+	milk.Status = "completed"
+	third, err := laptop.Sync(ctx, first.SyncKey, []taskwarrior.Task{milk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(third.Tasks) != 1 || third.Tasks[0].Uuid != bread.Uuid || third.SyncKey == first.SyncKey {
+		t.Errorf("Unexpected third sync result: %+v", third)
+	}
+
+	// Nothing changed since the last sync
+	noop, err := laptop.Sync(ctx, third.SyncKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noop.Code != 201 || noop.SyncKey != third.SyncKey || len(noop.Tasks) != 0 {
+		t.Errorf("Unexpected result without changes: %+v", noop)
+	}
+
+	// The latest version of a task is sent
+	fourth, err := phone.Sync(ctx, second.SyncKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fourth.Tasks) != 1 || fourth.Tasks[0].Status != "completed" {
+		t.Errorf("Unexpected fourth sync result: %+v", fourth)
+	}
+}
+
+func TestClient_SyncErrors(t *testing.T) {
+	server := newTestServer(t)
+	creds := server.AddUser("Public", "John Doe")
+	ctx := context.Background()
+
+	wrong := creds
+	wrong.Key = "00000000-0000-4000-8000-000000000000"
+	_, err := NewClient(server.ClientConfig(wrong)).Sync(ctx, "", nil)
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.Code != 430 {
+		t.Errorf("Expected access denied, got %v", err)
+	}
+
+	_, err = NewClient(server.ClientConfig(creds)).Sync(ctx, "unknown-key", nil)
+	if !errors.As(err, &respErr) || respErr.Code != 500 {
+		t.Errorf("Expected unknown sync key error, got %v", err)
+	}
+
+	// Server requires the client certificate
+	config := server.ClientConfig(creds)
+	config.TLS.Certificates = nil
+	if _, err := NewClient(config).Sync(ctx, "", nil); err == nil {
+		t.Error("Connection without client certificate was accepted")
+	}
+
+	// Canceled request
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := NewClient(server.ClientConfig(creds)).Sync(canceled, "", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+}
+
+func TestClient_SyncDataDir(t *testing.T) {
+	server := newTestServer(t)
+	creds := server.AddUser("Public", "John Doe")
+	client := NewClient(server.ClientConfig(creds))
+	ctx := context.Background()
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+
+	// Another client uploads tasks first
+	remote := []taskwarrior.Task{
+		{Uuid: "b0000000-0000-4000-8000-000000000001", Description: "Remote pending", Status: "pending", Entry: taskwarrior.NewTaskTime(now)},
+		{Uuid: "b0000000-0000-4000-8000-000000000002", Description: "Remote done", Status: "completed", Entry: taskwarrior.NewTaskTime(now), End: taskwarrior.NewTaskTime(now)},
+	}
+	if _, err := client.Sync(ctx, "", remote); err != nil {
+		t.Fatal(err)
+	}
+
+	// Local data directory with a task changed by DataFileBackend
+	dir := t.TempDir()
+	backend := &taskwarrior.DataFileBackend{Dir: dir, Now: func() time.Time { return now }}
+	for _, name := range []string{taskwarrior.PendingDataFile, taskwarrior.CompletedDataFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := backend.Add(ctx, &taskwarrior.Task{Uuid: "c0000000-0000-4000-8000-000000000001", Description: "Local"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.SyncDataDir(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Tasks) != 2 {
+		t.Errorf("Expected 2 received tasks, got %+v", result.Tasks)
+	}
+	backlog, err := os.ReadFile(filepath.Join(dir, taskwarrior.BacklogDataFile))
+	if err != nil || strings.TrimSpace(string(backlog)) != result.SyncKey {
+		t.Errorf("Backlog should contain only the sync key, got %q (%v)", backlog, err)
+	}
+
+	tasks, err := backend.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, task := range tasks {
+		statuses[task.Description] = task.Status
+	}
+	expected := map[string]string{"Local": "pending", "Remote pending": "pending", "Remote done": "completed"}
+	if len(statuses) != len(expected) {
+		t.Errorf("Unexpected tasks after sync: %v", statuses)
+	}
+	for desc, status := range expected {
+		if statuses[desc] != status {
+			t.Errorf("%s: expected status %s, got %s", desc, status, statuses[desc])
+		}
+	}
+	completed, err := taskwarrior.ReadDataFile(filepath.Join(dir, taskwarrior.CompletedDataFile))
+	if err != nil || len(completed) != 1 {
+		t.Errorf("Completed task should be stored in completed.data: %v (%v)", completed, err)
+	}
+
+	// The next sync uses the stored key
+	result, err = client.SyncDataDir(ctx, dir)
+	if err != nil || result.Code != 201 || len(result.Tasks) != 0 {
+		t.Errorf("Unexpected result of repeated sync: %+v (%v)", result, err)
+	}
+}
+
+func TestClient_SyncDataDirConcurrentChange(t *testing.T) {
+	server := newTestServer(t)
+	config := server.ClientConfig(server.AddUser("Public", "John Doe"))
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	backend := &taskwarrior.DataFileBackend{Dir: dir}
+	if _, err := backend.Add(ctx, &taskwarrior.Task{Description: "Sent"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Proxy that changes the data directory while the request is in flight
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	added := make(chan *taskwarrior.Task, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		task, _ := backend.Add(ctx, &taskwarrior.Task{Description: "Added during sync"})
+		added <- task
+		upstream, err := net.Dial("tcp", server.Addr)
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+	}()
+	config.Server = listener.Addr().String()
+
+	result, err := NewClient(config).SyncDataDir(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := <-added
+	if changed == nil {
+		t.Fatal("Task was not added during sync")
+	}
+	backlog, err := os.ReadFile(filepath.Join(dir, taskwarrior.BacklogDataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(backlog)), "\n")
+	if len(lines) != 2 || lines[0] != result.SyncKey || !strings.Contains(lines[1], changed.Uuid) {
+		t.Errorf("Backlog should contain the sync key and the change made during sync, got %q", backlog)
+	}
+}
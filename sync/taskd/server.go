@@ -0,0 +1,296 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// In-process taskd stand-in for tests.
+//
+// Server keeps accounts in memory and speaks the same protocol as taskd over TLS with certificates issued by its own
+// CA. Merging is simplified: the server doesn't merge attributes, the latest uploaded version of a task wins.
+
+package taskd
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Server is an in-memory taskd server listening on a random local port.
+type Server struct {
+	Addr          string // Address of the listener, `127.0.0.1:<port>`
+	CAPEM         []byte // Certificate of the CA that issued server and client certificates
+	ClientCertPEM []byte // Client certificate accepted by the server
+	ClientKeyPEM  []byte // Private key of the client certificate
+
+	listener net.Listener
+	client   tls.Certificate
+	mu       sync.Mutex
+	accounts map[string]*account
+	wg       sync.WaitGroup
+}
+
+// Account data: uploaded task versions interleaved with sync keys.
+type account struct {
+	key string
+	log []logEntry
+}
+
+type logEntry struct {
+	syncKey string // Set for entries marking a sync point
+	uuid    string
+	task    string // Task JSON
+}
+
+// Start server with new certificates. The server is stopped with Close.
+func NewServer() (*Server, error) {
+	ca, caKey, caPEM, err := newCertificate(nil, nil, "taskd test CA", true)
+	if err != nil {
+		return nil, err
+	}
+	serverCert, serverKey, _, err := newCertificate(ca, caKey, "localhost", false)
+	if err != nil {
+		return nil, err
+	}
+	clientCert, clientKey, clientPEM, err := newCertificate(ca, caKey, "client", false)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	config := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Addr:          listener.Addr().String(),
+		CAPEM:         caPEM,
+		ClientCertPEM: clientPEM,
+		ClientKeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		listener:      listener,
+		client:        tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey},
+		accounts:      map[string]*account{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Stop accepting connections and wait for running requests.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Create account and return its credentials.
+func (s *Server) AddUser(org, user string) Credentials {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	creds := Credentials{Org: org, User: user, Key: taskwarrior.NewUUID()}
+	s.accounts[org+"/"+user] = &account{key: creds.Key}
+	return creds
+}
+
+// Return client settings for the account that trust the server CA and use the client certificate.
+func (s *Server) ClientConfig(creds Credentials) *Config {
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(s.CAPEM)
+	return &Config{
+		Server:      s.Addr,
+		Credentials: creds,
+		TLS: &tls.Config{
+			Certificates: []tls.Certificate{s.client},
+			RootCAs:      pool,
+			ServerName:   "localhost",
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(30 * time.Second))
+			req, err := ReadMessage(bufio.NewReader(conn))
+			if err != nil {
+				return
+			}
+			WriteMessage(conn, s.handle(req))
+		}()
+	}
+}
+
+// Return response to the request.
+func (s *Server) handle(req *Message) *Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.Header["protocol"] != ProtocolVersion {
+		return response(400, "Malformed data", "")
+	}
+	acc, ok := s.accounts[req.Header["org"]+"/"+req.Header["user"]]
+	if !ok || acc.key != req.Header["key"] {
+		return response(430, "Access denied", "")
+	}
+	switch req.Header["type"] {
+	case "sync":
+		return acc.sync(req.Payload)
+	case "statistics":
+		return response(200, "Ok", "")
+	default:
+		return response(500, "Unsupported request type", "")
+	}
+}
+
+// Store uploaded tasks and return versions uploaded by other clients after the sync key.
+func (acc *account) sync(payload string) *Message {
+	var key string
+	var uploaded []logEntry
+	for _, line := range strings.Split(payload, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "{"):
+			var task struct {
+				Uuid string `json:"uuid"`
+			}
+			if err := json.Unmarshal([]byte(line), &task); err != nil || task.Uuid == "" {
+				return response(500, "Syntax error in request payload", "")
+			}
+			uploaded = append(uploaded, logEntry{uuid: task.Uuid, task: line})
+		default:
+			key = line
+		}
+	}
+
+	start := 0
+	if key != "" {
+		start = -1
+		for i, entry := range acc.log {
+			if entry.syncKey == key {
+				start = i + 1
+			}
+		}
+		if start < 0 {
+			return response(500, "Client sync key not found.", "")
+		}
+	}
+
+	// Latest version of each task changed since the sync, except tasks uploaded now.
+	sent := map[string]bool{}
+	for _, entry := range uploaded {
+		sent[entry.uuid] = true
+	}
+	latest := map[string]string{}
+	var order []string
+	for _, entry := range acc.log[start:] {
+		if entry.uuid == "" || sent[entry.uuid] {
+			continue
+		}
+		if _, ok := latest[entry.uuid]; !ok {
+			order = append(order, entry.uuid)
+		}
+		latest[entry.uuid] = entry.task
+	}
+
+	var out strings.Builder
+	for _, id := range order {
+		out.WriteString(latest[id] + "\n")
+	}
+	if len(uploaded) == 0 && len(order) == 0 && key != "" {
+		return response(201, "No change", key+"\n")
+	}
+
+	newKey := taskwarrior.NewUUID()
+	acc.log = append(acc.log, uploaded...)
+	acc.log = append(acc.log, logEntry{syncKey: newKey})
+	out.WriteString(newKey + "\n")
+	if len(order) == 0 {
+		return response(201, "No change", out.String())
+	}
+	return response(200, "Ok", out.String())
+}
+
+func response(code int, status, payload string) *Message {
+	return &Message{
+		Header: map[string]string{
+			"type":   "response",
+			"code":   strconv.Itoa(code),
+			"status": status,
+		},
+		Payload: payload,
+	}
+}
+
+// Create certificate signed by parent, or self-signed one if parent is nil. Returns certificate, its key and PEM.
+func newCertificate(parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string, ca bool) (*x509.Certificate, *ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ca {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		template.DNSNames = []string{"localhost"}
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
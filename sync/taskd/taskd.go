@@ -0,0 +1,255 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Package taskd implements the taskd (Taskserver) sync protocol used by taskwarrior 2.x.
+//
+// Messages are sent over TLS, each one prefixed with its length as a 4-byte big-endian number that includes the
+// prefix itself. A message is a block of `name: value` header lines, an empty line and a payload. Sync requests
+// carry the local changes, one task JSON per line, and the sync key of the previous sync; the response carries the
+// changes made by other clients and a new sync key. See https://taskwarrior.org/docs/taskserver/protocol/.
+//
+// Server is an in-process taskd stand-in for tests.
+package taskd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+// Largest accepted message.
+const MaxMessageSize = 64 * 1024 * 1024
+
+// Protocol version sent in requests.
+const ProtocolVersion = "v1"
+
+// Message is a request or response of taskd protocol.
+type Message struct {
+	Header  map[string]string
+	Payload string
+}
+
+// Encode the message with its length prefix. Header lines are written in sorted order.
+func (m *Message) Encode() []byte {
+	names := make([]string, 0, len(m.Header))
+	for name := range m.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var body bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&body, "%s: %s\n", name, m.Header[name])
+	}
+	body.WriteString("\n")
+	body.WriteString(m.Payload)
+
+	buf := make([]byte, 4, 4+body.Len())
+	binary.BigEndian.PutUint32(buf, uint32(4+body.Len()))
+	return append(buf, body.Bytes()...)
+}
+
+// Write the message to the connection.
+func WriteMessage(w io.Writer, m *Message) error {
+	_, err := w.Write(m.Encode())
+	return err
+}
+
+// Read one message from the connection.
+func ReadMessage(r io.Reader) (*Message, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size < 4 || size > MaxMessageSize {
+		return nil, fmt.Errorf("invalid message size %d", size)
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return ParseMessage(string(body))
+}
+
+// Parse message without the length prefix.
+func ParseMessage(body string) (*Message, error) {
+	header, payload, _ := strings.Cut(body, "\n\n")
+	m := &Message{Header: map[string]string{}, Payload: payload}
+	for _, line := range strings.Split(header, "\n") {
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line '%s'", line)
+		}
+		m.Header[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return m, nil
+}
+
+// Credentials identify account on the server, taskd.credentials option has form `<org>/<user>/<key>`.
+type Credentials struct {
+	Org  string
+	User string
+	Key  string
+}
+
+// Parse taskd.credentials value.
+func ParseCredentials(s string) (Credentials, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return Credentials{}, fmt.Errorf("taskd.credentials must have form <org>/<user>/<key>")
+	}
+	return Credentials{Org: parts[0], User: parts[1], Key: parts[2]}, nil
+}
+
+func (c Credentials) String() string {
+	return c.Org + "/" + c.User + "/" + c.Key
+}
+
+// Config contains connection settings of the client.
+type Config struct {
+	Server      string      // Address of the server, `<host>:<port>`
+	Credentials Credentials // Account on the server
+	TLS         *tls.Config // Client certificate and trusted CA
+}
+
+// Read connection settings from taskd.server, taskd.credentials, taskd.certificate, taskd.key, taskd.ca and
+// taskd.trust options. Trust `strict` verifies the server certificate and its host name, `ignore hostname` skips the
+// host name check and `allow all` accepts any certificate.
+func ConfigFromTaskRC(rc *taskwarrior.TaskRC) (*Config, error) {
+	server := rc.Get("taskd.server")
+	if server == "" {
+		return nil, errors.New("taskd.server is not set")
+	}
+	creds, err := ParseCredentials(rc.Get("taskd.credentials"))
+	if err != nil {
+		return nil, err
+	}
+
+	host := server
+	if i := strings.LastIndex(server, ":"); i >= 0 {
+		host = server[:i]
+	}
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+
+	if cert, key := rc.Get("taskd.certificate"), rc.Get("taskd.key"); cert != "" || key != "" {
+		pair, err := tls.LoadX509KeyPair(taskwarrior.PathExpandTilda(cert), taskwarrior.PathExpandTilda(key))
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	if ca := rc.Get("taskd.ca"); ca != "" {
+		pem, err := os.ReadFile(taskwarrior.PathExpandTilda(ca))
+		if err != nil {
+			return nil, fmt.Errorf("can't read taskd.ca: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", ca)
+		}
+	}
+
+	switch trust := rc.Get("taskd.trust"); trust {
+	case "", "strict":
+	case "ignore hostname":
+		verifyWithoutHostname(tlsConfig)
+	case "allow all":
+		tlsConfig.InsecureSkipVerify = true
+	default:
+		return nil, fmt.Errorf("invalid taskd.trust value '%s'", trust)
+	}
+	return &Config{Server: server, Credentials: creds, TLS: tlsConfig}, nil
+}
+
+// Verify server certificate chain without checking the host name.
+func verifyWithoutHostname(config *tls.Config) {
+	roots := config.RootCAs
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+		var certs []*x509.Certificate
+		for _, der := range raw {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+		if len(certs) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+}
+
+// ResponseError is returned when the server responds with an error code.
+type ResponseError struct {
+	Code   int
+	Status string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("taskd: %d %s", e.Code, e.Status)
+}
+
+// Split sync payload on tasks and sync key.
+func parsePayload(payload string) ([]taskwarrior.Task, string, error) {
+	var tasks []taskwarrior.Task
+	var key string
+	for _, line := range strings.Split(payload, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "{"):
+			var task taskwarrior.Task
+			if err := json.Unmarshal([]byte(line), &task); err != nil {
+				return nil, "", fmt.Errorf("invalid task in payload: %w", err)
+			}
+			tasks = append(tasks, task)
+		default:
+			key = line
+		}
+	}
+	return tasks, key, nil
+}
+
+// Compose sync payload: sync key followed by tasks.
+func composePayload(tasks []taskwarrior.Task, key string) (string, error) {
+	var b strings.Builder
+	if key != "" {
+		b.WriteString(key + "\n")
+	}
+	for i := range tasks {
+		line, err := json.Marshal(&tasks[i])
+		if err != nil {
+			return "", err
+		}
+		b.Write(line)
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func responseCode(m *Message) int {
+	code, _ := strconv.Atoi(m.Header["code"])
+	return code
+}
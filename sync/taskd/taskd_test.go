@@ -0,0 +1,140 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/errnoh/go-taskwarrior"
+)
+
+func TestMessage(t *testing.T) {
+	m := &Message{
+		Header:  map[string]string{"type": "sync", "org": "Public", "protocol": "v1"},
+		Payload: "key\n{\"uuid\":\"x\"}\n",
+	}
+	raw := m.Encode()
+	expected := "org: Public\nprotocol: v1\ntype: sync\n\nkey\n{\"uuid\":\"x\"}\n"
+	if string(raw[4:]) != expected {
+		t.Errorf("Unexpected message body: %q", raw[4:])
+	}
+	if size := binary.BigEndian.Uint32(raw); int(size) != len(raw) {
+		t.Errorf("Length prefix %d doesn't include itself, message has %d bytes", size, len(raw))
+	}
+
+	got, err := ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Payload != m.Payload || len(got.Header) != 3 || got.Header["org"] != "Public" {
+		t.Errorf("Unexpected decoded message: %+v", got)
+	}
+
+	// Truncated and oversized messages
+	if _, err := ReadMessage(bytes.NewReader(raw[:len(raw)-1])); err == nil {
+		t.Error("Truncated message was accepted")
+	}
+	if _, err := ReadMessage(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff})); err == nil {
+		t.Error("Oversized message was accepted")
+	}
+	if _, err := ParseMessage("no separator\n\n"); err == nil {
+		t.Error("Malformed header was accepted")
+	}
+}
+
+func TestParseCredentials(t *testing.T) {
+	creds, err := ParseCredentials("Public/John Doe/f00ba4")
+	if err != nil || creds != (Credentials{"Public", "John Doe", "f00ba4"}) {
+		t.Errorf("Unexpected credentials: %+v (%v)", creds, err)
+	}
+	if creds.String() != "Public/John Doe/f00ba4" {
+		t.Errorf("Unexpected string form: %s", creds)
+	}
+	for _, s := range []string{"", "Public/John", "Public//key", "a/b/c/d"} {
+		if _, err := ParseCredentials(s); err == nil {
+			t.Errorf("Invalid credentials '%s' were accepted", s)
+		}
+	}
+}
+
+func TestConfigFromTaskRC(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	dir := t.TempDir()
+	files := map[string][]byte{"ca.cert.pem": server.CAPEM, "client.cert.pem": server.ClientCertPEM, "client.key.pem": server.ClientKeyPEM}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	taskrc := func(extra string) (*Config, error) {
+		rc := &taskwarrior.TaskRC{}
+		err := rc.MapTaskRC(strings.Join([]string{
+			"taskd.server=" + server.Addr,
+			"taskd.credentials=Public/John Doe/f00ba4",
+			"taskd.certificate=" + filepath.Join(dir, "client.cert.pem"),
+			"taskd.key=" + filepath.Join(dir, "client.key.pem"),
+			"taskd.ca=" + filepath.Join(dir, "ca.cert.pem"),
+			extra,
+		}, "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ConfigFromTaskRC(rc)
+	}
+
+	config, err := taskrc("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Server != server.Addr || config.Credentials.User != "John Doe" || config.TLS.ServerName != "127.0.0.1" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if len(config.TLS.Certificates) != 1 || config.TLS.RootCAs == nil || config.TLS.InsecureSkipVerify {
+		t.Errorf("Unexpected TLS config: %+v", config.TLS)
+	}
+
+	// The server certificate is issued for the IP address, so strict trust works
+	creds := server.AddUser("Public", "John Doe")
+	config.Credentials = creds
+	if _, err := NewClient(config).Sync(t.Context(), "", nil); err != nil {
+		t.Errorf("Sync with strict trust failed: %v", err)
+	}
+
+	// Host name mismatch is ignored, but the chain is still verified
+	config, err = taskrc("taskd.trust=ignore hostname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Credentials = creds
+	config.TLS.ServerName = "example.com"
+	if _, err := NewClient(config).Sync(t.Context(), "", nil); err != nil {
+		t.Errorf("Sync ignoring host name failed: %v", err)
+	}
+	config.TLS.RootCAs = nil
+	verifyWithoutHostname(config.TLS)
+	if _, err := NewClient(config).Sync(t.Context(), "", nil); err == nil {
+		t.Error("Untrusted certificate was accepted")
+	}
+
+	if config, err := taskrc("taskd.trust=allow all"); err != nil || !config.TLS.InsecureSkipVerify {
+		t.Errorf("Unexpected config for 'allow all': %+v (%v)", config, err)
+	}
+	if _, err := taskrc("taskd.trust=sometimes"); err == nil {
+		t.Error("Invalid taskd.trust was accepted")
+	}
+
+	if _, err := ConfigFromTaskRC(&taskwarrior.TaskRC{}); err == nil {
+		t.Error("Missing taskd.server was accepted")
+	}
+}
@@ -6,42 +6,200 @@
 package taskwarrior
 
 import (
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Annotation represents a task annotation.
 type Annotation struct {
-	Entry        string `json:"entry"`
-	Description string `json:"description"`
+	Entry       TaskTime `json:"entry,omitzero"`
+	Description string   `json:"description"`
 }
 
 // Task representation.
 type Task struct {
-	Id          int32   `json:"id"`
-	Description string  `json:"description"`
-	Project     string  `json:"project,omitempty"`
-	Status      string  `json:"status,omitempty"`
-	Uuid        string  `json:"uuid,omitempty"`
-	Urgency     float32 `json:"urgency,omitempty"`
-	Priority    string  `json:"priority,omitempty"`
-	Due         string  `json:"due,omitempty"`
-	Start       string  `json:"start,omitempty"`
-	End         string  `json:"end,omitempty"`
-	Entry       string  `json:"entry,omitempty"`
-	Until       string  `json:"until,omitempty"`
-	Wait        string  `json:"wait,omitempty"`
-	Scheduled   string  `json:"scheduled,omitempty"`
-	Recur       string  `json:"recur,omitempty"`
-	Mask        string  `json:"mask,omitempty"`
-	Imask       int     `json:"imask,omitempty"`
-	Parent      string  `json:"parent,omitempty"`
-	Modified    string  `json:"modified,omitempty"`
-	Depends     []string `json:"depends,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	Annotations []Annotation `json:"annotations,omitempty"`
+	Id          int32                  `json:"id"`
+	Description string                 `json:"description"`
+	Project     string                 `json:"project,omitempty"`
+	Status      string                 `json:"status,omitempty"`
+	Uuid        string                 `json:"uuid,omitempty"`
+	Urgency     float32                `json:"urgency,omitempty"`
+	Priority    string                 `json:"priority,omitempty"`
+	Due         TaskTime               `json:"due,omitzero"`
+	Start       TaskTime               `json:"start,omitzero"`
+	End         TaskTime               `json:"end,omitzero"`
+	Entry       TaskTime               `json:"entry,omitzero"`
+	Until       TaskTime               `json:"until,omitzero"`
+	Wait        TaskTime               `json:"wait,omitzero"`
+	Scheduled   TaskTime               `json:"scheduled,omitzero"`
+	Recur       string                 `json:"recur,omitempty"`
+	Mask        string                 `json:"mask,omitempty"`
+	Imask       int                    `json:"imask,omitempty"`
+	Parent      string                 `json:"parent,omitempty"`
+	Modified    TaskTime               `json:"modified,omitzero"`
+	Depends     []string               `json:"depends,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Annotations []Annotation           `json:"annotations,omitempty"`
 	UDA         map[string]interface{} `json:"-"`
 }
 
+// JSON keys of attributes that have their own Task fields. Everything else is an UDA.
+var taskFieldKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(Task{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}()
+
+// MarshalJSON encodes the task with its UDA values as top-level attributes, like taskwarrior does.
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plainTask Task
+	buf, err := json.Marshal(plainTask(t))
+	if err != nil || len(t.UDA) == 0 {
+		return buf, err
+	}
+
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(buf, &obj); err != nil {
+		return nil, err
+	}
+	for name, value := range t.UDA {
+		if taskFieldKeys[name] {
+			continue
+		}
+		if date, ok := value.(time.Time); ok {
+			value = date.UTC().Format(dateLayout)
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("can't encode UDA '%s': %w", name, err)
+		}
+		obj[name] = raw
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON decodes the task and collects all unknown attributes in UDA. Dependencies are accepted both as an
+// array of UUIDs and as a comma-separated string exported by taskwarrior before 2.5.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plainTask Task
+	var wrapper struct {
+		plainTask
+		Depends json.RawMessage `json:"depends,omitempty"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return err
+	}
+	plain := wrapper.plainTask
+	depends, err := parseDepends(wrapper.Depends)
+	if err != nil {
+		return err
+	}
+	plain.Depends = depends
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	for name, raw := range obj {
+		if taskFieldKeys[name] {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if plain.UDA == nil {
+			plain.UDA = map[string]interface{}{}
+		}
+		plain.UDA[name] = value
+	}
+
+	*t = Task(plain)
+	return nil
+}
+
+// Decode dependencies given as an array of UUIDs or as a comma-separated string.
+func parseDepends(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("invalid depends %s: must be an array or a comma-separated string", raw)
+	}
+	for _, uuid := range strings.Split(s, ",") {
+		if uuid = strings.TrimSpace(uuid); uuid != "" {
+			list = append(list, uuid)
+		}
+	}
+	return list, nil
+}
+
+// Encode the task like MarshalJSON in the format expected by taskwarrior with given capabilities: versions before 2.5
+// take dependencies as a comma-separated string.
+func EncodeTask(task *Task, caps Capabilities) ([]byte, error) {
+	buf, err := json.Marshal(task)
+	if err != nil || caps.DependsArray || len(task.Depends) == 0 {
+		return buf, err
+	}
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(buf, &obj); err != nil {
+		return nil, err
+	}
+	obj["depends"], _ = json.Marshal(strings.Join(task.Depends, ","))
+	return json.Marshal(obj)
+}
+
+// Convert UDA values of the task to Go types matching their `uda.<name>.type` declarations in the configuration:
+// float64 for numeric, TaskTime for date, Duration for duration and string for string attributes. Undeclared
+// attributes and values that can't be converted are left as is.
+func (c *TaskRC) ConvertUDA(task *Task) {
+	for name, value := range task.UDA {
+		switch c.UDA[name] {
+		case "numeric":
+			if s, ok := value.(string); ok {
+				if f, err := strconv.ParseFloat(s, 64); err == nil {
+					task.UDA[name] = f
+				}
+			}
+		case "date":
+			if s, ok := value.(string); ok {
+				if date, err := ParseTaskTime(s); err == nil {
+					task.UDA[name] = date
+				}
+			}
+		case "duration":
+			switch v := value.(type) {
+			case string:
+				if d, err := ParseDuration(v); err == nil {
+					task.UDA[name] = d
+				}
+			case float64:
+				task.UDA[name] = Duration{Time: time.Duration(v) * time.Second}
+			}
+		case "string":
+			if f, ok := value.(float64); ok {
+				task.UDA[name] = strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+	}
+}
+
 // ValidateTask checks if the task has all required fields.
 // Returns error if validation fails.
 func ValidateTask(task *Task) error {
@@ -61,16 +219,16 @@ func ValidateTask(task *Task) error {
 		return fmt.Errorf("task uuid is required")
 	}
 
-	if task.Entry == "" {
+	if task.Entry.IsZero() {
 		return fmt.Errorf("task entry is required")
 	}
 
 	// Validate status is one of the allowed values
 	validStatuses := map[string]bool{
-		"pending":  true,
+		"pending":   true,
 		"completed": true,
-		"deleted":  true,
-		"waiting":  true,
+		"deleted":   true,
+		"waiting":   true,
 		"recurring": true,
 	}
 	if !validStatuses[task.Status] {
@@ -93,3 +251,12 @@ func ValidateTaskRC(config *TaskRC) error {
 
 	return nil
 }
+
+// Generate random (version 4) UUID.
+func NewUUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -4,7 +4,10 @@
 package taskwarrior
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestTask_NewFields(t *testing.T) {
@@ -13,18 +16,18 @@ func TestTask_NewFields(t *testing.T) {
 		Description: "Test task with all fields",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
-		Start:       "20260206T130000Z",
-		End:         "",
-		Due:         "20260210T120000Z",
-		Until:       "20260306T120000Z",
-		Wait:        "",
-		Scheduled:   "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
+		Start:       MustParseTaskTime("20260206T130000Z"),
+		End:         TaskTime{},
+		Due:         MustParseTaskTime("20260210T120000Z"),
+		Until:       MustParseTaskTime("20260306T120000Z"),
+		Wait:        TaskTime{},
+		Scheduled:   MustParseTaskTime("20260206T120000Z"),
 		Recur:       "weekly",
 		Mask:        "-----",
 		Imask:       0,
 		Parent:      "",
-		Modified:    "20260206T120000Z",
+		Modified:    MustParseTaskTime("20260206T120000Z"),
 		Depends:     []string{},
 		Tags: []string{
 			"work",
@@ -32,7 +35,7 @@ func TestTask_NewFields(t *testing.T) {
 		},
 		Annotations: []Annotation{
 			{
-				Entry:        "20260206T120500Z",
+				Entry:        MustParseTaskTime("20260206T120500Z"),
 				Description: "Initial annotation",
 			},
 		},
@@ -55,24 +58,24 @@ func TestTask_NewFields(t *testing.T) {
 		t.Errorf("Uuid mismatch: expected '00000000-0000-0000-0000-000000000001', got '%s'", task.Uuid)
 	}
 
-	if task.Entry != "20260206T120000Z" {
+	if task.Entry.String() != "20260206T120000Z" {
 		t.Errorf("Entry mismatch: expected '20260206T120000Z', got '%s'", task.Entry)
 	}
 
 	// Verify new fields
-	if task.Start != "20260206T130000Z" {
+	if task.Start.String() != "20260206T130000Z" {
 		t.Errorf("Start mismatch: expected '20260206T130000Z', got '%s'", task.Start)
 	}
 
-	if task.Due != "20260210T120000Z" {
+	if task.Due.String() != "20260210T120000Z" {
 		t.Errorf("Due mismatch: expected '20260210T120000Z', got '%s'", task.Due)
 	}
 
-	if task.Until != "20260306T120000Z" {
+	if task.Until.String() != "20260306T120000Z" {
 		t.Errorf("Until mismatch: expected '20260306T120000Z', got '%s'", task.Until)
 	}
 
-	if task.Scheduled != "20260206T120000Z" {
+	if task.Scheduled.String() != "20260206T120000Z" {
 		t.Errorf("Scheduled mismatch: expected '20260206T120000Z', got '%s'", task.Scheduled)
 	}
 
@@ -112,7 +115,7 @@ func TestTask_NewFields(t *testing.T) {
 		t.Errorf("Annotations length mismatch: expected 1, got %d", len(task.Annotations))
 	}
 
-	if task.Annotations[0].Entry != "20260206T120500Z" {
+	if task.Annotations[0].Entry.String() != "20260206T120500Z" {
 		t.Errorf("Annotation Entry mismatch: expected '20260206T120500Z', got '%s'", task.Annotations[0].Entry)
 	}
 
@@ -143,7 +146,7 @@ func TestTask_DependsFormat(t *testing.T) {
 		Description: "Depends test",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 		Depends:     []string{
 			"00000000-0000-0000-0000-000000000002",
 			"00000000-0000-0000-0000-000000000003",
@@ -163,16 +166,58 @@ func TestTask_DependsFormat(t *testing.T) {
 	}
 }
 
+func TestTask_DependsJSON(t *testing.T) {
+	expected := []string{"00000000-0000-0000-0000-000000000002", "00000000-0000-0000-0000-000000000003"}
+	inputs := map[string]string{
+		"array":  `["00000000-0000-0000-0000-000000000002","00000000-0000-0000-0000-000000000003"]`,
+		"string": `"00000000-0000-0000-0000-000000000002,00000000-0000-0000-0000-000000000003"`,
+		"spaces": `"00000000-0000-0000-0000-000000000002, 00000000-0000-0000-0000-000000000003,"`,
+	}
+	for name, depends := range inputs {
+		var task Task
+		data := `{"uuid":"00000000-0000-0000-0000-000000000001","description":"Depends","depends":` + depends + `}`
+		if err := json.Unmarshal([]byte(data), &task); err != nil {
+			t.Errorf("%s: can't decode task: %v", name, err)
+			continue
+		}
+		if len(task.Depends) != 2 || task.Depends[0] != expected[0] || task.Depends[1] != expected[1] {
+			t.Errorf("%s: unexpected depends %v", name, task.Depends)
+		}
+		if len(task.UDA) != 0 {
+			t.Errorf("%s: depends collected as UDA: %v", name, task.UDA)
+		}
+	}
+
+	var task Task
+	if err := json.Unmarshal([]byte(`{"description":"Empty","depends":""}`), &task); err != nil || task.Depends != nil {
+		t.Errorf("Empty depends: %v %v", task.Depends, err)
+	}
+	if err := json.Unmarshal([]byte(`{"description":"Invalid","depends":5}`), &task); err == nil {
+		t.Error("Invalid depends was accepted")
+	}
+
+	// Array is written by default, string for old taskwarrior versions
+	task = Task{Description: "Depends", Depends: expected}
+	buf, _ := EncodeTask(&task, CapabilitiesOf(Version{2, 6, 0}))
+	if !strings.Contains(string(buf), `"depends":["00000000-0000-0000-0000-000000000002",`) {
+		t.Errorf("Depends are not encoded as array: %s", buf)
+	}
+	buf, _ = EncodeTask(&task, CapabilitiesOf(Version{2, 4, 5}))
+	if !strings.Contains(string(buf), `"depends":"00000000-0000-0000-0000-000000000002,00000000-0000-0000-0000-000000000003"`) {
+		t.Errorf("Depends are not encoded as string: %s", buf)
+	}
+}
+
 func TestTask_RecurringTask(t *testing.T) {
 	// Test recurring task fields
 	parent := &Task{
 		Description: "Recurring parent",
 		Status:      "recurring",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 		Recur:       "weekly",
-		Due:         "20260306T120000Z",
-		Until:       "20260313T120000Z",
+		Due:         MustParseTaskTime("20260306T120000Z"),
+		Until:       MustParseTaskTime("20260313T120000Z"),
 		Mask:        "-----",
 		Parent:      "",
 	}
@@ -193,7 +238,7 @@ func TestTask_RecurringTask(t *testing.T) {
 		Description: "Recurring child",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000002",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 		Parent:      "00000000-0000-0000-0000-000000000001",
 		Imask:       1,
 	}
@@ -213,7 +258,7 @@ func TestTask_Udapreservation(t *testing.T) {
 		Description: "Task with UDA",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 		UDA: map[string]interface{}{
 			"custom_date": "20260201",
 			"custom_number": 42,
@@ -237,3 +282,76 @@ func TestTask_Udapreservation(t *testing.T) {
 		t.Errorf("custom_bool mismatch: expected true, got '%v'", task.UDA["custom_bool"])
 	}
 }
+
+func TestTask_UDAJSON(t *testing.T) {
+	data := `{"uuid":"00000000-0000-0000-0000-000000000001","description":"UDA task","status":"pending",` +
+		`"entry":"20260206T120000Z","estimate":3,"sprint":"S12","reviewed":"20260207T100000Z"}`
+
+	var task Task
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		t.Fatalf("Can't decode task: %v", err)
+	}
+	if task.Description != "UDA task" {
+		t.Errorf("Description mismatch: expected 'UDA task', got '%s'", task.Description)
+	}
+	if len(task.UDA) != 3 {
+		t.Fatalf("UDA length mismatch: expected 3, got %d (%v)", len(task.UDA), task.UDA)
+	}
+	if task.UDA["estimate"] != 3.0 || task.UDA["sprint"] != "S12" {
+		t.Errorf("Unexpected UDA values: %v", task.UDA)
+	}
+
+	// Convert values to declared types
+	config := &TaskRC{UDA: map[string]string{"estimate": "numeric", "sprint": "string", "reviewed": "date"}}
+	config.ConvertUDA(&task)
+	reviewed := time.Date(2026, 2, 7, 10, 0, 0, 0, time.UTC)
+	if date, ok := task.UDA["reviewed"].(TaskTime); !ok || !date.Equal(reviewed) {
+		t.Errorf("Date UDA mismatch: expected %v, got %v", reviewed, task.UDA["reviewed"])
+	}
+
+	// Write back
+	buf, err := json.Marshal(task)
+	if err != nil {
+		t.Fatalf("Can't encode task: %v", err)
+	}
+	var obj map[string]interface{}
+	json.Unmarshal(buf, &obj)
+	if obj["estimate"] != 3.0 || obj["sprint"] != "S12" || obj["reviewed"] != "20260207T100000Z" {
+		t.Errorf("UDA values were not written back: %s", buf)
+	}
+	if obj["description"] != "UDA task" {
+		t.Errorf("Regular attributes were lost: %s", buf)
+	}
+
+	// UDA can't override regular attributes
+	task.UDA["description"] = "Overridden"
+	buf, _ = json.Marshal(&task)
+	json.Unmarshal(buf, &obj)
+	if obj["description"] != "UDA task" {
+		t.Errorf("UDA overrides regular attribute: %s", buf)
+	}
+}
+
+func TestTaskRC_ConvertUDA(t *testing.T) {
+	config := &TaskRC{}
+	config.MapTaskRC("uda.estimate.type=numeric")
+	if config.UDA["estimate"] != "numeric" {
+		t.Fatalf("UDA type declaration was not parsed: %v", config.UDA)
+	}
+
+	task := &Task{UDA: map[string]interface{}{"estimate": "2.5", "other": "2.5"}}
+	config.ConvertUDA(task)
+	if task.UDA["estimate"] != 2.5 {
+		t.Errorf("Numeric UDA mismatch: expected 2.5, got %v", task.UDA["estimate"])
+	}
+	if task.UDA["other"] != "2.5" {
+		t.Errorf("Undeclared UDA was converted: %v", task.UDA["other"])
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	a, b := NewUUID(), NewUUID()
+	if a == b || len(a) != 36 || a[14] != '4' || !strings.ContainsRune("89ab", rune(a[19])) {
+		t.Errorf("Invalid version 4 UUIDs: %s, %s", a, b)
+	}
+}
@@ -0,0 +1,379 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Storage of taskwarrior 3 tasks in TaskChampion SQLite replica.
+//
+// Taskwarrior 3 keeps tasks in taskchampion.sqlite3 file inside `data.location` directory. The replica consists of
+// following tables:
+//
+//	tasks (uuid, data)      -- JSON object of string attributes for each task
+//	working_set (id, uuid)  -- IDs of pending tasks
+//	operations (id, data)   -- log of changes, sent to the sync server
+//	sync_meta (key, value)  -- synchronization state
+//
+// Task attributes use the same representation as taskwarrior 2.x data files, except that every tag is stored as
+// separate tag_<name> attribute and every dependency as dep_<uuid> attribute.
+//
+// The backend uses database/sql, so a SQLite driver must be registered by the application, e.g. with
+//
+//	import _ "modernc.org/sqlite"
+//
+// TaskChampionDriver must match name of the registered driver.
+
+package taskwarrior
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Name of taskwarrior 3 replica file.
+const TaskChampionFile = "taskchampion.sqlite3"
+
+// Name of database/sql driver used to open TaskChampion replicas.
+var TaskChampionDriver = "sqlite"
+
+// TaskChampionBackend reads and writes tasks directly in taskwarrior 3 replica. Every change is recorded in the
+// operations log, so it is sent to the server on the next synchronization.
+type TaskChampionBackend struct {
+	DB     *sql.DB          // Opened replica
+	Config *TaskRC          // Configuration used to convert UDA values, may be nil
+	Now    func() time.Time // Clock used for entry and modified timestamps, time.Now if nil
+}
+
+// Open replica in data directory of given configuration.
+func NewTaskChampionBackend(config *TaskRC) (*TaskChampionBackend, error) {
+	path := filepath.Join(PathExpandTilda(config.DataLocation), TaskChampionFile)
+	db, err := sql.Open(TaskChampionDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open %s (is SQLite driver '%s' imported?): %w", path, TaskChampionDriver, err)
+	}
+	var name string
+	err = db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'tasks'`).Scan(&name)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s is not a TaskChampion replica: %w", path, err)
+	}
+	return &TaskChampionBackend{DB: db, Config: config}, nil
+}
+
+// Close the replica.
+func (b *TaskChampionBackend) Close() error {
+	return b.DB.Close()
+}
+
+// Return all tasks of the replica. Tasks from the working set come first in order of their IDs.
+func (b *TaskChampionBackend) List(ctx context.Context) ([]Task, error) {
+	ids := map[string]int32{}
+	rows, err := b.DB.QueryContext(ctx, `SELECT id, uuid FROM working_set`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int32
+		var uuid sql.NullString
+		if err := rows.Scan(&id, &uuid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if uuid.Valid {
+			ids[uuid.String] = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = b.DB.QueryContext(ctx, `SELECT uuid, data FROM tasks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var uuid, data string
+		if err := rows.Scan(&uuid, &data); err != nil {
+			return nil, err
+		}
+		task, err := decodeChampionTask(uuid, data)
+		if err != nil {
+			return nil, err
+		}
+		if task.Status == "pending" || task.Status == "waiting" {
+			task.Id = ids[uuid]
+		}
+		if b.Config != nil {
+			b.Config.ConvertUDA(&task)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if (tasks[i].Id == 0) != (tasks[j].Id == 0) {
+			return tasks[i].Id != 0
+		}
+		if tasks[i].Id != tasks[j].Id {
+			return tasks[i].Id < tasks[j].Id
+		}
+		if !tasks[i].Entry.Equal(tasks[j].Entry.Time) {
+			return tasks[i].Entry.Before(tasks[j].Entry.Time)
+		}
+		return tasks[i].Uuid < tasks[j].Uuid
+	})
+	return tasks, nil
+}
+
+// Return task with given UUID.
+func (b *TaskChampionBackend) Get(ctx context.Context, uuid string) (*Task, error) {
+	tasks, err := b.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return findTask(tasks, uuid)
+}
+
+// Add new task to the replica and to the working set if it is pending.
+func (b *TaskChampionBackend) Add(ctx context.Context, task *Task) (*Task, error) {
+	newTask := prepareNewTask(task, b.now())
+	attrs, err := encodeChampionTask(&newTask)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.update(ctx, func(tx *sql.Tx, ops *championOps) error {
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks WHERE uuid = ?`, newTask.Uuid).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("task %s already exists", newTask.Uuid)
+		}
+
+		ops.add(map[string]interface{}{"Create": map[string]string{"uuid": newTask.Uuid}})
+		ops.diff(newTask.Uuid, nil, attrs)
+		if err := saveChampionTask(ctx, tx, newTask.Uuid, attrs); err != nil {
+			return err
+		}
+		return updateWorkingSet(ctx, tx, newTask.Uuid, newTask.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Get(ctx, newTask.Uuid)
+}
+
+// Replace stored task having the same UUID with given one. Every changed attribute is recorded as a separate
+// operation. Completed and deleted tasks leave the working set, reopened tasks join it again.
+func (b *TaskChampionBackend) Modify(ctx context.Context, task *Task) (*Task, error) {
+	modified := *task
+	modified.Modified = NewTaskTime(b.now())
+	attrs, err := encodeChampionTask(&modified)
+	if err != nil {
+		return nil, err
+	}
+
+	err = b.update(ctx, func(tx *sql.Tx, ops *championOps) error {
+		var data string
+		err := tx.QueryRowContext(ctx, `SELECT data FROM tasks WHERE uuid = ?`, task.Uuid).Scan(&data)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("task %s: %w", task.Uuid, ErrNoMatchingTasks)
+		}
+		if err != nil {
+			return err
+		}
+		old := map[string]string{}
+		if err := json.Unmarshal([]byte(data), &old); err != nil {
+			return err
+		}
+
+		ops.diff(task.Uuid, old, attrs)
+		if err := saveChampionTask(ctx, tx, task.Uuid, attrs); err != nil {
+			return err
+		}
+		return updateWorkingSet(ctx, tx, task.Uuid, modified.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Get(ctx, task.Uuid)
+}
+
+// Mark task as deleted, like `task delete` does.
+func (b *TaskChampionBackend) Delete(ctx context.Context, uuid string) error {
+	task, err := b.Get(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	markDeleted(task, b.now())
+	_, err = b.Modify(ctx, task)
+	return err
+}
+
+// Synchronization requires the `task` binary or a sync client.
+func (b *TaskChampionBackend) Sync(ctx context.Context) error {
+	return ErrNotSupported
+}
+
+// Run changes of the replica in a transaction and append recorded operations to the log.
+func (b *TaskChampionBackend) update(ctx context.Context, fn func(tx *sql.Tx, ops *championOps) error) error {
+	tx, err := b.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ops := &championOps{now: b.now()}
+	ops.add("UndoPoint")
+	if err := fn(tx, ops); err != nil {
+		return err
+	}
+	for _, op := range ops.ops {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO operations (data) VALUES (?)`, op); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (b *TaskChampionBackend) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// Operations recorded during single replica change.
+type championOps struct {
+	now time.Time
+	ops []string
+}
+
+func (o *championOps) add(op interface{}) {
+	buf, _ := json.Marshal(op)
+	o.ops = append(o.ops, string(buf))
+}
+
+// Record update operations for every attribute that differs between old and new task.
+func (o *championOps) diff(uuid string, old, new map[string]string) {
+	var keys []string
+	for key := range old {
+		keys = append(keys, key)
+	}
+	for key := range new {
+		if _, ok := old[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	timestamp := o.now.UTC().Format(time.RFC3339Nano)
+	for _, key := range keys {
+		oldVal, hadOld := old[key]
+		newVal, hasNew := new[key]
+		if hadOld == hasNew && oldVal == newVal {
+			continue
+		}
+		update := map[string]interface{}{
+			"uuid": uuid, "property": key, "old_value": nil, "value": nil, "timestamp": timestamp,
+		}
+		if hadOld {
+			update["old_value"] = oldVal
+		}
+		if hasNew {
+			update["value"] = newVal
+		}
+		o.add(map[string]interface{}{"Update": update})
+	}
+}
+
+func saveChampionTask(ctx context.Context, tx *sql.Tx, uuid string, attrs map[string]string) error {
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO tasks (uuid, data) VALUES (?, ?)`, uuid, string(data))
+	return err
+}
+
+// Keep the task in the working set while it is pending or waiting. New members get the next free ID.
+func updateWorkingSet(ctx context.Context, tx *sql.Tx, uuid, status string) error {
+	if status != "pending" && status != "waiting" {
+		_, err := tx.ExecContext(ctx, `DELETE FROM working_set WHERE uuid = ?`, uuid)
+		return err
+	}
+	var member int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM working_set WHERE uuid = ?`, uuid).Scan(&member); err != nil {
+		return err
+	}
+	if member > 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO working_set (id, uuid) `+
+		`VALUES ((SELECT COALESCE(MAX(id), 0) + 1 FROM working_set), ?)`, uuid)
+	return err
+}
+
+// Convert replica record to Task.
+func decodeChampionTask(uuid, data string) (Task, error) {
+	record := map[string]string{}
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return Task{}, fmt.Errorf("task %s: %w", uuid, err)
+	}
+
+	attrs := map[string]string{"uuid": uuid}
+	var tags, depends []string
+	for key, val := range record {
+		switch {
+		case strings.HasPrefix(key, "tag_"):
+			tags = append(tags, strings.TrimPrefix(key, "tag_"))
+		case strings.HasPrefix(key, "dep_"):
+			depends = append(depends, strings.TrimPrefix(key, "dep_"))
+		default:
+			attrs[key] = val
+		}
+	}
+	sort.Strings(tags)
+	sort.Strings(depends)
+	if len(tags) > 0 {
+		attrs["tags"] = strings.Join(tags, ",")
+	}
+	if len(depends) > 0 {
+		attrs["depends"] = strings.Join(depends, ",")
+	}
+
+	task, err := unflattenTask(attrs)
+	if err != nil {
+		return task, fmt.Errorf("task %s: %w", uuid, err)
+	}
+	return task, nil
+}
+
+// Convert Task to replica record.
+func encodeChampionTask(task *Task) (map[string]string, error) {
+	attrs, err := flattenTask(task)
+	if err != nil {
+		return nil, err
+	}
+	delete(attrs, "uuid")
+	delete(attrs, "tags")
+	delete(attrs, "depends")
+	for _, tag := range task.Tags {
+		attrs["tag_"+tag] = ""
+	}
+	for _, dep := range task.Depends {
+		attrs["dep_"+dep] = ""
+	}
+	return attrs, nil
+}
@@ -0,0 +1,194 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Helper that creates empty replica with taskwarrior 3 schema in given directory.
+func createChampionReplica(t *testing.T, dir string) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(dir, TaskChampionFile))
+	if err != nil {
+		t.Fatalf("Can't create replica: %v", err)
+	}
+	schema := []string{
+		`CREATE TABLE operations (id INTEGER PRIMARY KEY AUTOINCREMENT, data STRING)`,
+		`CREATE TABLE sync_meta (key STRING PRIMARY KEY, value STRING)`,
+		`CREATE TABLE tasks (uuid STRING PRIMARY KEY, data STRING)`,
+		`CREATE TABLE working_set (id INTEGER PRIMARY KEY, uuid STRING)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Can't create replica: %v", err)
+		}
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestTaskChampionBackend(t *testing.T) {
+	dir := t.TempDir()
+	db := createChampionReplica(t, dir)
+	backend, err := NewTaskChampionBackend(&TaskRC{DataLocation: dir})
+	if err != nil {
+		t.Fatalf("NewTaskChampionBackend fails with following error: %v", err)
+	}
+	defer backend.Close()
+
+	testBackend(t, backend)
+
+	// Replica content
+	var data string
+	db.QueryRow(`SELECT data FROM tasks`).Scan(&data)
+	record := map[string]string{}
+	json.Unmarshal([]byte(data), &record)
+	if _, ok := record["tag_b"]; !ok || record["status"] != "deleted" || record["due"] != "1770724800" {
+		t.Errorf("Unexpected replica record: %s", data)
+	}
+	if _, ok := record["tag_a"]; ok {
+		t.Errorf("Removed tag is still in replica record: %s", data)
+	}
+
+	// Operations log
+	rows, err := db.Query(`SELECT data FROM operations ORDER BY id`)
+	if err != nil {
+		t.Fatalf("Can't read operations: %v", err)
+	}
+	defer rows.Close()
+	var ops []string
+	for rows.Next() {
+		var op string
+		rows.Scan(&op)
+		ops = append(ops, op)
+	}
+	if len(ops) < 3 || ops[0] != `"UndoPoint"` {
+		t.Fatalf("Unexpected operations: %v", ops)
+	}
+	var create map[string]map[string]string
+	if err := json.Unmarshal([]byte(ops[1]), &create); err != nil || create["Create"]["uuid"] == "" {
+		t.Errorf("Expected Create operation, got %s", ops[1])
+	}
+	var update map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(ops[2]), &update); err != nil || update["Update"]["property"] == nil {
+		t.Errorf("Expected Update operation, got %s", ops[2])
+	}
+
+	if !errors.Is(backend.Sync(context.Background()), ErrNotSupported) {
+		t.Error("Sync should not be supported")
+	}
+}
+
+func TestTaskChampionBackend_WorkingSet(t *testing.T) {
+	dir := t.TempDir()
+	db := createChampionReplica(t, dir)
+	backend, err := NewTaskChampionBackend(&TaskRC{DataLocation: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	ctx := context.Background()
+
+	workingSet := func() map[string]int {
+		ids := map[string]int{}
+		rows, err := db.Query(`SELECT id, uuid FROM working_set`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			var uuid string
+			rows.Scan(&id, &uuid)
+			ids[uuid] = id
+		}
+		return ids
+	}
+
+	first, _ := backend.Add(ctx, &Task{Description: "First"})
+	second, _ := backend.Add(ctx, &Task{Description: "Second"})
+	if ids := workingSet(); len(ids) != 2 || ids[first.Uuid] != 1 || ids[second.Uuid] != 2 {
+		t.Fatalf("Unexpected working set: %v", ids)
+	}
+
+	// Completed task leaves the working set
+	first.Status = "completed"
+	first.End = NewTaskTime(time.Now())
+	if _, err := backend.Modify(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if ids := workingSet(); len(ids) != 1 || ids[second.Uuid] != 2 {
+		t.Errorf("Completed task is still in working set: %v", ids)
+	}
+
+	// Reopened task gets the next ID, modification of a pending task keeps it
+	first.Status = "pending"
+	first.End = TaskTime{}
+	backend.Modify(ctx, first)
+	second.Project = "home"
+	backend.Modify(ctx, second)
+	if ids := workingSet(); len(ids) != 2 || ids[first.Uuid] != 3 || ids[second.Uuid] != 2 {
+		t.Errorf("Unexpected working set after reopening: %v", ids)
+	}
+
+	// Deleted task leaves the working set
+	if err := backend.Delete(ctx, second.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	if ids := workingSet(); len(ids) != 1 || ids[first.Uuid] != 3 {
+		t.Errorf("Deleted task is still in working set: %v", ids)
+	}
+	tasks, _ := backend.List(ctx)
+	for _, task := range tasks {
+		if (task.Uuid == first.Uuid) != (task.Id == 3) {
+			t.Errorf("Unexpected ID of %s: %d", task.Description, task.Id)
+		}
+	}
+}
+
+func TestTaskChampionBackend_List(t *testing.T) {
+	dir := t.TempDir()
+	db := createChampionReplica(t, dir)
+	db.Exec(`INSERT INTO tasks (uuid, data) VALUES (?, ?), (?, ?)`,
+		"00000000-0000-0000-0000-000000000001",
+		`{"description":"Done","status":"completed","entry":"1770379200","end":"1770382800"}`,
+		"00000000-0000-0000-0000-000000000002",
+		`{"description":"Todo","status":"pending","entry":"1770379200","tag_next":"","dep_00000000-0000-0000-0000-000000000001":"","annotation_1770379300":"Note"}`)
+	db.Exec(`INSERT INTO working_set (id, uuid) VALUES (1, ?)`, "00000000-0000-0000-0000-000000000002")
+
+	backend, err := NewTaskChampionBackend(&TaskRC{DataLocation: dir})
+	if err != nil {
+		t.Fatalf("NewTaskChampionBackend fails with following error: %v", err)
+	}
+	defer backend.Close()
+
+	tasks, err := backend.List(context.Background())
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("List returned %d tasks (%v)", len(tasks), err)
+	}
+	todo := tasks[0]
+	if todo.Description != "Todo" || todo.Id != 1 || len(todo.Tags) != 1 || todo.Tags[0] != "next" {
+		t.Errorf("Incorrect pending task: %+v", todo)
+	}
+	if len(todo.Depends) != 1 || len(todo.Annotations) != 1 || todo.Entry.String() != "20260206T120000Z" {
+		t.Errorf("Incorrect pending task: %+v", todo)
+	}
+	if tasks[1].Status != "completed" || tasks[1].Id != 0 {
+		t.Errorf("Incorrect completed task: %+v", tasks[1])
+	}
+
+	// Not a replica
+	_, err = NewTaskChampionBackend(&TaskRC{DataLocation: t.TempDir()})
+	if err == nil {
+		t.Error("NewTaskChampionBackend opens directory without replica")
+	}
+}
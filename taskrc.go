@@ -32,29 +32,50 @@
 package taskwarrior
 
 import (
+	"fmt"
 	"os"
 	"os/user"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 // Default configuration path.
 var TASKRC = PathExpandTilda("~/.taskrc")
 
+// Directories searched for included files with relative paths that don't exist near the including file, e.g.
+// themes and holidays files shipped with taskwarrior.
+var IncludeSearchPaths = []string{
+	"/usr/share/taskwarrior",
+	"/usr/local/share/taskwarrior",
+	"/usr/share/doc/task/rc",
+	"/usr/local/share/doc/task/rc",
+}
+
 // Describes configuration file entries that currently supported by this library.
+//
+// Structure fields contain the most used options, all parsed values are available through Get* methods.
 type TaskRC struct {
-	ConfigPath      string // Location of this .taskrc
-	DataLocation    string `taskwarrior:"data.location"`
-	DependencyTracking string `taskwarrior:"dependency.on"`
-	Recall          string `taskwarrior:"recurrence"`
-	RecallAfter     string `taskwarrior:"recurrence.limit"`
+	ConfigPath         string            // Location of this .taskrc
+	DataLocation       string            `taskwarrior:"data.location"`
+	DependencyTracking string            `taskwarrior:"dependency.on"`
+	HooksLocation      string            `taskwarrior:"hooks.location"`
+	Recall             string            `taskwarrior:"recurrence"`
+	RecallAfter        string            `taskwarrior:"recurrence.limit"`
+	UDA                map[string]string // Types of user defined attributes declared with `uda.<name>.type`
+
+	values  map[string]string // All configuration values by name
+	origins map[string]Origin // Sources of configuration values
 }
 
 // Regular expressions that describes parser rules.
-var reEntry = regexp.MustCompile(`^\s*([a-zA-Z\.]+)\s*=\s*(.*)\s*$`)
-var reInclude = regexp.MustCompile(`^\s*include\s*(.*)\s*$`)
+var reEntry = regexp.MustCompile(`^\s*([^\s=]+)\s*=\s*(.*?)\s*$`)
+var reUDAType = regexp.MustCompile(`^uda\.(.+)\.type$`)
+var reInclude = regexp.MustCompile(`^\s*include\s+(.+?)\s*$`)
+var reUnicodeEscape = regexp.MustCompile(`\\u[0-9a-fA-F]{4}`)
 
 // Expand tilda in filepath as $HOME of current user.
 func PathExpandTilda(path string) string {
@@ -77,80 +98,238 @@ func ParseTaskRC(configPath string) (*TaskRC, error) {
 	// Fix '~' in a path
 	configPath = PathExpandTilda(configPath)
 
-	// Use default configuration file as we need
+	// Use default configuration file as we need. Missing default file means empty configuration.
+	explicit := true
 	if configPath == "" {
-		configPath = TASKRC
+		configPath, explicit = findTaskRC(os.Getenv)
 	} else if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return nil, err
 	}
 
-	// Read the given configuration file content in temporary buffer
-	buf, err := os.ReadFile(configPath)
-	if err != nil {
+	// Read the given configuration file and all included files
+	task := TaskRC{ConfigPath: configPath}
+	err := task.parseFile(configPath, nil)
+	if err != nil && (explicit || !os.IsNotExist(err)) {
 		return nil, err
 	}
-
-	// Map content in new TaskRC instance
-	task := TaskRC{ConfigPath: configPath}
-	task.MapTaskRC(string(buf[:]))
+	task.mapValues()
 
 	return &task, nil
 }
 
-// Map buffer values to given TaskRC struct.
+// Map buffer values to given TaskRC struct. Relative include paths are resolved against directory of ConfigPath.
 func (c *TaskRC) MapTaskRC(buf string) error {
-	// Since we need a little part of all available configuration values we can just traverse line-by-line and check
-	// that key from this line represents in out structure. Otherwise skip this line and continue.
-	avaialbleKeys := GetAvailableKeys()
-	lines := strings.Split(buf, `\n`)
-	for _, line := range lines {
+	var stack []string
+	dir := "."
+	if c.ConfigPath != "" {
+		stack = []string{absPath(c.ConfigPath)}
+		dir = filepath.Dir(c.ConfigPath)
+	}
+	err := c.parseBuffer(buf, dir, stack)
+	c.mapValues()
+	return err
+}
+
+// Read configuration file and store its values. Stack contains absolute paths of files that include this one and
+// is used to detect include cycles.
+func (c *TaskRC) parseFile(path string, stack []string) error {
+	abs := absPath(path)
+	for _, p := range stack {
+		if p == abs {
+			return fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+
+	// Read the given configuration file content in temporary buffer
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return c.parseBuffer(string(buf), filepath.Dir(path), append(stack[:len(stack):len(stack)], abs))
+}
+
+// Parse configuration file content. Dir is used to resolve relative include paths.
+func (c *TaskRC) parseBuffer(buf string, dir string, stack []string) error {
+	origin := OriginFile
+	if len(stack) > 1 {
+		origin = OriginInclude
+	}
+
+	lines := strings.Split(strings.ReplaceAll(buf, "\r\n", "\n"), "\n")
+	for n, line := range lines {
 		// Remove comments
 		line = StripComments(line)
 
 		// Here is an empty line: continue
-		if len(line) == 0 {
+		if len(strings.TrimSpace(line)) == 0 {
 			continue
 		}
 
 		// Is there include pattern?
 		res := reInclude.FindStringSubmatch(line)
 		if len(res) >= 2 {
-			continue // TODO: Not implemented
+			path, err := resolveInclude(res[1], dir)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if err := c.parseFile(path, stack); err != nil {
+				return err
+			}
+			continue
 		}
 
 		// Is there regular configuration entry?
 		res = reEntry.FindStringSubmatch(line)
 		if len(res) >= 3 {
-			// Fill the structure
-			keyTag, val := res[1], res[2]
-			for _, k := range avaialbleKeys {
-				// Check field tag
-				field, _ := reflect.TypeOf(c).Elem().FieldByName(k)
-				tag := field.Tag
-				if tag.Get("taskwarrior") != keyTag {
-					continue
-				}
-
-				// Set the value
-				ps := reflect.ValueOf(c)
-				s := ps.Elem()
-				if s.Kind() == reflect.Struct {
-					f := s.FieldByName(k)
-					if f.IsValid() {
-						if f.CanSet() {
-							if f.Kind() == reflect.String {
-								f.SetString(val)
-							}
-						}
-					}
-				}
-			}
+			c.set(res[1], unescapeValue(res[2]), origin)
 		}
 	}
 
 	return nil
 }
 
+// Fill the structure fields from parsed values.
+func (c *TaskRC) mapValues() {
+	// Since we need a little part of all available configuration values we can just traverse available keys and
+	// check that each of them represents in parsed values.
+	s := reflect.ValueOf(c).Elem()
+	for _, k := range mappedKeys() {
+		field, _ := s.Type().FieldByName(k)
+		val, ok := c.values[field.Tag.Get("taskwarrior")]
+		if !ok {
+			continue
+		}
+
+		// Set the value
+		f := s.FieldByName(k)
+		if f.IsValid() && f.CanSet() && f.Kind() == reflect.String {
+			f.SetString(val)
+		}
+	}
+
+	for key, val := range c.values {
+		if uda := reUDAType.FindStringSubmatch(key); uda != nil {
+			if c.UDA == nil {
+				c.UDA = map[string]string{}
+			}
+			c.UDA[uda[1]] = val
+		}
+	}
+}
+
+// Find included file. Relative paths are resolved against directory of the including file first, then against
+// IncludeSearchPaths.
+func resolveInclude(path string, dir string) (string, error) {
+	path = PathExpandTilda(path)
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	candidates := []string{filepath.Join(dir, path)}
+	for _, searchPath := range IncludeSearchPaths {
+		candidates = append(candidates, filepath.Join(searchPath, path))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("can't find included file '%s'", path)
+}
+
+// Decode \uNNNN escape sequences in a value.
+func unescapeValue(val string) string {
+	return reUnicodeEscape.ReplaceAllStringFunc(val, func(esc string) string {
+		r, err := strconv.ParseUint(esc[2:], 16, 32)
+		if err != nil {
+			return esc
+		}
+		return string(rune(r))
+	})
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// Return value of configuration option and whether it was set. Blank entries (`<name> =`) are reported as set.
+func (c *TaskRC) Lookup(key string) (string, bool) {
+	val, ok := c.values[key]
+	return val, ok
+}
+
+// Return value of configuration option or empty string if it is not set.
+func (c *TaskRC) Get(key string) string {
+	return c.values[key]
+}
+
+// Return boolean value of configuration option. Taskwarrior treats "on", "yes", "y", "1" and "true" as true and
+// anything else as false.
+func (c *TaskRC) GetBool(key string) bool {
+	return parseBool(c.values[key])
+}
+
+func parseBool(val string) bool {
+	switch strings.ToLower(val) {
+	case "on", "yes", "y", "1", "true":
+		return true
+	}
+	return false
+}
+
+// Return integer value of configuration option.
+func (c *TaskRC) GetInt(key string) (int, error) {
+	val, ok := c.values[key]
+	if !ok {
+		return 0, fmt.Errorf("configuration option '%s' is not set", key)
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("configuration option '%s' is not an integer: '%s'", key, val)
+	}
+	return i, nil
+}
+
+// Return comma-separated list value of configuration option. Empty values result in nil.
+func (c *TaskRC) GetList(key string) []string {
+	val := c.values[key]
+	if val == "" {
+		return nil
+	}
+	list := strings.Split(val, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// Return names of all configuration options that were set, in sorted order.
+func (c *TaskRC) Keys() []string {
+	keys := make([]string, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Return directory with hook scripts: `hooks.location` or the hooks subdirectory of `data.location`.
+func (c *TaskRC) HooksDir() string {
+	if c != nil && c.HooksLocation != "" {
+		return PathExpandTilda(c.HooksLocation)
+	}
+	data := c.valueOrDefault("data.location")
+	if c != nil && c.DataLocation != "" {
+		data = c.DataLocation
+	}
+	return filepath.Join(PathExpandTilda(data), "hooks")
+}
+
 // Return list of available configuration options represented by TaskRC structure fields.
 func GetAvailableKeys() []string {
 	var availableKeys []string
@@ -158,11 +337,26 @@ func GetAvailableKeys() []string {
 	s := reflect.ValueOf(t).Elem()
 	typeOf := s.Type()
 	for i := 0; i < s.NumField(); i++ {
+		if !typeOf.Field(i).IsExported() {
+			continue
+		}
 		availableKeys = append(availableKeys, typeOf.Field(i).Name)
 	}
 	return availableKeys
 }
 
+// Return names of TaskRC fields mapped to configuration options with `taskwarrior` tag.
+func mappedKeys() []string {
+	var keys []string
+	typeOf := reflect.TypeOf(TaskRC{})
+	for i := 0; i < typeOf.NumField(); i++ {
+		if typeOf.Field(i).Tag.Get("taskwarrior") != "" {
+			keys = append(keys, typeOf.Field(i).Name)
+		}
+	}
+	return keys
+}
+
 // Remove commented part of input string.
 func StripComments(line string) string {
 	newLine := line
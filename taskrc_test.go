@@ -5,6 +5,7 @@ package taskwarrior
 
 import (
 	"os/user"
+	"strings"
 	"testing"
 )
 
@@ -30,7 +31,7 @@ func TestPathExpandTilda(t *testing.T) {
 }
 
 func TestGetAvailableKeys(t *testing.T) {
-	expected := []string{"DataLocation"}
+	expected := []string{"ConfigPath", "DataLocation", "UDA"}
 	result := GetAvailableKeys()
 	found := false
 	for _, vE := range expected {
@@ -72,6 +73,20 @@ func TestTaskRC_MapTaskRC(t *testing.T) {
 	if taskrc1.DataLocation != expected1 {
 		t.Errorf("Incorrect map for DataLocation: expected '%s' got '%s'", expected1, taskrc1.DataLocation)
 	}
+
+	// Multiple lines, blank values and escapes
+	orig2 := "data.location=/home/tester/data\n  default.project = home \n\ndata.location=\nlabel=\\u0041\\u00e9\n"
+	taskrc2 := &TaskRC{}
+	taskrc2.MapTaskRC(orig2)
+	if taskrc2.DataLocation != "" {
+		t.Errorf("Blank entry should override value, got '%s'", taskrc2.DataLocation)
+	}
+	if taskrc2.Get("default.project") != "home" {
+		t.Errorf("Incorrect value for default.project: expected 'home' got '%s'", taskrc2.Get("default.project"))
+	}
+	if taskrc2.Get("label") != "Aé" {
+		t.Errorf("Incorrect unicode escape: expected 'Aé' got '%s'", taskrc2.Get("label"))
+	}
 }
 
 func TestParseTaskRC(t *testing.T) {
@@ -108,3 +123,106 @@ func TestParseTaskRC(t *testing.T) {
 		t.Errorf("Read configuration file '%s' content without permissions?", config3)
 	}
 }
+
+func TestParseTaskRC_Full(t *testing.T) {
+	config := "./fixtures/taskrc/include_1"
+	result, err := ParseTaskRC(config)
+	if err != nil {
+		t.Fatalf("Can't parse configuration file %s with following error: %v", config, err)
+	}
+
+	expected := map[string]string{
+		"data.location":           "./fixtures/data_1",
+		"report.next.filter":      "status:pending  limit:page",
+		"default.project":         "inbox",
+		"weekstart":               "monday",
+		"color.alternate":         "",
+		"uda.estimate.label":      "Esté",
+		"urgency.due.coefficient": "12.0",
+	}
+	for key, val := range expected {
+		got, ok := result.Lookup(key)
+		if !ok || got != val {
+			t.Errorf("Incorrect value of '%s': expected '%s' got '%s' (set: %v)", key, val, got, ok)
+		}
+	}
+	if _, ok := result.Lookup("include"); ok {
+		t.Error("Include directive was parsed as configuration entry")
+	}
+
+	// Structure fields
+	if result.DataLocation != "./fixtures/data_1" || result.RecallAfter != "3" {
+		t.Errorf("Structure fields were not filled: %+v", result)
+	}
+	if result.UDA["estimate"] != "numeric" {
+		t.Errorf("UDA types were not filled: %v", result.UDA)
+	}
+
+	// Typed getters
+	if !result.GetBool("search.case.sensitive") || result.GetBool("color.alternate") {
+		t.Error("Incorrect boolean values")
+	}
+	if limit, err := result.GetInt("recurrence.limit"); err != nil || limit != 3 {
+		t.Errorf("Incorrect integer value: %d (%v)", limit, err)
+	}
+	if _, err := result.GetInt("default.project"); err == nil {
+		t.Error("GetInt accepts non-integer value")
+	}
+	if _, err := result.GetInt("not.set"); err == nil {
+		t.Error("GetInt accepts missing value")
+	}
+	columns := result.GetList("report.list.columns")
+	if len(columns) != 3 || columns[0] != "id" || columns[1] != "description" || columns[2] != "project" {
+		t.Errorf("Incorrect list value: %v", columns)
+	}
+	if result.GetList("color.alternate") != nil {
+		t.Error("Blank list value should be nil")
+	}
+	if len(result.Keys()) != 11 {
+		t.Errorf("Expected 11 keys, got %d: %v", len(result.Keys()), result.Keys())
+	}
+}
+
+func TestParseTaskRC_Redundant(t *testing.T) {
+	// The last value wins
+	result, err := ParseTaskRC("./fixtures/taskrc/redundant_values_1")
+	if err != nil {
+		t.Fatalf("Can't parse configuration file: %v", err)
+	}
+	if result.DataLocation != "./fixtures/data_2" {
+		t.Errorf("Incorrect redundant DataLocation: expected './fixtures/data_2' got '%s'", result.DataLocation)
+	}
+	if result.Get("useless") != "1911" {
+		t.Errorf("Unknown keys should be available, got '%s'", result.Get("useless"))
+	}
+}
+
+func TestParseTaskRC_IncludeErrors(t *testing.T) {
+	_, err := ParseTaskRC("./fixtures/taskrc/err_include_cycle_1")
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Include cycle was not detected: %v", err)
+	}
+
+	_, err = ParseTaskRC("./fixtures/taskrc/err_include_paths_1")
+	if err == nil {
+		t.Error("Missing included file was not reported")
+	}
+}
+
+func TestTaskRC_HooksDir(t *testing.T) {
+	userDir, _ := user.Current()
+	home := userDir.HomeDir
+	tests := map[string]string{
+		"":                        home + "/.task/hooks",
+		"data.location=/var/task": "/var/task/hooks",
+		"hooks.location=~/hooks":  home + "/hooks",
+		"data.location=/var/task\nhooks.location=/etc/task/hooks": "/etc/task/hooks",
+	}
+	for buf, expected := range tests {
+		rc := &TaskRC{}
+		rc.MapTaskRC(buf)
+		if dir := rc.HooksDir(); dir != expected {
+			t.Errorf("%q: expected %s, got %s", buf, expected, dir)
+		}
+	}
+}
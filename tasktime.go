@@ -0,0 +1,113 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Date values of taskwarrior tasks.
+//
+// Taskwarrior stores dates in UTC with a compact ISO 8601 form: YYYYMMDDTHHMMSSZ. Older versions exported dates as
+// Unix timestamps, which are accepted on decoding too.
+
+package taskwarrior
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Layout of dates used by taskwarrior JSON format.
+const dateLayout = "20060102T150405Z"
+
+// TaskTime is a date attribute of a task. Zero value means that the attribute is not set.
+type TaskTime struct {
+	time.Time
+}
+
+// Create TaskTime from given time. Taskwarrior dates have one second precision, so the rest is truncated.
+func NewTaskTime(t time.Time) TaskTime {
+	if t.IsZero() {
+		return TaskTime{}
+	}
+	return TaskTime{t.UTC().Truncate(time.Second)}
+}
+
+// Parse date in taskwarrior format or as a Unix timestamp. Empty string results in zero TaskTime.
+func ParseTaskTime(s string) (TaskTime, error) {
+	if s == "" {
+		return TaskTime{}, nil
+	}
+	if epoch, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return TaskTime{time.Unix(epoch, 0).UTC()}, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return TaskTime{}, fmt.Errorf("invalid taskwarrior date '%s'", s)
+	}
+	return TaskTime{t}, nil
+}
+
+// Same as ParseTaskTime, but panics if the date can't be parsed. Simplifies initialization of tasks with constant
+// dates.
+func MustParseTaskTime(s string) TaskTime {
+	t, err := ParseTaskTime(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Return date in taskwarrior format or empty string for zero TaskTime.
+func (t TaskTime) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(dateLayout)
+}
+
+// MarshalJSON encodes the date in taskwarrior format.
+func (t TaskTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes the date from taskwarrior format string or Unix timestamp number.
+func (t *TaskTime) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*t = TaskTime{}
+		return nil
+	}
+
+	var s string
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	} else {
+		s = string(data)
+	}
+
+	parsed, err := ParseTaskTime(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// Check whether the task is pending and its due date is before given time.
+func (t *Task) IsOverdue(now time.Time) bool {
+	if t.Due.IsZero() || (t.Status != "pending" && t.Status != "waiting") {
+		return false
+	}
+	return t.Due.Before(now)
+}
+
+// Return time remaining until the due date of the task, negative for overdue tasks. Returns zero if the task has
+// no due date.
+func (t *Task) DueIn(now time.Time) time.Duration {
+	if t.Due.IsZero() {
+		return 0
+	}
+	return t.Due.Sub(now)
+}
@@ -0,0 +1,96 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTaskTime(t *testing.T) {
+	expected := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+
+	result, err := ParseTaskTime("20260206T120000Z")
+	if err != nil || !result.Equal(expected) {
+		t.Errorf("Incorrect parse of compact date: expected %v got %v (%v)", expected, result, err)
+	}
+
+	// Unix timestamps of old taskwarrior versions
+	result, err = ParseTaskTime("1770379200")
+	if err != nil || !result.Equal(expected) {
+		t.Errorf("Incorrect parse of epoch date: expected %v got %v (%v)", expected, result, err)
+	}
+
+	result, err = ParseTaskTime("")
+	if err != nil || !result.IsZero() {
+		t.Errorf("Empty string should result in zero date, got %v (%v)", result, err)
+	}
+
+	_, err = ParseTaskTime("2026-02-06")
+	if err == nil {
+		t.Error("ParseTaskTime accepts date in unsupported format")
+	}
+
+	if s := NewTaskTime(expected.Add(500 * time.Millisecond)).String(); s != "20260206T120000Z" {
+		t.Errorf("Incorrect date format: expected '20260206T120000Z' got '%s'", s)
+	}
+}
+
+func TestTaskTime_JSON(t *testing.T) {
+	var task Task
+	data := `{"description":"Dates","entry":"20260206T120000Z","due":1770379200,"wait":null,` +
+		`"annotations":[{"entry":"20260206T120500Z","description":"Note"}]}`
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		t.Fatalf("Can't decode task: %v", err)
+	}
+	if task.Entry.String() != "20260206T120000Z" || task.Due.String() != "20260206T120000Z" {
+		t.Errorf("Incorrect dates: entry %s, due %s", task.Entry, task.Due)
+	}
+	if !task.Wait.IsZero() {
+		t.Errorf("Null date should be zero, got %s", task.Wait)
+	}
+	if task.Annotations[0].Entry.String() != "20260206T120500Z" {
+		t.Errorf("Incorrect annotation date: %s", task.Annotations[0].Entry)
+	}
+
+	buf, err := json.Marshal(task)
+	if err != nil {
+		t.Fatalf("Can't encode task: %v", err)
+	}
+	var obj map[string]interface{}
+	json.Unmarshal(buf, &obj)
+	if obj["due"] != "20260206T120000Z" {
+		t.Errorf("Due date was encoded incorrectly: %s", buf)
+	}
+	if _, ok := obj["wait"]; ok {
+		t.Errorf("Unset dates should be omitted: %s", buf)
+	}
+
+	if err := json.Unmarshal([]byte(`{"due":"tomorrow"}`), &task); err == nil {
+		t.Error("Invalid date was decoded without errors")
+	}
+}
+
+func TestTask_IsOverdue(t *testing.T) {
+	now := time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)
+	task := &Task{Status: "pending", Due: MustParseTaskTime("20260209T120000Z")}
+
+	if !task.IsOverdue(now) {
+		t.Error("Pending task with due date in the past should be overdue")
+	}
+	if task.DueIn(now) != -12*time.Hour {
+		t.Errorf("DueIn mismatch: expected -12h, got %s", task.DueIn(now))
+	}
+
+	task.Status = "completed"
+	if task.IsOverdue(now) {
+		t.Error("Completed task can't be overdue")
+	}
+
+	task = &Task{Status: "pending"}
+	if task.IsOverdue(now) || task.DueIn(now) != 0 {
+		t.Error("Task without due date can't be overdue")
+	}
+}
@@ -10,86 +10,366 @@
 package taskwarrior
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"strings"
+	"time"
 )
 
+// Default time limit for a single `task` command call of new TaskWarrior instances. Zero means no limit.
+var DefaultTimeout = time.Minute
+
 // Represents a single taskwarrior instance.
 type TaskWarrior struct {
-	Config *TaskRC // Configuration options
-	Tasks  []Task  // Task JSON entries
+	Config        *TaskRC       // Configuration options
+	Tasks         []Task        // Task JSON entries
+	Runner        Runner        // Executor for `task` commands, DefaultRunner if nil
+	Timeout       time.Duration // Time limit for a single `task` command call, no limit if zero
+	Backend       Backend       // Source of tasks used instead of `task export` if set
+	WatchInterval time.Duration // Polling period of Watch, DefaultWatchInterval if zero
+
+	snapshot      map[string]string // Fingerprints of fetched or committed tasks, keyed by UUID
+	version       Version           // Detected taskwarrior version
+	versionErr    error             // Error of version detection
+	versionRunner Runner            // Runner the version was detected with
+}
+
+// Result of Commit: UUIDs of saved tasks, grouped by the action taken by taskwarrior.
+type CommitResult struct {
+	Added    []string // New tasks
+	Modified []string // Stored tasks that were changed
+	Skipped  []string // Tasks identical to the stored ones
 }
 
-// Create new empty TaskWarrior instance.
+// Create new empty TaskWarrior instance. Configuration is resolved with ResolveTaskRC, so empty path means the rc
+// file taskwarrior itself would use. Taskwarrior version is detected on the first command that depends on it, so
+// Runner and Backend can be set before anything is run.
 func NewTaskWarrior(configPath string) (*TaskWarrior, error) {
 	// Read the configuration file.
-	taskRC, err := ParseTaskRC(configPath)
+	taskRC, err := ResolveTaskRC(ConfigSources{Path: configPath})
 	if err != nil {
 		return nil, err
 	}
 
 	// Create new TaskWarrior instance.
-	tw := &TaskWarrior{Config: taskRC}
-	return tw, nil
+	return &TaskWarrior{Config: taskRC, Timeout: DefaultTimeout}, nil
 }
 
 // Fetch all tasks for given TaskWarrior with system `taskwarrior` command call.
 func (tw *TaskWarrior) FetchAllTasks() error {
+	return tw.FetchAllTasksContext(context.Background())
+}
+
+// Same as FetchAllTasks, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) FetchAllTasksContext(ctx context.Context) error {
 	if tw == nil {
 		return fmt.Errorf("Uninitialized taskwarrior database!")
 	}
 
-	rcOpt := "rc:" + tw.Config.ConfigPath
-	out, err := exec.Command("task", rcOpt, "export").Output()
-	if err != nil {
-		return err
+	if tw.Backend != nil {
+		tasks, err := tw.Backend.List(ctx)
+		if err != nil {
+			return err
+		}
+		tw.Tasks = tasks
+		tw.resetChanges()
+		return nil
 	}
-	err = json.Unmarshal([]byte(out), &tw.Tasks)
+
+	tasks, err := tw.export(ctx)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
+	tw.Tasks = tasks
+	tw.resetChanges()
 	return nil
 }
 
+// Export tasks matching given filter arguments with `task export` command.
+func (tw *TaskWarrior) export(ctx context.Context, filter ...string) ([]Task, error) {
+	rcOpt := "rc:" + tw.Config.ConfigPath
+	args := append([]string{rcOpt}, filter...)
+	caps, err := tw.capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !caps.JSONArray {
+		args = append(args, "rc.json.array=on")
+	}
+	out, err := tw.run(ctx, nil, append(args, "export")...)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+	err = json.Unmarshal(out, &tasks)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse exported tasks: %w", err)
+	}
+	for i := range tasks {
+		tw.Config.ConvertUDA(&tasks[i])
+	}
+	return tasks, nil
+}
+
+// Execute `task` command with given arguments using runner of the instance. Non-zero exit status of the command is
+// reported as *CommandError, interruption by the context or the instance timeout as *TimeoutError.
+func (tw *TaskWarrior) run(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	if tw.Config != nil {
+		args = append(tw.Config.OverrideArgs(), args...)
+	}
+	return tw.exec(ctx, stdin, args...)
+}
+
+// Same as run, but configuration overrides are not passed.
+func (tw *TaskWarrior) exec(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	runner := tw.runner()
+	if tw.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tw.Timeout)
+		defer cancel()
+	}
+
+	out, err := runner.Run(ctx, Invocation{Args: args, Stdin: stdin})
+	// Run that completed before the context was done is not a timeout.
+	if ctxErr := ctx.Err(); ctxErr != nil && (err != nil || out.ExitCode != 0) {
+		return nil, &TimeoutError{Args: args, Timeout: tw.Timeout, Err: ctxErr}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if out.ExitCode != 0 {
+		return nil, &CommandError{Args: args, ExitCode: out.ExitCode, Stdout: out.Stdout, Stderr: out.Stderr}
+	}
+	return out.Stdout, nil
+}
+
+// Return runner of the instance or DefaultRunner.
+func (tw *TaskWarrior) runner() Runner {
+	if tw.Runner == nil {
+		return DefaultRunner
+	}
+	return tw.Runner
+}
+
 // Pretty print for all tasks represented in given TaskWarrior.
 func (tw *TaskWarrior) PrintTasks() {
 	out, _ := json.MarshalIndent(tw.Tasks, "", "\t")
 	os.Stdout.Write(out)
 }
 
-// Add new Task entry to given TaskWarrior.
+// Add new Task entry to given TaskWarrior. Task without UUID gets a new one, so it can be tracked by Commit.
 func (tw *TaskWarrior) AddTask(task *Task) {
+	if task.Uuid == "" {
+		task.Uuid = NewUUID()
+	}
 	tw.Tasks = append(tw.Tasks, *task)
 }
 
-// Save current changes of given TaskWarrior instance.
-func (tw *TaskWarrior) Commit() error {
-	tasks, err := json.Marshal(tw.Tasks)
+// Return copies of tasks that were added or changed since the last fetch or commit. Before the first fetch every
+// task is considered changed.
+func (tw *TaskWarrior) DirtyTasks() []Task {
+	var dirty []Task
+	for i := range tw.Tasks {
+		if tw.isDirty(&tw.Tasks[i]) {
+			dirty = append(dirty, tw.Tasks[i])
+		}
+	}
+	return dirty
+}
+
+// Save tasks that were added or changed since the last fetch or commit. Returns *CycleError without saving anything
+// if dependencies of the tasks have a cycle. On other errors no result is returned; with a Backend the tasks saved
+// before the error are not dirty any more, so the next commit saves only the rest.
+func (tw *TaskWarrior) Commit() (*CommitResult, error) {
+	return tw.CommitContext(context.Background())
+}
+
+// Same as Commit, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) CommitContext(ctx context.Context) (*CommitResult, error) {
+	var dirty []*Task
+	for i := range tw.Tasks {
+		if tw.Tasks[i].Uuid == "" {
+			tw.Tasks[i].Uuid = NewUUID()
+		}
+		if tw.isDirty(&tw.Tasks[i]) {
+			dirty = append(dirty, &tw.Tasks[i])
+		}
+	}
+	result := &CommitResult{}
+	if len(dirty) == 0 {
+		return result, nil
+	}
+	if err := tw.DependencyGraph().DetectCycle(); err != nil {
+		return nil, err
+	}
+
+	if tw.Backend != nil {
+		if err := tw.commitBackend(ctx, dirty, result); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	caps, err := tw.capabilities(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var buf []byte
+	for _, task := range dirty {
+		line, err := EncodeTask(task, caps)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(buf, line...), '\n')
 	}
 
-	cmd := exec.Command("task", "import", "-")
-	cmd.Stdin = bytes.NewBuffer(tasks)
-	err = cmd.Run()
+	rcOpt := "rc:" + tw.Config.ConfigPath
+	out, err := tw.run(ctx, buf, rcOpt, "rc.confirmation=off", "import", "-")
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	actions := parseImportOutput(out)
+	for _, task := range dirty {
+		action, ok := actions[task.Uuid]
+		if !ok {
+			// Output format is unknown, guess from the state of the last fetch.
+			action = "add"
+			if _, known := tw.snapshot[task.Uuid]; known {
+				action = "mod"
+			}
+		}
+		result.add(action, task.Uuid)
+		tw.markSaved(task)
 	}
+	return result, nil
+}
 
+// Save changed tasks with the backend of the instance, replacing them with their stored versions.
+func (tw *TaskWarrior) commitBackend(ctx context.Context, dirty []*Task, result *CommitResult) error {
+	for _, task := range dirty {
+		stored, err := tw.Backend.Get(ctx, task.Uuid)
+		saved, action := stored, "skip"
+		switch {
+		case errors.Is(err, ErrNoMatchingTasks):
+			saved, err = tw.Backend.Add(ctx, task)
+			action = "add"
+		case err != nil:
+		case taskFingerprint(stored) != taskFingerprint(task):
+			saved, err = tw.Backend.Modify(ctx, task)
+			action = "mod"
+		}
+		if err != nil {
+			return err
+		}
+		result.add(action, task.Uuid)
+		*task = *saved
+		tw.markSaved(task)
+	}
 	return nil
 }
 
+func (r *CommitResult) add(action, uuid string) {
+	switch action {
+	case "add":
+		r.Added = append(r.Added, uuid)
+	case "mod":
+		r.Modified = append(r.Modified, uuid)
+	case "skip":
+		r.Skipped = append(r.Skipped, uuid)
+	}
+}
+
+// Parse lines like ` add  <uuid> <description>` of `task import` output. Returns action for every reported UUID.
+func parseImportOutput(out []byte) map[string]string {
+	actions := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "add", "mod", "skip":
+			actions[fields[1]] = fields[0]
+		}
+	}
+	return actions
+}
+
+// Consider current tasks as stored.
+func (tw *TaskWarrior) resetChanges() {
+	tw.snapshot = make(map[string]string, len(tw.Tasks))
+	for i := range tw.Tasks {
+		tw.markSaved(&tw.Tasks[i])
+	}
+}
+
+func (tw *TaskWarrior) markSaved(task *Task) {
+	if tw.snapshot == nil {
+		tw.snapshot = map[string]string{}
+	}
+	tw.snapshot[task.Uuid] = taskFingerprint(task)
+}
+
+func (tw *TaskWarrior) isDirty(task *Task) bool {
+	fingerprint, ok := tw.snapshot[task.Uuid]
+	return !ok || task.Uuid == "" || fingerprint != taskFingerprint(task)
+}
+
+// Return representation of stored task attributes. ID and urgency are computed by taskwarrior and ignored.
+func taskFingerprint(task *Task) string {
+	t := *task
+	t.Id = 0
+	t.Urgency = 0
+	buf, _ := json.Marshal(t)
+	return string(buf)
+}
+
 // Filter represents query parameters for filtering tasks.
-// Used with QueryTasks() to filter exported task data.
+// Used with QueryTasks() to filter exported task data. All set fields have to match.
 type Filter struct {
-	Project string // Filter by project (e.g., "MyProject")
-	Tags    []string // Filter by tags (e.g., ["urgent", "work"])
+	Project string   // Filter by project (e.g., "MyProject")
+	Tags    []string // Filter by tags, all of them are required (e.g., ["urgent", "work"])
 	Status  string   // Filter by status (e.g., "pending", "completed")
-	UUIDs   []string // Filter by specific UUIDs (e.g., ["uuid1", "uuid2"])
+	UUIDs   []string // Filter by specific UUIDs, any of them matches (e.g., ["uuid1", "uuid2"])
+	Expr    Expr     // Arbitrary filter expression (e.g., Or(Tag(VirtualOverdue), Project("inbox")))
+}
+
+// Return the filter as an expression.
+func (f Filter) Expression() Expr {
+	var exprs AndExpr
+	if f.Project != "" {
+		exprs = append(exprs, Project(f.Project))
+	}
+	for _, tag := range f.Tags {
+		exprs = append(exprs, Tag(tag))
+	}
+	if f.Status != "" {
+		exprs = append(exprs, Status(f.Status))
+	}
+	if len(f.UUIDs) > 0 {
+		var uuids OrExpr
+		for _, uuid := range f.UUIDs {
+			uuids = append(uuids, UUID(uuid))
+		}
+		exprs = append(exprs, uuids)
+	}
+	if f.Expr != nil {
+		exprs = append(exprs, f.Expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+	return exprs
+}
+
+// Return `task` command-line arguments of the filter.
+func (f Filter) Args() []string {
+	return f.Expression().Args()
 }
 
 // QueryTasks retrieves tasks matching the specified filters using Taskwarrior's
@@ -102,49 +382,15 @@ type Filter struct {
 //
 // For more filter syntax, see: https://taskwarrior.org/docs/userguide/#filtering-tasks
 func (tw *TaskWarrior) QueryTasks(filter Filter) ([]Task, error) {
+	return tw.QueryTasksContext(context.Background(), filter)
+}
+
+// Same as QueryTasks, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) QueryTasksContext(ctx context.Context, filter Filter) ([]Task, error) {
 	if tw == nil {
 		return nil, fmt.Errorf("Uninitialized taskwarrior database!")
 	}
 
-	// Build filter string for taskwarrior command
-	args := []string{}
-
-	// Add filters before export subcommand
-	if filter.Project != "" {
-		args = append(args, fmt.Sprintf("project:%s", filter.Project))
-	}
-
-	for _, tag := range filter.Tags {
-		args = append(args, fmt.Sprintf("+%s", tag))
-	}
-
-	if filter.Status != "" {
-		args = append(args, fmt.Sprintf("status:%s", filter.Status))
-	}
-
-	for _, uuid := range filter.UUIDs {
-		args = append(args, fmt.Sprintf("uuid:%s", uuid))
-	}
-
-	// Add export subcommand
-	args = append(args, "export")
-
-	rcOpt := "rc:" + tw.Config.ConfigPath
-	args = append([]string{rcOpt}, args...)
-
 	// Execute task command with filters
-	cmd := exec.Command("task", args...)
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	// Unmarshal results
-	var tasks []Task
-	err = json.Unmarshal([]byte(out), &tasks)
-	if err != nil {
-		return nil, err
-	}
-
-	return tasks, nil
+	return tw.export(ctx, filter.Args()...)
 }
@@ -4,9 +4,17 @@
 package taskwarrior
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Helper that executes `task` with selected config path and return result as new TaskRC instances array.
@@ -67,7 +75,7 @@ func TestTaskWarrior_FetchAllTasks(t *testing.T) {
 		Description: "Test task",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 		Tags: []string{"test", "fixture"},
 		UDA: map[string]interface{}{
 			"custom_field": "test_value",
@@ -93,6 +101,22 @@ func TestTaskWarrior_FetchAllTasks(t *testing.T) {
 		t.Errorf("UDA mismatch: expected 'test_value', got '%v'", tw1.Tasks[0].UDA["custom_field"])
 	}
 
+	// Read tasks from the database
+	tw1.Runner = NewFakeRunner(*task)
+	err = tw1.FetchAllTasks()
+	if err != nil {
+		t.Errorf("FetchAllTasks fails with following error: %s", err)
+	}
+	if len(tw1.Tasks) != 1 || tw1.Tasks[0].Uuid != task.Uuid {
+		t.Errorf("FetchAllTasks returned unexpected tasks: %v", tw1.Tasks)
+	}
+	if tw1.Tasks[0].Id != 1 {
+		t.Errorf("Expected pending task to have ID 1, got %d", tw1.Tasks[0].Id)
+	}
+	if tw1.Tasks[0].UDA["custom_field"] != "test_value" {
+		t.Errorf("UDA was not fetched: expected 'test_value', got '%v'", tw1.Tasks[0].UDA["custom_field"])
+	}
+
 	// Uninitilized database error handling
 	tw_buggy, _ := NewTaskWarrior("/tmp/does/not/exists")
 	err = tw_buggy.FetchAllTasks()
@@ -112,7 +136,7 @@ func TestTaskWarrior_AddTask(t *testing.T) {
 		Description: "Test task",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	}
 
 	tw1.AddTask(t1)
@@ -121,3 +145,239 @@ func TestTaskWarrior_AddTask(t *testing.T) {
 		t.Errorf("Expected 1 task, got %d", len(tw1.Tasks))
 	}
 }
+
+func TestTaskWarrior_Commit(t *testing.T) {
+	tw1 := newFakeTaskWarrior(t)
+	tw1.AddTask(&Task{
+		Description: "Committed task",
+		Status:      "pending",
+		Uuid:        "00000000-0000-0000-0000-000000000001",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
+	})
+
+	result, err := tw1.Commit()
+	if err != nil {
+		t.Fatalf("Commit fails with following error: %s", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("Unexpected commit result: %+v", result)
+	}
+
+	stored := tw1.Runner.(*FakeRunner).Tasks()
+	if len(stored) != 1 || stored[0].Description != "Committed task" {
+		t.Errorf("Commit didn't save task to the database: %v", stored)
+	}
+
+	// Unchanged tasks already stored in the database
+	tw2 := newFakeTaskWarrior(t)
+	tw2.Runner = tw1.Runner
+	tw2.AddTask(&tw1.Tasks[0])
+	result, err = tw2.Commit()
+	if err != nil || len(result.Skipped) != 1 || len(result.Added) != 0 {
+		t.Errorf("Expected skipped task, got %+v (%v)", result, err)
+	}
+}
+
+func TestTaskWarrior_CommitIncremental(t *testing.T) {
+	tw1 := newFakeTaskWarrior(t,
+		Task{Description: "First", Status: "pending", Uuid: "00000000-0000-0000-0000-000000000001",
+			Entry: MustParseTaskTime("20260206T120000Z")},
+		Task{Description: "Second", Status: "pending", Uuid: "00000000-0000-0000-0000-000000000002",
+			Entry: MustParseTaskTime("20260206T120000Z")},
+		Task{Description: "Third", Status: "pending", Uuid: "00000000-0000-0000-0000-000000000003",
+			Entry: MustParseTaskTime("20260206T120000Z")},
+	)
+	if err := tw1.FetchAllTasks(); err != nil {
+		t.Fatalf("FetchAllTasks fails with following error: %v", err)
+	}
+	if dirty := tw1.DirtyTasks(); len(dirty) != 0 {
+		t.Errorf("Fetched tasks should not be dirty: %v", dirty)
+	}
+
+	tw1.Tasks[1].Priority = "H"
+	tw1.AddTask(&Task{Description: "Fourth"})
+	if dirty := tw1.DirtyTasks(); len(dirty) != 2 {
+		t.Fatalf("Expected 2 dirty tasks, got %v", dirty)
+	}
+
+	result, err := tw1.Commit()
+	if err != nil {
+		t.Fatalf("Commit fails with following error: %v", err)
+	}
+	if len(result.Modified) != 1 || result.Modified[0] != "00000000-0000-0000-0000-000000000002" {
+		t.Errorf("Unexpected modified tasks: %+v", result)
+	}
+	if len(result.Added) != 1 || result.Added[0] != tw1.Tasks[3].Uuid || len(result.Skipped) != 0 {
+		t.Errorf("Unexpected added tasks: %+v", result)
+	}
+
+	runner := tw1.Runner.(*FakeRunner)
+	call := runner.Calls[len(runner.Calls)-1]
+	expectedArgs := []string{"rc:./fixtures/taskrc/simple_1", "rc.confirmation=off", "import", "-"}
+	if !reflect.DeepEqual(call.Args, expectedArgs) {
+		t.Errorf("Unexpected import arguments: %v", call.Args)
+	}
+	if lines := bytes.Count(call.Stdin, []byte("\n")); lines != 2 {
+		t.Errorf("Expected 2 imported tasks, got %d:\n%s", lines, call.Stdin)
+	}
+	if stored := runner.Tasks(); len(stored) != 4 || stored[1].Priority != "H" {
+		t.Errorf("Changes were not saved: %v", stored)
+	}
+
+	// Nothing to commit
+	calls := len(runner.Calls)
+	result, err = tw1.Commit()
+	if err != nil || len(result.Added)+len(result.Modified)+len(result.Skipped) != 0 || len(runner.Calls) != calls {
+		t.Errorf("Commit without changes should not call taskwarrior: %+v (%v)", result, err)
+	}
+}
+
+func TestTaskWarrior_CommitBackend(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, PendingDataFile), nil, 0600)
+	os.WriteFile(filepath.Join(dir, CompletedDataFile), nil, 0600)
+	tw1 := newFakeTaskWarrior(t)
+	tw1.Config.DataLocation = dir
+	tw1.Backend = NewDataFileBackend(tw1.Config)
+
+	tw1.AddTask(&Task{Description: "First"})
+	result, err := tw1.Commit()
+	if err != nil || len(result.Added) != 1 {
+		t.Fatalf("Unexpected commit result: %+v (%v)", result, err)
+	}
+	if tw1.Tasks[0].Id != 1 || tw1.Tasks[0].Entry.IsZero() {
+		t.Errorf("Task was not replaced with the stored one: %+v", tw1.Tasks[0])
+	}
+
+	tw1.Tasks[0].Project = "work"
+	result, err = tw1.Commit()
+	if err != nil || len(result.Modified) != 1 {
+		t.Fatalf("Unexpected commit result: %+v (%v)", result, err)
+	}
+	if err := tw1.FetchAllTasks(); err != nil || tw1.Tasks[0].Project != "work" {
+		t.Errorf("Changes were not saved: %+v (%v)", tw1.Tasks, err)
+	}
+	if len(tw1.Runner.(*FakeRunner).Calls) != 0 {
+		t.Error("Commit with backend should not call taskwarrior")
+	}
+
+	// Failed commit returns no result, saved tasks are not dirty any more
+	tw1.AddTask(&Task{Description: "Second"})
+	tw1.AddTask(&Task{Description: "Broken", UDA: map[string]interface{}{"sprint": map[string]int{"a": 1}}})
+	if result, err := tw1.Commit(); result != nil || err == nil {
+		t.Errorf("Expected error without result, got %+v (%v)", result, err)
+	}
+	if dirty := tw1.DirtyTasks(); len(dirty) != 1 || dirty[0].Description != "Broken" {
+		t.Errorf("Only the failed task should be dirty: %+v", dirty)
+	}
+}
+
+// Helper that creates TaskWarrior instance backed by in-memory database with given tasks.
+func newFakeTaskWarrior(t *testing.T, tasks ...Task) *TaskWarrior {
+	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
+	if err != nil {
+		t.Fatalf("NewTaskWarrior fails with following error: %s", err)
+	}
+	runner := NewFakeRunner(tasks...)
+	tw.Runner = runner
+
+	// Detect version in advance, so tests see only their own calls.
+	if _, err := tw.Version(); err != nil {
+		t.Fatalf("Version fails with following error: %v", err)
+	}
+	runner.Calls = nil
+	return tw
+}
+
+// Runner that never finishes until its context is done, like `task` waiting for a confirmation.
+type hangingRunner struct{}
+
+func (hangingRunner) Run(ctx context.Context, inv Invocation) (Output, error) {
+	<-ctx.Done()
+	return Output{}, ctx.Err()
+}
+
+func TestTaskWarrior_Timeout(t *testing.T) {
+	tw1 := newFakeTaskWarrior(t)
+	tw1.Runner = hangingRunner{}
+	tw1.Timeout = 10 * time.Millisecond
+
+	err := tw1.FetchAllTasks()
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected TimeoutError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("TimeoutError should wrap context.DeadlineExceeded: %v", err)
+	}
+	if timeoutErr.Timeout != tw1.Timeout || timeoutErr.Args[len(timeoutErr.Args)-1] != "export" {
+		t.Errorf("Unexpected TimeoutError content: %+v", timeoutErr)
+	}
+
+	// Cancellation by the caller
+	tw1.Timeout = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = tw1.QueryTasksContext(ctx, Filter{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+	tw1.AddTask(&Task{Description: "Canceled"})
+	_, err = tw1.CommitContext(ctx)
+	if !errors.As(err, &timeoutErr) {
+		t.Errorf("Expected TimeoutError for canceled commit, got %v", err)
+	}
+
+	// Failures of taskwarrior itself are not timeouts
+	tw1.Runner = NewFakeRunner()
+	tw1.Timeout = time.Second
+	_, err = tw1.run(context.Background(), nil, "unknown-command")
+	if err == nil || errors.As(err, &timeoutErr) {
+		t.Errorf("Expected regular error, got %v", err)
+	}
+
+	// Context done right after the command finished successfully
+	ctx, cancel = context.WithCancel(context.Background())
+	tw1.Runner = finishingRunner{cancel}
+	out, err := tw1.run(ctx, nil, "export")
+	if err != nil || string(out) != "[]" {
+		t.Errorf("Expected output of finished command, got %q (%v)", out, err)
+	}
+}
+
+// Runner that cancels its context after the command finished, like a deadline expiring at that moment.
+type finishingRunner struct {
+	cancel context.CancelFunc
+}
+
+func (r finishingRunner) Run(ctx context.Context, inv Invocation) (Output, error) {
+	r.cancel()
+	return Output{Stdout: []byte("[]")}, nil
+}
+
+func TestTaskWarrior_CommitDependsString(t *testing.T) {
+	// Database of taskwarrior 2.4, which exports depends as a string.
+	runner := NewFakeRunner()
+	runner.Version = "2.4.5"
+	runner.Run(context.Background(), Invocation{Args: []string{"import", "-"}, Stdin: []byte(
+		`{"uuid":"00000000-0000-0000-0000-000000000001","description":"First","status":"pending"}` + "\n" +
+			`{"uuid":"00000000-0000-0000-0000-000000000002","description":"Second","status":"pending",` +
+			`"depends":"00000000-0000-0000-0000-000000000001"}`)})
+	tw1 := newFakeTaskWarrior(t)
+	tw1.Runner = runner
+	if err := tw1.FetchAllTasks(); err != nil {
+		t.Fatalf("FetchAllTasks fails with following error: %v", err)
+	}
+	if len(tw1.Tasks) != 2 || len(tw1.Tasks[1].Depends) != 1 {
+		t.Fatalf("Depends string was not decoded: %+v", tw1.Tasks)
+	}
+
+	tw1.Tasks[1].Priority = "H"
+	if _, err := tw1.Commit(); err != nil {
+		t.Fatalf("Commit fails with following error: %v", err)
+	}
+	stdin := string(runner.Calls[len(runner.Calls)-1].Stdin)
+	if !strings.Contains(stdin, `"depends":"00000000-0000-0000-0000-000000000001"`) {
+		t.Errorf("Depends were not written as string: %s", stdin)
+	}
+}
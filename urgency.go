@@ -0,0 +1,185 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Urgency calculation.
+//
+// Taskwarrior urgency is a sum of terms, each one is a coefficient from the configuration multiplied by a factor
+// between 0 and 1 computed from the task, see https://taskwarrior.org/docs/urgency/. This file reproduces the
+// calculation of taskwarrior 2.6, so urgency of tasks changed in memory can be computed without `task export`.
+// The urgency.inherit option is not supported.
+
+package taskwarrior
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UrgencyCalculator computes urgency of tasks.
+type UrgencyCalculator struct {
+	Config *TaskRC   // Urgency coefficients, built-in defaults if nil
+	Now    time.Time // Time used for due, age and scheduled terms, time.Now if zero
+	Tasks  []Task    // All tasks of the database, used for blocked and blocking terms
+
+	matcher *Matcher
+}
+
+// Compute urgency of the task with coefficients of given configuration. The task is considered blocked if it has
+// dependencies, and it can't be considered blocking, because other tasks are unknown. Use UrgencyCalculator to take
+// the whole database into account.
+func Urgency(task *Task, config *TaskRC) float64 {
+	c := &UrgencyCalculator{Config: config}
+	return c.Urgency(task)
+}
+
+// Compute urgency of the task.
+func (c *UrgencyCalculator) Urgency(task *Task) float64 {
+	if c.matcher == nil {
+		c.matcher = &Matcher{Config: c.Config, Now: c.Now, Tasks: c.Tasks}
+	}
+	m := c.matcher
+	now := m.now()
+	coef := func(key string) float64 {
+		f, _ := strconv.ParseFloat(c.Config.valueOrDefault(key), 64)
+		return f
+	}
+	boolFactor := func(b bool) float64 {
+		if b {
+			return 1.0
+		}
+		return 0.0
+	}
+
+	urgency := 0.0
+	urgency += coef("urgency.project.coefficient") * boolFactor(task.Project != "")
+	urgency += coef("urgency.active.coefficient") * boolFactor(!task.Start.IsZero())
+	urgency += coef("urgency.scheduled.coefficient") * boolFactor(!task.Scheduled.IsZero() && task.Scheduled.Before(now))
+	urgency += coef("urgency.waiting.coefficient") * boolFactor(virtualTags[VirtualWaiting](m, task))
+	urgency += coef("urgency.annotations.coefficient") * countFactor(len(task.Annotations))
+	urgency += coef("urgency.tags.coefficient") * countFactor(len(task.Tags))
+	urgency += coef("urgency.due.coefficient") * dueFactor(task, now)
+	urgency += coef("urgency.age.coefficient") * ageFactor(task, now, coef("urgency.age.max"))
+
+	if c.Tasks == nil {
+		urgency += coef("urgency.blocked.coefficient") * boolFactor(len(task.Depends) > 0)
+	} else {
+		urgency += coef("urgency.blocked.coefficient") * boolFactor(m.isBlocked(task))
+		urgency += coef("urgency.blocking.coefficient") * boolFactor(m.isBlocking(task))
+	}
+
+	// Coefficients for specific tags, projects, keywords and UDA values.
+	for _, key := range c.configKeys() {
+		if !strings.HasPrefix(key, "urgency.") || !strings.HasSuffix(key, ".coefficient") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "urgency."), ".coefficient")
+		switch {
+		case strings.HasPrefix(name, "user.tag."):
+			if m.hasTag(task, strings.TrimPrefix(name, "user.tag.")) {
+				urgency += coef(key)
+			}
+		case strings.HasPrefix(name, "user.project."):
+			project := strings.TrimPrefix(name, "user.project.")
+			if task.Project == project || strings.HasPrefix(task.Project, project+".") {
+				urgency += coef(key)
+			}
+		case strings.HasPrefix(name, "user.keyword."):
+			if strings.Contains(task.Description, strings.TrimPrefix(name, "user.keyword.")) {
+				urgency += coef(key)
+			}
+		case strings.HasPrefix(name, "uda."):
+			if c.matchUDA(task, strings.TrimPrefix(name, "uda.")) {
+				urgency += coef(key)
+			}
+		}
+	}
+	return urgency
+}
+
+// Compute urgency of all tasks of the instance and store it in their Urgency fields.
+func (tw *TaskWarrior) UpdateUrgency() {
+	c := &UrgencyCalculator{Config: tw.Config, Tasks: tw.Tasks}
+	for i := range tw.Tasks {
+		tw.Tasks[i].Urgency = float32(c.Urgency(&tw.Tasks[i]))
+	}
+}
+
+// Return names of all configuration options, including built-in defaults, in sorted order.
+func (c *UrgencyCalculator) configKeys() []string {
+	keys := make([]string, 0, len(DefaultConfig))
+	for key := range DefaultConfig {
+		if c.Config == nil {
+			keys = append(keys, key)
+		} else if _, ok := c.Config.Lookup(key); !ok {
+			keys = append(keys, key)
+		}
+	}
+	if c.Config != nil {
+		keys = append(keys, c.Config.Keys()...)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Check whether UDA term `<name>` or `<name>.<value>` applies to the task.
+func (c *UrgencyCalculator) matchUDA(task *Task, term string) bool {
+	if v := c.matcher.attribute(task, term); v.set {
+		return true
+	}
+	// UDA names have no dots, so the value is everything after the first one.
+	i := strings.Index(term, ".")
+	if i < 0 {
+		return false
+	}
+	v := c.matcher.attribute(task, term[:i])
+	switch v.kind {
+	case attrString:
+		return v.set && v.str == term[i+1:]
+	case attrNumber:
+		return v.set && strconv.FormatFloat(v.num, 'f', -1, 64) == term[i+1:]
+	}
+	return false
+}
+
+// Factor of annotations and tags terms.
+func countFactor(n int) float64 {
+	switch {
+	case n >= 3:
+		return 1.0
+	case n == 2:
+		return 0.9
+	case n == 1:
+		return 0.8
+	}
+	return 0.0
+}
+
+// Factor of due term: grows linearly from 0.2 two weeks before the due date to 1.0 a week after it.
+func dueFactor(task *Task, now time.Time) float64 {
+	if task.Due.IsZero() {
+		return 0.0
+	}
+	daysOverdue := now.Sub(task.Due.Time).Seconds() / 86400.0
+	switch {
+	case daysOverdue >= 7.0:
+		return 1.0
+	case daysOverdue >= -14.0:
+		return ((daysOverdue+14.0)*0.8)/21.0 + 0.2
+	}
+	return 0.2
+}
+
+// Factor of age term: age of the task relative to urgency.age.max days. Tasks without entry date are as old as it
+// gets, like in taskwarrior.
+func ageFactor(task *Task, now time.Time, maxAge float64) float64 {
+	if task.Entry.IsZero() {
+		return 1.0
+	}
+	age := now.Sub(task.Entry.Time).Seconds() / 86400.0
+	if maxAge == 0 || age > maxAge {
+		return 1.0
+	}
+	return age / maxAge
+}
@@ -0,0 +1,119 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUrgencyCalculator(t *testing.T) {
+	// Export with default coefficients. Urgency values are the ones taskwarrior 2.6 reports at the time below.
+	buf, err := os.ReadFile("./fixtures/export/urgency_1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tasks []Task
+	if err := json.Unmarshal(buf, &tasks); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &UrgencyCalculator{Now: MustParseTaskTime("20260210T120000Z").Time, Tasks: tasks}
+	for i := range tasks {
+		got := c.Urgency(&tasks[i])
+		if math.Abs(got-float64(tasks[i].Urgency)) > 1e-4 {
+			t.Errorf("Incorrect urgency of '%s': expected %v, got %v", tasks[i].Description, tasks[i].Urgency, got)
+		}
+	}
+}
+
+func TestUrgency_Config(t *testing.T) {
+	config := &TaskRC{}
+	err := config.MapTaskRC(`
+uda.estimate.type=numeric
+urgency.uda.estimate.coefficient=1.5
+urgency.uda.estimate.8.coefficient=2.0
+urgency.user.project.work.coefficient=2.0
+urgency.user.keyword.report.coefficient=3.0
+urgency.user.tag.next.coefficient=10.0
+urgency.uda.priority.H.coefficient=0
+urgency.project.coefficient=0.5
+urgency.age.coefficient=0
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task := &Task{
+		Description: "Write report",
+		Project:     "work.backend",
+		Priority:    "H",
+		Tags:        []string{"next"},
+		Entry:       NewTaskTime(time.Now()),
+		UDA:         map[string]interface{}{"estimate": 8.0},
+	}
+	// project 0.5 + user.project 2 + keyword 3 + next 10 + tags 0.8 + estimate 1.5 + estimate.8 2
+	if got := Urgency(task, config); math.Abs(got-19.8) > 1e-4 {
+		t.Errorf("Incorrect urgency: expected 19.8, got %v", got)
+	}
+
+	// Defaults, dependencies are considered blocking without other tasks. Missing entry date gives the full age term.
+	task = &Task{Description: "Blocked", Depends: []string{"00000000-0000-0000-0000-000000000001"}}
+	if got := Urgency(task, nil); got != -5+2 {
+		t.Errorf("Incorrect urgency: expected -3, got %v", got)
+	}
+}
+
+func TestUrgency_Matching(t *testing.T) {
+	config := &TaskRC{}
+	err := config.MapTaskRC(`
+uda.estimate.type=numeric
+urgency.uda.estimate.1.5.coefficient=4.0
+urgency.user.project.work.coefficient=2.0
+urgency.age.coefficient=0
+urgency.project.coefficient=0
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		task     Task
+		expected float64
+	}{
+		// Project coefficient applies to the project and its subprojects only
+		{Task{Project: "work"}, 2.0},
+		{Task{Project: "work.backend"}, 2.0},
+		{Task{Project: "workshop"}, 0},
+		{Task{}, 0},
+		// UDA value is everything after the first dot of the term
+		{Task{UDA: map[string]interface{}{"estimate": 1.5}}, 4.0},
+		{Task{UDA: map[string]interface{}{"estimate": 5.0}}, 0},
+	}
+	for _, test := range tests {
+		test.task.Description = "Task"
+		test.task.Entry = NewTaskTime(time.Now())
+		if got := Urgency(&test.task, config); math.Abs(got-test.expected) > 1e-4 {
+			t.Errorf("Incorrect urgency of %+v: expected %v, got %v", test.task, test.expected, got)
+		}
+	}
+
+	// Task without entry date has maximal age
+	task := &Task{Description: "Task"}
+	if got := ageFactor(task, time.Now(), 365); got != 1.0 {
+		t.Errorf("Incorrect age factor without entry date: %v", got)
+	}
+}
+
+func TestTaskWarrior_UpdateUrgency(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+	tw.AddTask(&Task{Description: "Next", Tags: []string{"next"}, Entry: NewTaskTime(time.Now())})
+	tw.UpdateUrgency()
+	if math.Abs(float64(tw.Tasks[0].Urgency)-15.8) > 1e-3 {
+		t.Errorf("Urgency was not updated: %v", tw.Tasks[0].Urgency)
+	}
+}
@@ -13,7 +13,7 @@ func TestValidateTask(t *testing.T) {
 		Description: "Test task",
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	}
 
 	err := ValidateTask(validTask)
@@ -31,7 +31,7 @@ func TestValidateTask(t *testing.T) {
 	invalidTask := &Task{
 		Status:      "pending",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	}
 
 	err = ValidateTask(invalidTask)
@@ -43,7 +43,7 @@ func TestValidateTask(t *testing.T) {
 	invalidTask = &Task{
 		Description: "Test task",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	}
 
 	err = ValidateTask(invalidTask)
@@ -55,7 +55,7 @@ func TestValidateTask(t *testing.T) {
 	invalidTask = &Task{
 		Description: "Test task",
 		Status:      "pending",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	}
 
 	err = ValidateTask(invalidTask)
@@ -80,7 +80,7 @@ func TestValidateTask(t *testing.T) {
 		Description: "Test task",
 		Status:      "invalid_status",
 		Uuid:        "00000000-0000-0000-0000-000000000001",
-		Entry:       "20260206T120000Z",
+		Entry:       MustParseTaskTime("20260206T120000Z"),
 	}
 
 	err = ValidateTask(invalidTask)
@@ -0,0 +1,160 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Taskwarrior version detection.
+//
+// Releases differ in JSON format and storage: 2.5 exports depends as an array and JSON arrays by default, 2.6 adds
+// `task purge`, 3.0 replaces data files and taskd sync with TaskChampion. The version is read from `task --version`
+// once per runner, when the library first needs Capabilities of the detected version. Versions older than
+// MinimumVersion are not supported.
+
+package taskwarrior
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+// Version is a taskwarrior release number.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// Oldest supported taskwarrior version.
+var MinimumVersion = Version{2, 4, 0}
+
+// Version assumed when `task --version` can't be run, e.g. with a custom Runner that doesn't support it.
+var DefaultVersion = Version{2, 6, 0}
+
+var versionRegexp = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// Parse version number like `2.6.2` or `3.1.0`. Text around the number is ignored.
+func ParseVersion(s string) (Version, error) {
+	m := versionRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("'%s' is not a valid taskwarrior version", s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare versions. Returns -1, 0 or 1.
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Check whether the version is the same or newer than given one.
+func (v Version) AtLeast(major, minor, patch int) bool {
+	return v.Compare(Version{major, minor, patch}) >= 0
+}
+
+// Capabilities are differences between taskwarrior versions the library has to take into account.
+type Capabilities struct {
+	DependsArray    bool // Depends are exported and imported as JSON array instead of comma-separated string (2.5)
+	JSONArray       bool // Export returns JSON array by default, json.array=on is required otherwise (2.5)
+	Purge           bool // Deleted tasks can be removed with `task purge` (2.6)
+	TaskdSync       bool // `task sync` talks to taskd server (2.x)
+	ChampionSync    bool // `task sync` talks to TaskChampion sync server (3.x)
+	ChampionStorage bool // Tasks are stored in taskchampion.sqlite3 instead of data files (3.x)
+}
+
+// Return capabilities of given taskwarrior version.
+func CapabilitiesOf(v Version) Capabilities {
+	return Capabilities{
+		DependsArray:    v.AtLeast(2, 5, 0),
+		JSONArray:       v.AtLeast(2, 5, 0),
+		Purge:           v.AtLeast(2, 6, 0),
+		TaskdSync:       v.Major == 2,
+		ChampionSync:    v.Major >= 3,
+		ChampionStorage: v.Major >= 3,
+	}
+}
+
+// Return version of taskwarrior used by the instance. The version is detected with `task --version` once for each
+// runner. Returns error wrapping ErrUnsupportedVersion for versions older than MinimumVersion.
+func (tw *TaskWarrior) Version() (Version, error) {
+	return tw.VersionContext(context.Background())
+}
+
+// Same as Version, but the command is interrupted when given context is done.
+func (tw *TaskWarrior) VersionContext(ctx context.Context) (Version, error) {
+	if tw == nil {
+		return Version{}, fmt.Errorf("Uninitialized taskwarrior database!")
+	}
+	runner := tw.runner()
+	if tw.versionRunner == nil || !sameRunner(tw.versionRunner, runner) {
+		v, err := tw.detectVersion(ctx)
+		var timeout *TimeoutError
+		if errors.As(err, &timeout) {
+			// Interrupted detection is tried again next time.
+			return v, err
+		}
+		tw.version, tw.versionErr, tw.versionRunner = v, err, runner
+	}
+	return tw.version, tw.versionErr
+}
+
+func (tw *TaskWarrior) detectVersion(ctx context.Context) (Version, error) {
+	// Taskwarrior recognizes --version only as the single argument, so overrides are not passed.
+	out, err := tw.exec(ctx, nil, "--version")
+	if err != nil {
+		return Version{}, err
+	}
+	v, err := ParseVersion(string(out))
+	if err != nil {
+		return Version{}, err
+	}
+	if v.Compare(MinimumVersion) < 0 {
+		return v, fmt.Errorf("taskwarrior %s: %w, %s or newer is required", v, ErrUnsupportedVersion, MinimumVersion)
+	}
+	return v, nil
+}
+
+// Return capabilities of taskwarrior used by the instance. Capabilities of DefaultVersion are returned if the
+// version can't be detected.
+func (tw *TaskWarrior) Capabilities() Capabilities {
+	caps, _ := tw.capabilities(context.Background())
+	return caps
+}
+
+// Same as Capabilities, but unsupported version is reported as error, so commands are not run with it. The version
+// is detected on the first call.
+func (tw *TaskWarrior) capabilities(ctx context.Context) (Capabilities, error) {
+	v, err := tw.VersionContext(ctx)
+	if errors.Is(err, ErrUnsupportedVersion) {
+		return CapabilitiesOf(v), err
+	}
+	if err != nil {
+		v = DefaultVersion
+	}
+	return CapabilitiesOf(v), nil
+}
+
+// Check whether runners are the same value. Runners of incomparable types are never the same.
+func sameRunner(a, b Runner) bool {
+	if a == nil || b == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
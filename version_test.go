@@ -0,0 +1,120 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"2.6.2\n":       {2, 6, 2},
+		"3.1.0":         {3, 1, 0},
+		"task 2.5.3 ":   {2, 5, 3},
+		"2.4":           {2, 4, 0},
+		"3.0.0-alpha.1": {3, 0, 0},
+	}
+	for s, expected := range tests {
+		v, err := ParseVersion(s)
+		if err != nil || v != expected {
+			t.Errorf("ParseVersion(%q): expected %v, got %v (%v)", s, expected, v, err)
+		}
+	}
+	if _, err := ParseVersion("unknown"); err == nil {
+		t.Error("Invalid version was accepted")
+	}
+
+	v := Version{2, 5, 3}
+	if v.String() != "2.5.3" || !v.AtLeast(2, 5, 0) || v.AtLeast(2, 6, 0) || v.Compare(Version{10, 0, 0}) != -1 {
+		t.Errorf("Incorrect comparison of %s", v)
+	}
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	tests := map[Version]Capabilities{
+		{2, 4, 5}: {TaskdSync: true},
+		{2, 5, 3}: {DependsArray: true, JSONArray: true, TaskdSync: true},
+		{2, 6, 2}: {DependsArray: true, JSONArray: true, Purge: true, TaskdSync: true},
+		{3, 1, 0}: {DependsArray: true, JSONArray: true, Purge: true, ChampionSync: true, ChampionStorage: true},
+	}
+	for v, expected := range tests {
+		if got := CapabilitiesOf(v); got != expected {
+			t.Errorf("%s: expected %+v, got %+v", v, expected, got)
+		}
+	}
+}
+
+func TestTaskWarrior_Version(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+	runner := &FakeRunner{Version: "3.1.0"}
+	tw.Runner = runner
+	tw.Config.Override("confirmation", "off")
+
+	for i := 0; i < 2; i++ {
+		v, err := tw.Version()
+		if err != nil || v != (Version{3, 1, 0}) {
+			t.Fatalf("Unexpected version: %v (%v)", v, err)
+		}
+	}
+	if len(runner.Calls) != 1 || len(runner.Calls[0].Args) != 1 || runner.Calls[0].Args[0] != "--version" {
+		t.Errorf("Version should be detected once with --version only: %v", runner.Calls)
+	}
+	if !tw.Capabilities().ChampionStorage {
+		t.Error("Unexpected capabilities")
+	}
+
+	// New runner is asked again
+	tw.Runner = &FakeRunner{Version: "2.4.5"}
+	if caps := tw.Capabilities(); caps.DependsArray || caps.JSONArray {
+		t.Errorf("Unexpected capabilities of 2.4: %+v", caps)
+	}
+	if err := tw.FetchAllTasks(); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, arg := range tw.Runner.(*FakeRunner).Calls[1].Args {
+		found = found || arg == "rc.json.array=on"
+	}
+	if !found {
+		t.Errorf("JSON array was not requested from taskwarrior 2.4: %v", tw.Runner.(*FakeRunner).Calls[1].Args)
+	}
+
+	// Unsupported version
+	tw.Runner = &FakeRunner{Version: "2.3.0"}
+	if _, err := tw.Version(); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+
+	// Runner without version support
+	tw.Runner = &ExecRunner{Binary: "/nonexistent/task"}
+	if caps := tw.Capabilities(); caps != CapabilitiesOf(DefaultVersion) {
+		t.Errorf("Expected default capabilities, got %+v", caps)
+	}
+}
+
+func TestNewTaskWarrior_UnsupportedVersion(t *testing.T) {
+	defer func(runner Runner) { DefaultRunner = runner }(DefaultRunner)
+	runner := &FakeRunner{Version: "2.3.1"}
+	DefaultRunner = runner
+	tw, err := NewTaskWarrior("./fixtures/taskrc/simple_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runner.Calls) != 0 {
+		t.Errorf("NewTaskWarrior ran commands: %v", runner.Calls)
+	}
+
+	// Version is checked before the first command
+	if err := tw.FetchAllTasks(); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+	if len(runner.Calls) != 1 {
+		t.Errorf("Export was run with unsupported version: %v", runner.Calls)
+	}
+	tw.AddTask(&Task{Description: "Buy milk"})
+	if _, err := tw.Commit(); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+}
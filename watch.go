@@ -0,0 +1,222 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+//
+// Watching the task database for changes.
+//
+// Watch polls modification times and sizes of the storage files in `data.location` directory: pending.data,
+// completed.data and backlog.data of taskwarrior 2.x and the taskchampion.sqlite3 replica of taskwarrior 3. When any
+// of them changes, tasks are fetched again and compared with the previous ones by UUID. Polling needs no OS-specific
+// notification API and works on network file systems.
+
+package taskwarrior
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Polling period of Watch used when TaskWarrior.WatchInterval is zero.
+var DefaultWatchInterval = 2 * time.Second
+
+// Kind of task change reported by Watch.
+type ChangeType string
+
+const (
+	TaskAdded     ChangeType = "added"     // New task appeared
+	TaskModified  ChangeType = "modified"  // Attributes of the task changed
+	TaskCompleted ChangeType = "completed" // Task status changed to completed
+	TaskDeleted   ChangeType = "deleted"   // Task status changed to deleted or the task was removed
+)
+
+// Change of a single task attribute. Values use the representation of data files, see ComposeDataLine: dates are
+// Unix timestamps and lists are comma-separated. Empty value means the attribute is not set.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// TaskEvent is a change of a single task, or a failed re-fetch if Err is set.
+type TaskEvent struct {
+	Type    ChangeType
+	UUID    string
+	Task    *Task         // Current state, nil if the task was removed from the database
+	Old     *Task         // Previous state, nil for added tasks
+	Changes []FieldChange // Changed attributes sorted by name, nil for added and removed tasks
+	Err     error         // Error of fetching tasks, other fields are empty then
+}
+
+// Files of data directory checked for changes by Watch.
+var watchedFiles = []string{PendingDataFile, CompletedDataFile, BacklogDataFile, TaskChampionFile,
+	TaskChampionFile + "-wal"}
+
+// Modification time and size of a watched file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch the database for changes until given context is done. Tasks are fetched like FetchAllTasksContext does, but
+// Tasks of the instance are not changed. The current tasks are the baseline, so only later changes are reported. The
+// returned channel is closed when the context is done.
+//
+// Errors of later fetches are sent as events with Err set and watching continues, since data files may be caught in
+// the middle of a write.
+func (tw *TaskWarrior) Watch(ctx context.Context) (<-chan TaskEvent, error) {
+	data := tw.Config.valueOrDefault("data.location")
+	if tw.Config.DataLocation != "" {
+		data = tw.Config.DataLocation
+	}
+	dir := PathExpandTilda(data)
+	if b, ok := tw.Backend.(*DataFileBackend); ok {
+		dir = b.Dir
+	}
+	interval := tw.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	stamps := statFiles(dir)
+	tasks, err := tw.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan TaskEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		send := func(event TaskEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			current := statFiles(dir)
+			if equalStamps(stamps, current) {
+				continue
+			}
+			fetched, err := tw.fetch(ctx)
+			if err != nil {
+				if ctx.Err() != nil || !send(TaskEvent{Err: err}) {
+					return
+				}
+				// Retry on the next tick even if files are not changed any more.
+				stamps = nil
+				continue
+			}
+			stamps = current
+			for _, event := range CompareTasks(tasks, fetched) {
+				if !send(event) {
+					return
+				}
+			}
+			tasks = fetched
+		}
+	}()
+	return events, nil
+}
+
+// Return all tasks from the backend of the instance, or with `task export` if it has none.
+func (tw *TaskWarrior) fetch(ctx context.Context) ([]Task, error) {
+	if tw.Backend != nil {
+		return tw.Backend.List(ctx)
+	}
+	return tw.export(ctx)
+}
+
+// Return changes between two lists of tasks, sorted by UUID. Tasks are matched by UUID; ID and urgency are ignored.
+func CompareTasks(old, current []Task) []TaskEvent {
+	oldByUUID := map[string]*Task{}
+	for i := range old {
+		oldByUUID[old[i].Uuid] = &old[i]
+	}
+
+	var events []TaskEvent
+	for i := range current {
+		task := &current[i]
+		prev, ok := oldByUUID[task.Uuid]
+		delete(oldByUUID, task.Uuid)
+		if !ok {
+			events = append(events, TaskEvent{Type: TaskAdded, UUID: task.Uuid, Task: task})
+			continue
+		}
+		if taskFingerprint(prev) == taskFingerprint(task) {
+			continue
+		}
+
+		event := TaskEvent{Type: TaskModified, UUID: task.Uuid, Task: task, Old: prev, Changes: diffTasks(prev, task)}
+		if task.Status != prev.Status {
+			switch task.Status {
+			case "completed":
+				event.Type = TaskCompleted
+			case "deleted":
+				event.Type = TaskDeleted
+			}
+		}
+		events = append(events, event)
+	}
+	for uuid, prev := range oldByUUID {
+		events = append(events, TaskEvent{Type: TaskDeleted, UUID: uuid, Old: prev})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].UUID < events[j].UUID })
+	return events
+}
+
+// Return changed attributes of the task sorted by name.
+func diffTasks(old, current *Task) []FieldChange {
+	// Tasks that can't be flattened have unsupported UDA values, their changes are reported without details.
+	oldAttrs, _ := flattenTask(old)
+	newAttrs, _ := flattenTask(current)
+
+	var changes []FieldChange
+	for field, value := range newAttrs {
+		if oldAttrs[field] != value {
+			changes = append(changes, FieldChange{Field: field, Old: oldAttrs[field], New: value})
+		}
+	}
+	for field, value := range oldAttrs {
+		if _, ok := newAttrs[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Old: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// Return stamps of watched files in the directory. Missing files have no stamp.
+func statFiles(dir string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, name := range watchedFiles {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			stamps[name] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+func equalStamps(a, b map[string]fileStamp) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for name, stamp := range a {
+		if other, ok := b[name]; !ok || !other.modTime.Equal(stamp.modTime) || other.size != stamp.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+// The MIT License (MIT)
+// Copyright (C) 2018 Georgy Komarov <jubnzv@gmail.com>
+
+package taskwarrior
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareTasks(t *testing.T) {
+	entry := NewTaskTime(time.Unix(1517745313, 0))
+	old := []Task{
+		{Id: 1, Uuid: "a", Description: "Buy milk", Status: "pending", Entry: entry},
+		{Id: 2, Uuid: "b", Description: "Write report", Status: "pending", Tags: []string{"work"}},
+		{Id: 3, Uuid: "c", Description: "Call mom", Status: "pending"},
+		{Id: 4, Uuid: "d", Description: "Old task", Status: "completed"},
+		{Id: 5, Uuid: "e", Description: "Unchanged", Status: "pending", Urgency: 1},
+	}
+	current := []Task{
+		{Id: 1, Uuid: "e", Description: "Unchanged", Status: "pending", Urgency: 2},
+		{Id: 2, Uuid: "a", Description: "Buy milk", Status: "completed", Entry: entry, End: entry},
+		{Id: 3, Uuid: "b", Description: "Write final report", Status: "pending", Project: "job"},
+		{Id: 4, Uuid: "c", Description: "Call mom", Status: "deleted"},
+		{Id: 5, Uuid: "f", Description: "New task", Status: "pending"},
+	}
+
+	events := CompareTasks(old, current)
+	var types []ChangeType
+	var uuids []string
+	for _, event := range events {
+		types = append(types, event.Type)
+		uuids = append(uuids, event.UUID)
+	}
+	if expected := []ChangeType{TaskCompleted, TaskModified, TaskDeleted, TaskDeleted, TaskAdded}; !reflect.DeepEqual(types, expected) {
+		t.Fatalf("Expected %v, got %v for %v", expected, types, uuids)
+	}
+	if expected := []string{"a", "b", "c", "d", "f"}; !reflect.DeepEqual(uuids, expected) {
+		t.Errorf("Expected events for %v, got %v", expected, uuids)
+	}
+
+	expected := []FieldChange{
+		{Field: "description", Old: "Write report", New: "Write final report"},
+		{Field: "project", New: "job"},
+		{Field: "tags", Old: "work"},
+	}
+	if !reflect.DeepEqual(events[1].Changes, expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, events[1].Changes)
+	}
+	if events[0].Changes[0] != (FieldChange{Field: "end", New: "1517745313"}) {
+		t.Errorf("Unexpected changes of completed task: %+v", events[0].Changes)
+	}
+
+	// Removed task has no current state
+	if removed := events[3]; removed.Task != nil || removed.Old == nil || removed.Old.Description != "Old task" {
+		t.Errorf("Unexpected event of removed task: %+v", removed)
+	}
+	if added := events[4]; added.Task == nil || added.Old != nil || added.Changes != nil {
+		t.Errorf("Unexpected event of added task: %+v", added)
+	}
+}
+
+// Helper that receives the next event or fails after a timeout.
+func nextEvent(t *testing.T, events <-chan TaskEvent) TaskEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Events channel was closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("No event was received")
+	}
+	return TaskEvent{}
+}
+
+func TestTaskWarrior_Watch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, PendingDataFile), nil, 0600)
+	os.WriteFile(filepath.Join(dir, CompletedDataFile), nil, 0600)
+	backend := NewDataFileBackend(&TaskRC{DataLocation: dir})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	existing, err := backend.Add(ctx, &Task{Description: "Existing task"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := &TaskWarrior{Config: backend.Config, Backend: backend, WatchInterval: 10 * time.Millisecond}
+	events, err := tw.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch fails with following error: %v", err)
+	}
+
+	added, err := backend.Add(ctx, &Task{Description: "Buy milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != TaskAdded || event.UUID != added.Uuid {
+		t.Errorf("Expected added event for %s, got %+v", added.Uuid, event)
+	}
+
+	added.Project = "home"
+	if _, err := backend.Modify(ctx, added); err != nil {
+		t.Fatal(err)
+	}
+	event := nextEvent(t, events)
+	if event.Type != TaskModified || event.UUID != added.Uuid || event.Task.Project != "home" {
+		t.Errorf("Expected modified event for %s, got %+v", added.Uuid, event)
+	}
+	found := false
+	for _, change := range event.Changes {
+		found = found || change == FieldChange{Field: "project", New: "home"}
+	}
+	if !found {
+		t.Errorf("Project change is not reported: %+v", event.Changes)
+	}
+
+	if err := backend.Delete(ctx, existing.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != TaskDeleted || event.UUID != existing.Uuid {
+		t.Errorf("Expected deleted event for %s, got %+v", existing.Uuid, event)
+	}
+
+	// Broken data file is reported and watching continues
+	os.WriteFile(filepath.Join(dir, PendingDataFile), []byte("not a task\n"), 0600)
+	if event := nextEvent(t, events); event.Err == nil {
+		t.Errorf("Expected error event, got %+v", event)
+	}
+	os.WriteFile(filepath.Join(dir, PendingDataFile), nil, 0600)
+	event = nextEvent(t, events)
+	for event.Err != nil {
+		// Fetch is retried until the file is fixed
+		event = nextEvent(t, events)
+	}
+	if event.Type != TaskDeleted || event.UUID != added.Uuid || event.Task != nil {
+		t.Errorf("Expected removal of %s, got %+v", added.Uuid, event)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestTaskWarrior_WatchError(t *testing.T) {
+	tw := newFakeTaskWarrior(t)
+	tw.Backend = NewDataFileBackend(&TaskRC{DataLocation: t.TempDir()})
+	os.WriteFile(filepath.Join(tw.Backend.(*DataFileBackend).Dir, PendingDataFile), []byte("[broken"), 0600)
+	if _, err := tw.Watch(context.Background()); err == nil {
+		t.Error("Watch started with broken data file")
+	}
+}